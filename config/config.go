@@ -9,13 +9,27 @@ import (
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"log"
+	"os"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	App      AppConfig      `mapstructure:"app"`
-	Database DatabaseConfig `mapstructure:"database"`
-	RabbitMQ RabbitMQConfig `mapstructure:"rabbitmq"`
-	Logging  LoggingConfig  `mapstructure:"logging"`
+	App             AppConfig             `mapstructure:"app"`
+	Admin           AdminConfig           `mapstructure:"admin"`
+	Database        DatabaseConfig        `mapstructure:"database"`
+	RabbitMQ        RabbitMQConfig        `mapstructure:"rabbitmq"`
+	Logging         LoggingConfig         `mapstructure:"logging"`
+	JWT             JWTConfig             `mapstructure:"jwt"`
+	TwoFactor       TwoFactorConfig       `mapstructure:"two_factor"`
+	LoginProtection LoginProtectionConfig `mapstructure:"login_protection"`
+	Mail            MailConfig            `mapstructure:"mail"`
+	PasswordReset   PasswordResetConfig   `mapstructure:"password_reset"`
+	SSO             SSOConfig             `mapstructure:"sso"`
+	Auth            AuthConfig            `mapstructure:"auth"`
+	LDAP            LDAPConfig            `mapstructure:"ldap"`
+	PasswordPolicy  PasswordPolicyConfig  `mapstructure:"password_policy"`
+	Chat            ChatConfig            `mapstructure:"chat"`
 }
 
 type AppConfig struct {
@@ -23,6 +37,14 @@ type AppConfig struct {
 	Port int    `mapstructure:"port"`
 }
 
+// AdminConfig 管理员相关配置，密钥可由环境变量 APP_ADMIN_REGISTER_SECRET 或密钥文件提供
+type AdminConfig struct {
+	RegisterSecret     string        `mapstructure:"register_secret"`      // 初始化密钥，仅在系统中还没有管理员时可用于注册第一个管理员
+	RegisterSecretFile string        `mapstructure:"register_secret_file"` // 从文件读取注册密钥（优先于 register_secret）
+	InvitationTTL      time.Duration `mapstructure:"invitation_ttl"`       // 管理员邀请有效期
+	InvitationURL      string        `mapstructure:"invitation_url"`       // 前端接受邀请页面，令牌以 ?token= 附加
+}
+
 type MySQLConfig struct {
 	DSN string `mapstructure:"dsn"`
 }
@@ -47,6 +69,158 @@ type LoggingConfig struct {
 	Level string `mapstructure:"level"`
 }
 
+// JWTConfig 令牌有效期配置
+type JWTConfig struct {
+	AccessTTL     time.Duration `mapstructure:"access_ttl"`      // 访问令牌有效期（短）
+	RefreshTTL    time.Duration `mapstructure:"refresh_ttl"`     // 刷新令牌空闲有效期，每次刷新顺延（滑动会话）
+	SessionMaxAge time.Duration `mapstructure:"session_max_age"` // 会话最长存活时间，超过后必须重新登录
+
+	// 签名密钥。未配置 keys 时使用 secret 作为唯一的 HS256 密钥（kid 为 default）
+	Secret     string         `mapstructure:"secret"`
+	SecretFile string         `mapstructure:"secret_file"`
+	ActiveKID  string         `mapstructure:"active_kid"` // 当前用于签发的密钥，其余密钥只用于校验
+	Keys       []JWTKeyConfig `mapstructure:"keys"`
+}
+
+// JWTKeyConfig 单个签名密钥。轮换时新增密钥并切换 active_kid，旧密钥保留到已签发令牌过期后再删除
+type JWTKeyConfig struct {
+	KID            string `mapstructure:"kid"`
+	Alg            string `mapstructure:"alg"`              // HS256、RS256 或 EdDSA
+	Secret         string `mapstructure:"secret"`           // HS256 共享密钥
+	SecretFile     string `mapstructure:"secret_file"`      // HS256 共享密钥文件
+	PrivateKeyFile string `mapstructure:"private_key_file"` // RS256/EdDSA 私钥（PEM）
+	PublicKeyFile  string `mapstructure:"public_key_file"`  // 只有公钥时该密钥仅用于校验
+}
+
+// TwoFactorConfig 两步验证配置
+type TwoFactorConfig struct {
+	Issuer        string        `mapstructure:"issuer"`         // 验证器 App 中显示的发行方
+	RequiredRoles []string      `mapstructure:"required_roles"` // 强制绑定两步验证的角色，如 admin
+	ChallengeTTL  time.Duration `mapstructure:"challenge_ttl"`  // 登录挑战令牌有效期
+}
+
+// LoginProtectionConfig 登录防爆破配置
+type LoginProtectionConfig struct {
+	MaxUserFailures int           `mapstructure:"max_user_failures"` // 窗口期内同一用户名允许的失败次数
+	MaxIPFailures   int           `mapstructure:"max_ip_failures"`   // 窗口期内同一 IP 允许的失败次数
+	FailureWindow   time.Duration `mapstructure:"failure_window"`    // 失败计数窗口
+	BaseLockout     time.Duration `mapstructure:"base_lockout"`      // 首次锁定时长，之后每次锁定翻倍
+	MaxLockout      time.Duration `mapstructure:"max_lockout"`       // 锁定时长上限
+	LevelResetAfter time.Duration `mapstructure:"level_reset_after"` // 多久没有再被锁定后，锁定时长回到初始值
+}
+
+// MailConfig 邮件发送配置，driver 为 smtp 或 log（开发/测试环境写日志文件）
+type MailConfig struct {
+	Driver  string     `mapstructure:"driver"`
+	From    string     `mapstructure:"from"`
+	SMTP    SMTPConfig `mapstructure:"smtp"`
+	LogFile string     `mapstructure:"log_file"` // driver=log 时写入的文件，为空则输出到标准日志
+}
+
+type SMTPConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+}
+
+// PasswordResetConfig 找回密码配置
+type PasswordResetConfig struct {
+	TokenTTL time.Duration `mapstructure:"token_ttl"` // 重置链接有效期
+	ResetURL string        `mapstructure:"reset_url"` // 前端重置页面地址，令牌以 ?token= 附加
+}
+
+// SSOConfig OIDC 单点登录配置（授权码 + PKCE）
+type SSOConfig struct {
+	Enabled              bool          `mapstructure:"enabled"`
+	Issuer               string        `mapstructure:"issuer"` // IdP 地址，启动后首次使用时通过 /.well-known/openid-configuration 发现端点
+	ClientID             string        `mapstructure:"client_id"`
+	ClientSecret         string        `mapstructure:"client_secret"`
+	ClientSecretFile     string        `mapstructure:"client_secret_file"`
+	RedirectURL          string        `mapstructure:"redirect_url"` // IdP 回调的前端页面，前端再携带 code/state 调用 /api/sso/callback
+	Scopes               []string      `mapstructure:"scopes"`
+	StateTTL             time.Duration `mapstructure:"state_ttl"`              // 从跳转 IdP 到回调的最长时间
+	RequireVerifiedEmail bool          `mapstructure:"require_verified_email"` // 按邮箱匹配已有用户时要求 IdP 声明邮箱已验证
+	JITProvisioning      bool          `mapstructure:"jit_provisioning"`       // 找不到对应用户时自动创建员工
+	DefaultDepartmentID  uint          `mapstructure:"default_department_id"`  // 自动创建的员工所属部门
+}
+
+// AuthConfig 密码登录认证配置
+type AuthConfig struct {
+	Providers []string `mapstructure:"providers"` // 按顺序尝试的认证方式：local（本地密码）、ldap（目录服务绑定）
+}
+
+// LDAPConfig LDAP 绑定认证：先用服务账号按用户名查出用户 DN，再用用户密码绑定校验
+type LDAPConfig struct {
+	URL                 string          `mapstructure:"url"`                  // ldap://host:389 或 ldaps://host:636
+	StartTLS            bool            `mapstructure:"start_tls"`            // ldap:// 连接上升级为 TLS
+	InsecureSkipVerify  bool            `mapstructure:"insecure_skip_verify"` // 仅用于测试环境
+	Timeout             time.Duration   `mapstructure:"timeout"`
+	BindDN              string          `mapstructure:"bind_dn"` // 查询用户的服务账号，为空时匿名查询
+	BindPassword        string          `mapstructure:"bind_password"`
+	BindPasswordFile    string          `mapstructure:"bind_password_file"`
+	BaseDN              string          `mapstructure:"base_dn"`
+	UserFilter          string          `mapstructure:"user_filter"` // %s 替换为转义后的用户名，如 (uid=%s)
+	EmailAttr           string          `mapstructure:"email_attr"`
+	GroupAttr           string          `mapstructure:"group_attr"`            // 用户所属组 DN 的属性，如 memberOf
+	GroupRoles          []LDAPGroupRole `mapstructure:"group_roles"`           // 组到角色的映射，登录时同步
+	JITProvisioning     bool            `mapstructure:"jit_provisioning"`      // 本系统没有对应用户时自动创建员工
+	DefaultDepartmentID uint            `mapstructure:"default_department_id"` // 自动创建的员工所属部门
+}
+
+// LDAPGroupRole 目录组与系统角色的对应关系。映射中出现的角色由目录管理：
+// 用户离开对应的组后，下次登录时收回该角色
+type LDAPGroupRole struct {
+	Group string `mapstructure:"group"` // 组 DN，不区分大小写
+	Role  string `mapstructure:"role"`  // 角色名，如 hr
+}
+
+// PasswordPolicyConfig 密码策略，注册、创建管理员、重置和修改密码时校验
+type PasswordPolicyConfig struct {
+	MinLength        int           `mapstructure:"min_length"`
+	MaxLength        int           `mapstructure:"max_length"` // bcrypt 只使用前 72 字节，另外按字节再限制一次
+	RequireUpper     bool          `mapstructure:"require_upper"`
+	RequireLower     bool          `mapstructure:"require_lower"`
+	RequireDigit     bool          `mapstructure:"require_digit"`
+	RequireSymbol    bool          `mapstructure:"require_symbol"`
+	DisallowUsername bool          `mapstructure:"disallow_username"` // 密码不能包含用户名（不区分大小写）
+	BannedFile       string        `mapstructure:"banned_file"`       // 额外的禁用密码列表，每行一个，与内置常见密码合并
+	HistorySize      int           `mapstructure:"history_size"`      // 新密码不能与当前及最近 N 次密码相同
+	MaxAge           time.Duration `mapstructure:"max_age"`           // 密码最长使用时间，超过后会话只能修改密码或注销；0 表示不限制
+}
+
+// ChatConfig 聊天相关配置
+type ChatConfig struct {
+	EditWindow   time.Duration `mapstructure:"edit_window"`   // 发送后多长时间内可以编辑，0 表示不允许编辑
+	RecallWindow time.Duration `mapstructure:"recall_window"` // 发送后多长时间内可以撤回，0 表示不允许撤回
+	// 给非联系人发送消息的条数上限，对方回复后不再限制；0 表示只能给联系人发消息，-1 表示不限制。
+	// 同部门同事视为联系人
+	NonContactLimit int `mapstructure:"non_contact_limit"`
+
+	Attachments AttachmentConfig `mapstructure:"attachments"`
+}
+
+// AttachmentConfig 聊天附件：存储后端、大小和类型限制
+type AttachmentConfig struct {
+	Driver        string        `mapstructure:"driver"`         // local 或 s3（兼容 S3 的对象存储，如 MinIO）
+	LocalDir      string        `mapstructure:"local_dir"`      // driver=local 时的存储目录
+	MaxSize       int64         `mapstructure:"max_size"`       // 单个文件上限（字节）
+	AllowedTypes  []string      `mapstructure:"allowed_types"`  // 允许的 MIME 类型，支持 image/* 这样的通配
+	ThumbnailSize int           `mapstructure:"thumbnail_size"` // 图片缩略图最长边（像素）
+	UnsentTTL     time.Duration `mapstructure:"unsent_ttl"`     // 上传后超过该时长仍未发送的附件会被删除，0 表示不清理
+	S3            S3Config      `mapstructure:"s3"`
+}
+
+type S3Config struct {
+	Endpoint      string `mapstructure:"endpoint"` // 如 s3.amazonaws.com、minio.company.com:9000
+	Region        string `mapstructure:"region"`
+	Bucket        string `mapstructure:"bucket"`
+	AccessKey     string `mapstructure:"access_key"`
+	SecretKey     string `mapstructure:"secret_key"`
+	SecretKeyFile string `mapstructure:"secret_key_file"`
+	UseSSL        bool   `mapstructure:"use_ssl"`
+}
+
 var Cfg Config
 
 func LoadConfig() {
@@ -61,6 +235,65 @@ func LoadConfig() {
 	// 环境变量支持（优先级高于配置文件）
 	v.AutomaticEnv()
 	v.SetEnvPrefix("APP") // 环境变量前缀 APP_DATABASE_MYSQL_DSN
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+
+	// 默认值
+	v.SetDefault("jwt.access_ttl", "15m")
+	v.SetDefault("jwt.refresh_ttl", "168h")
+	v.SetDefault("jwt.session_max_age", "720h")
+	v.SetDefault("jwt.secret", "")
+	v.SetDefault("jwt.secret_file", "")
+	v.SetDefault("jwt.active_kid", "")
+	v.SetDefault("admin.register_secret", "")
+	v.SetDefault("admin.register_secret_file", "")
+	v.SetDefault("admin.invitation_ttl", "72h")
+	v.SetDefault("two_factor.issuer", "EmployeeManagement")
+	v.SetDefault("two_factor.challenge_ttl", "5m")
+	v.SetDefault("login_protection.max_user_failures", 5)
+	v.SetDefault("login_protection.max_ip_failures", 20)
+	v.SetDefault("login_protection.failure_window", "15m")
+	v.SetDefault("login_protection.base_lockout", "1m")
+	v.SetDefault("login_protection.max_lockout", "1h")
+	v.SetDefault("login_protection.level_reset_after", "24h")
+	v.SetDefault("mail.driver", "log")
+	v.SetDefault("password_reset.token_ttl", "30m")
+	v.SetDefault("sso.enabled", false)
+	v.SetDefault("sso.client_secret", "")
+	v.SetDefault("sso.client_secret_file", "")
+	v.SetDefault("sso.scopes", []string{"openid", "email", "profile"})
+	v.SetDefault("sso.state_ttl", "10m")
+	v.SetDefault("sso.require_verified_email", true)
+	v.SetDefault("auth.providers", []string{"local"})
+	v.SetDefault("ldap.timeout", "5s")
+	v.SetDefault("ldap.bind_password", "")
+	v.SetDefault("ldap.bind_password_file", "")
+	v.SetDefault("ldap.user_filter", "(uid=%s)")
+	v.SetDefault("ldap.email_attr", "mail")
+	v.SetDefault("ldap.group_attr", "memberOf")
+	v.SetDefault("password_policy.min_length", 8)
+	v.SetDefault("password_policy.max_length", 64)
+	v.SetDefault("password_policy.require_lower", true)
+	v.SetDefault("password_policy.require_digit", true)
+	v.SetDefault("password_policy.disallow_username", true)
+	v.SetDefault("password_policy.banned_file", "")
+	v.SetDefault("password_policy.history_size", 5)
+	v.SetDefault("password_policy.max_age", "0s")
+	v.SetDefault("chat.edit_window", "15m")
+	v.SetDefault("chat.recall_window", "2m")
+	v.SetDefault("chat.non_contact_limit", 3)
+	v.SetDefault("chat.attachments.driver", "local")
+	v.SetDefault("chat.attachments.local_dir", "./uploads/chat")
+	v.SetDefault("chat.attachments.max_size", 20<<20)
+	v.SetDefault("chat.attachments.allowed_types", []string{
+		"image/*", "text/plain", "application/pdf", "application/zip",
+		"application/msword", "application/vnd.ms-excel", "application/vnd.ms-powerpoint",
+		"application/vnd.openxmlformats-officedocument.*",
+	})
+	v.SetDefault("chat.attachments.thumbnail_size", 320)
+	v.SetDefault("chat.attachments.unsent_ttl", "24h")
+	v.SetDefault("chat.attachments.s3.secret_key", "")
+	v.SetDefault("chat.attachments.s3.secret_key_file", "")
+	v.SetDefault("chat.attachments.s3.use_ssl", true)
 
 	// 读取配置
 	if err := v.ReadInConfig(); err != nil {
@@ -71,6 +304,51 @@ func LoadConfig() {
 	if err := v.Unmarshal(&Cfg); err != nil {
 		log.Fatalf("配置解析失败: %v", err)
 	}
+
+	// 密钥文件（如 Docker/K8s secret 挂载）优先于明文配置
+	if err := resolveSecrets(&Cfg); err != nil {
+		log.Fatalf("读取密钥文件失败: %v", err)
+	}
+	if Cfg.JWT.Secret == "" && len(Cfg.JWT.Keys) == 0 {
+		log.Fatalf("未配置 JWT 签名密钥：请设置 APP_JWT_SECRET、jwt.secret_file 或 jwt.keys")
+	}
+}
+
+func resolveSecrets(cfg *Config) error {
+	if err := readSecretFile(cfg.Admin.RegisterSecretFile, &cfg.Admin.RegisterSecret); err != nil {
+		return err
+	}
+	if err := readSecretFile(cfg.JWT.SecretFile, &cfg.JWT.Secret); err != nil {
+		return err
+	}
+	if err := readSecretFile(cfg.SSO.ClientSecretFile, &cfg.SSO.ClientSecret); err != nil {
+		return err
+	}
+	if err := readSecretFile(cfg.LDAP.BindPasswordFile, &cfg.LDAP.BindPassword); err != nil {
+		return err
+	}
+	if err := readSecretFile(cfg.Chat.Attachments.S3.SecretKeyFile, &cfg.Chat.Attachments.S3.SecretKey); err != nil {
+		return err
+	}
+	for i := range cfg.JWT.Keys {
+		if err := readSecretFile(cfg.JWT.Keys[i].SecretFile, &cfg.JWT.Keys[i].Secret); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readSecretFile 文件路径非空时读取文件内容（去掉首尾空白）覆盖 dst
+func readSecretFile(path string, dst *string) error {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	*dst = strings.TrimSpace(string(data))
+	return nil
 }
 
 var (
@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/services"
+	"EmployeeManagementDemo/utils"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// CreateAPIKey 创建 API 密钥，明文密钥只在响应中出现一次
+func CreateAPIKey(c *gin.Context) {
+	var req models.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, utils.TranslateValidationErrors(err)))
+		return
+	}
+
+	userID, _ := utils.GetCurrentUserID(c)
+	role, _ := utils.GetCurrentUserRole(c)
+	principalID, _ := utils.GetCurrentPrincipalID(c)
+	key, err := services.CreateAPIKey(role, userID, principalID, req)
+	if err != nil {
+		respondAPIKeyError(c, err)
+		return
+	}
+
+	logAPIKeyAction(c, "create_api_key", key.ID)
+	c.JSON(http.StatusCreated, models.Success(key))
+}
+
+// ListAPIKeys 密钥列表（含状态和最近使用情况，不含密钥本身）
+func ListAPIKeys(c *gin.Context) {
+	list, err := services.ListAPIKeys()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, "查询 API 密钥失败"))
+		return
+	}
+	c.JSON(http.StatusOK, models.Success(list))
+}
+
+// RevokeAPIKey 吊销 API 密钥
+func RevokeAPIKey(c *gin.Context) {
+	keyID, err := strconv.ParseUint(c.Param("key_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "密钥ID格式错误"))
+		return
+	}
+
+	if err := services.RevokeAPIKey(uint(keyID)); err != nil {
+		respondAPIKeyError(c, err)
+		return
+	}
+
+	logAPIKeyAction(c, "revoke_api_key", uint(keyID))
+	c.JSON(http.StatusOK, models.Success(nil))
+}
+
+func respondAPIKeyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrAPIKeyNotFound):
+		c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+	case errors.Is(err, services.ErrAPIKeyRevoked):
+		c.JSON(http.StatusConflict, models.Error(409, err.Error()))
+	case errors.Is(err, services.ErrAPIKeyScopeInvalid):
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+	case errors.Is(err, services.ErrAPIKeyScopeTooBroad):
+		c.JSON(http.StatusForbidden, models.Error(403, err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, models.Error(500, "操作失败"))
+	}
+}
+
+func logAPIKeyAction(c *gin.Context, action string, keyID uint) {
+	logOperation(c, action, strconv.FormatUint(uint64(keyID), 10))
+}
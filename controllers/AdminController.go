@@ -2,9 +2,11 @@ package controllers
 
 import (
 	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/dao"
 	"EmployeeManagementDemo/models"
 	"EmployeeManagementDemo/services"
 	"EmployeeManagementDemo/utils"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/xuri/excelize/v2"
@@ -17,7 +19,7 @@ import (
 	"time"
 )
 
-// AdminRegister 管理员注册接口
+// AdminRegister 管理员注册接口：凭邀请令牌注册；系统中还没有管理员时可凭初始化密钥注册第一个管理员
 func AdminRegister(c *gin.Context) {
 	var req models.AdminRegisterRequest
 
@@ -27,6 +29,26 @@ func AdminRegister(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, models.Error(400, utils.TranslateValidationErrors(err)))
 		return
 	}
+	if err := services.ValidatePassword(req.Password, req.Username); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	if req.InviteToken != "" {
+		redeemAdminInvitation(c, req)
+		return
+	}
+
+	// 初始化：只有尚无管理员时密钥才有效
+	bootstrap, err := services.AdminBootstrapAllowed()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, "服务器错误"))
+		return
+	}
+	if !bootstrap {
+		c.JSON(http.StatusForbidden, models.Error(403, "管理员注册需要邀请"))
+		return
+	}
 
 	// 验证管理员密钥
 	if valid := services.ValidateAdminSecret(req.SecretKey); !valid {
@@ -35,8 +57,8 @@ func AdminRegister(c *gin.Context) {
 		return
 	}
 
-	// 检查用户名是否存在
-	if exists, _ := services.CheckAdminNameExists(req.Username); exists {
+	// 检查用户名是否存在（与员工共用账号用户名空间）
+	if exists, _ := services.UsernameExists(req.Username); exists {
 		//c.JSON(http.StatusConflict, gin.H{"error": "管理员用户名已存在"})
 		c.JSON(http.StatusConflict, models.Error(400, "管理员用户名已存在"))
 
@@ -52,19 +74,26 @@ func AdminRegister(c *gin.Context) {
 
 	// 构建管理员对象
 	newAdmin := models.Admin{
-		AdminName:     req.Username,
-		AdminPassword: hashedPassword,
-		AdminEmail:    req.Email,
-		AdminPhone:    req.Phone,
+		AdminName:  req.Username,
+		AdminEmail: req.Email,
+		AdminPhone: req.Phone,
 		//Avatar:        "/avatars/default-admin.png", // 默认头像
 	}
 
 	// 创建管理员
-	if err := services.CreateAdmin(&newAdmin); err != nil {
+	if err := services.CreateAdmin(&newAdmin, hashedPassword); err != nil {
 		c.JSON(http.StatusInternalServerError, models.Error(500, "创建管理员失败"))
 		return
 	}
 
+	// 凭密钥注册的第一个管理员为超级管理员，之后的管理员通过邀请加入
+	if err := services.AssignRoleByName(models.RoleSuperAdmin, models.UserTypeAdmin, newAdmin.AdminID); err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, "分配管理员角色失败"))
+		return
+	}
+
+	services.LogOperation(newAdmin.GetAccountID(), models.ActorUser, "bootstrap_admin_register", "")
+
 	// 返回创建成功响应（隐藏敏感信息）
 	//c.JSON(http.StatusCreated, gin.H{
 	//	"message": "管理员账号创建成功",
@@ -99,7 +128,7 @@ func CreateEmployee(c *gin.Context) {
 		return
 	}
 
-	// 创建员工记录
+	// 创建员工记录（同时创建未设置密码的账号，员工可通过找回密码激活）
 	employee := models.Employee{
 		Username: req.Name,
 		DepID:    req.DepartmentID,
@@ -109,11 +138,16 @@ func CreateEmployee(c *gin.Context) {
 		Status:   "在职", // 默认状态
 	}
 
-	if err := config.DB.Create(&employee).Error; err != nil {
+	if err := services.CreateEmployee(&employee, ""); err != nil {
+		if errors.Is(err, services.ErrUsernameTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": "用户名已存在"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建失败: " + err.Error()})
 		return
 	}
 
+	logOperation(c, "create_employee", strconv.FormatUint(uint64(employee.EmpID), 10))
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "员工创建成功",
 		"emp_id":  employee.EmpID,
@@ -121,6 +155,12 @@ func CreateEmployee(c *gin.Context) {
 }
 
 func GetEmployees(c *gin.Context) {
+	scope, err := currentDataScope(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, "数据权限查询失败"))
+		return
+	}
+
 	// 分页参数
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
@@ -132,7 +172,8 @@ func GetEmployees(c *gin.Context) {
 		Model(&models.Employee{}).
 		Select("employees.*, departments.depart as dep_name").
 		Joins("LEFT JOIN departments ON employees.dep_id = departments.dep_id").
-		Where("employees.deleted_at IS NULL")
+		Where("employees.deleted_at IS NULL").
+		Scopes(dao.DepartmentScope(scope, "employees.dep_id")) // 部门负责人只能看到自己部门
 
 	// 部门筛选（支持多选）
 	if depIDs := c.QueryArray("dep_id"); len(depIDs) > 0 {
@@ -188,6 +229,11 @@ func GetEmployees(c *gin.Context) {
 
 func UpdateEmployee(c *gin.Context) {
 	empID := c.Param("emp_id")
+	scope, err := currentDataScope(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据权限查询失败"})
+		return
+	}
 
 	var req models.UpdateEmployeeRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -195,9 +241,9 @@ func UpdateEmployee(c *gin.Context) {
 		return
 	}
 
-	// 查找目标员工
+	// 查找目标员工（只能修改数据范围内的员工）
 	var employee models.Employee
-	if err := config.DB.First(&employee, empID).Error; err != nil {
+	if err := config.DB.Scopes(dao.DepartmentScope(scope, "employees.dep_id")).First(&employee, empID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "员工不存在"})
 		return
 	}
@@ -213,6 +259,8 @@ func UpdateEmployee(c *gin.Context) {
 	}
 
 	// 更新其他字段
+	oldDepID := employee.DepID
+	renamed := req.Name != "" && req.Name != employee.Username
 	if req.Name != "" {
 		employee.Username = req.Name
 	}
@@ -223,6 +271,11 @@ func UpdateEmployee(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "部门不存在"})
 			return
 		}
+		// 也不能调入数据范围之外的部门
+		if !scope.Contains(req.DepartmentID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "无权将员工调入该部门"})
+			return
+		}
 		employee.DepID = req.DepartmentID
 	}
 	if req.Position != "" {
@@ -235,11 +288,33 @@ func UpdateEmployee(c *gin.Context) {
 		employee.Status = req.Status
 	}
 
-	if err := config.DB.Save(&employee).Error; err != nil {
+	// 改名需同步账号用户名，调动部门需同步部门群，并撤销其在原部门担任的负责人
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if renamed {
+			if err := services.RenameAccount(tx, employee.GetAccountID(), employee.Username); err != nil {
+				return err
+			}
+		}
+		if err := tx.Save(&employee).Error; err != nil {
+			return err
+		}
+		if employee.DepID != oldDepID {
+			if err := services.RevokeEmployeeManagement(tx, employee.EmpID); err != nil {
+				return err
+			}
+		}
+		return services.MoveDepartmentChannelMember(tx, employee.GetAccountID(), oldDepID, employee.DepID)
+	})
+	if errors.Is(err, services.ErrUsernameTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": "用户名已存在"})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新失败: " + err.Error()})
 		return
 	}
 
+	logOperation(c, "update_employee", empID)
 	c.JSON(http.StatusOK, gin.H{"message": "员工信息更新成功"})
 }
 
@@ -257,35 +332,57 @@ func UpdateEmployee(c *gin.Context) {
 // @Router /employees/{id} [delete]
 func DeleteEmployee(c *gin.Context) {
 	empID := c.Param("emp_id")
-	adminId, err := utils.GetCurrentUserID(c)
-	if err != nil {
+	if _, err := utils.GetCurrentUserID(c); err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "请先登录!"})
 		return
 	}
+	scope, err := currentDataScope(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据权限查询失败"})
+		return
+	}
 
-	// 执行删除（硬删除，如需软删除需修改模型）
-	if err := config.DB.Delete(&models.Employee{}, empID).Error; err != nil {
+	// 只能删除数据范围内的员工
+	var employee models.Employee
+	if err := config.DB.Scopes(dao.DepartmentScope(scope, "employees.dep_id")).First(&employee, empID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "员工不存在"})
+		return
+	}
+
+	// 执行删除（硬删除，如需软删除需修改模型），同时退出部门群；负责的部门一并撤销负责人
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&employee).Error; err != nil {
+			return err
+		}
+		if err := services.RevokeEmployeeManagement(tx, employee.EmpID); err != nil {
+			return err
+		}
+		return services.MoveDepartmentChannelMember(tx, employee.GetAccountID(), employee.DepID, 0)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除失败: " + err.Error()})
 		return
 	}
 
 	// 发送操作日志
-	services.SendLogToRabbitMQ(map[string]interface{}{
-		"user_id":   adminId,
-		"action":    "delete_employee",
-		"target_id": empID,
-	})
+	logOperation(c, "delete_employee", empID)
 
 	c.JSON(http.StatusOK, gin.H{"message": "员工删除成功"})
 }
 
 func ApproveLeaveRequest(c *gin.Context) {
-	// 获取管理员ID
-	adminID, err := utils.GetCurrentUserID(c)
+	// 获取审批人ID（管理员或部门负责人）
+	approverID, err := utils.GetCurrentUserID(c)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "请先登录"})
 		return
 	}
+	role, _ := utils.GetCurrentUserRole(c)
+	scope, err := currentDataScope(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据权限查询失败"})
+		return
+	}
 
 	leaveID := c.Param("id")
 	var req models.ApproveLeaveRequest
@@ -294,16 +391,31 @@ func ApproveLeaveRequest(c *gin.Context) {
 		return
 	}
 
-	// 查找待审批的请假记录
+	// 查找待审批的请假记录（只能审批数据范围内员工的申请）
 	var leave models.LeaveRequest
-	if err := config.DB.First(&leave, "id = ? AND status = 'pending'", leaveID).Error; err != nil {
+	if err := config.DB.
+		Select("leave_requests.*").
+		Joins("JOIN employees ON employees.emp_id = leave_requests.emp_id").
+		Scopes(dao.DepartmentScope(scope, "employees.dep_id")).
+		First(&leave, "leave_requests.id = ? AND leave_requests.status = 'pending'", leaveID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "未找到待审批的申请"})
 		return
 	}
+	// 部门负责人不能审批自己的申请
+	if role == models.UserTypeEmployee && leave.EmpID == approverID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "不能审批自己的申请"})
+		return
+	}
 
-	// 更新审批状态和管理员ID
-	leave.AdminID = &adminID
+	// 更新审批状态和审批人
+	now := time.Now()
+	if role == models.UserTypeAdmin {
+		leave.AdminID = &approverID
+	} else {
+		leave.ApproverID = &approverID
+	}
 	leave.Status = req.Status
+	leave.ApprovedAt = &now
 	if err := config.DB.Save(&leave).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "审批失败"})
 		return
@@ -313,11 +425,20 @@ func ApproveLeaveRequest(c *gin.Context) {
 }
 
 func GetAllLeaveRequests(c *gin.Context) {
+	scope, err := currentDataScope(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据权限查询失败"})
+		return
+	}
+
 	status := c.Query("status") // 支持按状态过滤
-	query := config.DB.Model(&models.LeaveRequest{})
+	query := config.DB.Model(&models.LeaveRequest{}).
+		Select("leave_requests.*").
+		Joins("JOIN employees ON employees.emp_id = leave_requests.emp_id").
+		Scopes(dao.DepartmentScope(scope, "employees.dep_id"))
 
 	if status != "" {
-		query = query.Where("status = ?", status)
+		query = query.Where("leave_requests.status = ?", status)
 	}
 
 	var leaves []models.LeaveRequest
@@ -326,36 +447,119 @@ func GetAllLeaveRequests(c *gin.Context) {
 	c.JSON(http.StatusOK, leaves)
 }
 
+// GetAttendanceRecords 查看员工考勤（按数据范围过滤，可按员工、部门筛选）
+func GetAttendanceRecords(c *gin.Context) {
+	scope, err := currentDataScope(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, "数据权限查询失败"))
+		return
+	}
+
+	yearMonth := c.Query("month")
+	startTime, err := time.ParseInLocation("2006-01", yearMonth, time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "月份格式无效（正确示例：2025-03）"))
+		return
+	}
+	startOfMonth := time.Date(startTime.Year(), startTime.Month(), 1, 0, 0, 0, 0, startTime.Location())
+	endOfMonth := startOfMonth.AddDate(0, 1, -1)
+
+	query := config.DB.Model(&models.SignRecord{}).
+		Select("sign_records.*, employees.username, employees.dep_id").
+		Joins("JOIN employees ON employees.emp_id = sign_records.emp_id").
+		Where("sign_records.date BETWEEN ? AND ?", startOfMonth.Format("2006-01-02"), endOfMonth.Format("2006-01-02")).
+		Scopes(dao.DepartmentScope(scope, "employees.dep_id"))
+
+	if empID := c.Query("emp_id"); empID != "" {
+		query = query.Where("sign_records.emp_id = ?", empID)
+	}
+	if depID := c.Query("dep_id"); depID != "" {
+		query = query.Where("employees.dep_id = ?", depID)
+	}
+
+	var records []models.AttendanceRecordDTO
+	if err := query.Order("sign_records.date DESC, sign_records.emp_id").Find(&records).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, "查询失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(records))
+}
+
+// 当前用户的数据范围（部门负责人仅限所负责部门）
+func currentDataScope(c *gin.Context) (*models.DataScope, error) {
+	userID, err := utils.GetCurrentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+	role, err := utils.GetCurrentUserRole(c)
+	if err != nil {
+		return nil, err
+	}
+	return services.GetDataScope(role, userID)
+}
+
 // controllers/admin.go
 func KickUser(c *gin.Context) {
-	userID := c.Param("user_id")
-	adminID, err2 := utils.GetCurrentUserID(c)
-	if err2 != nil {
+	userID := c.Param("user_id") // 账号ID
+	if _, err := utils.GetCurrentUserID(c); err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "请先登录"})
 		return
 	}
+	principalID, err := strconv.ParseUint(userID, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "用户ID格式错误"})
+		return
+	}
 
 	// 记录踢出时间戳
-	invalidateKey := "user_invalid:" + userID
-	err := config.Rdb.Set(config.Ctx, invalidateKey, time.Now().Unix(), 0).Err()
+	err = services.InvalidateUserSessions(uint(principalID))
 	if err != nil {
 		c.JSON(500, gin.H{"error": "操作失败"})
 		return
 	}
 
 	//发送踢人日志消息
-	logData := map[string]interface{}{
-		"user_id":   adminID,
-		"action":    "kick_user",
-		"target_id": userID,
-	}
-	services.SendLogToRabbitMQ(logData)
+	logOperation(c, "kick_user", userID)
 
 	c.JSON(200, gin.H{"message": "用户已被踢出"})
 }
 
+// UnlockLogin 解除登录锁定（按用户名和/或 IP）
+func UnlockLogin(c *gin.Context) {
+	if _, err := utils.GetCurrentUserID(c); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "请先登录"})
+		return
+	}
+
+	var req models.UnlockLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, utils.TranslateValidationErrors(err)))
+		return
+	}
+	if req.Username == "" && req.IP == "" {
+		c.JSON(http.StatusBadRequest, models.Error(400, "用户名和IP至少填写一项"))
+		return
+	}
+
+	if err := services.UnlockLogin(req.Username, req.IP); err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, "解锁失败"))
+		return
+	}
+
+	logOperation(c, "unlock_login", strings.TrimSpace(req.Username+" "+req.IP))
+
+	c.JSON(http.StatusOK, models.Success(nil))
+}
+
 // ExportEmployees 导出接口(事务版)
 func ExportEmployees(c *gin.Context) {
+	scope, err := currentDataScope(c)
+	if err != nil {
+		c.JSON(500, models.Error(500, "数据权限查询失败"))
+		return
+	}
+
 	// 开启事务（隔离级别设为REPEATABLE READ）
 	tx := config.DB.Begin()
 	if tx.Error != nil {
@@ -373,7 +577,8 @@ func ExportEmployees(c *gin.Context) {
 	query := tx.Model(&models.Employee{}).
 		Select("employees.*, departments.depart as dep_name").
 		Joins("LEFT JOIN departments ON employees.dep_id = departments.dep_id").
-		Where("employees.deleted_at IS NULL")
+		Where("employees.deleted_at IS NULL").
+		Scopes(dao.DepartmentScope(scope, "employees.dep_id"))
 
 	if err := query.Find(&employeesWithDepNameDto).Error; err != nil {
 		tx.Rollback()
@@ -550,8 +755,8 @@ func ImportEmployees(c *gin.Context) {
 				return fmt.Errorf("第%d行数据错误: %v", i+1, err)
 			}
 
-			// 数据库写入（使用事务对象）
-			if err := tx.Create(&emp).Error; err != nil {
+			// 数据库写入（使用事务对象），同时创建账号
+			if err := services.CreateEmployeeWithTx(tx, &emp, ""); err != nil {
 				return fmt.Errorf("第%d行保存失败: %v", i+1, err)
 			}
 		}
@@ -564,6 +769,7 @@ func ImportEmployees(c *gin.Context) {
 		return
 	}
 
+	logOperation(c, "import_employees", file.Filename)
 	c.JSON(200, models.Success(nil))
 }
 
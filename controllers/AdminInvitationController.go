@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/services"
+	"EmployeeManagementDemo/utils"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// CreateAdminInvitation 邀请新管理员（邮件发送一次性注册链接）
+func CreateAdminInvitation(c *gin.Context) {
+	var req models.CreateAdminInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, utils.TranslateValidationErrors(err)))
+		return
+	}
+
+	accountID, _ := utils.GetCurrentPrincipalID(c)
+	userType, _ := utils.GetCurrentUserRole(c)
+	inv, err := services.CreateAdminInvitation(accountID, userType, req.Email, req.RoleID)
+	if err != nil {
+		respondInvitationError(c, err)
+		return
+	}
+
+	logInvitationAction(c, "create_admin_invitation", inv.ID)
+	c.JSON(http.StatusCreated, models.Success(inv))
+}
+
+// ListAdminInvitations 邀请列表（含状态）
+func ListAdminInvitations(c *gin.Context) {
+	list, err := services.ListAdminInvitations()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, "查询邀请失败"))
+		return
+	}
+	c.JSON(http.StatusOK, models.Success(list))
+}
+
+// RevokeAdminInvitation 撤销尚未使用的邀请
+func RevokeAdminInvitation(c *gin.Context) {
+	invID, err := strconv.ParseUint(c.Param("invitation_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "邀请ID格式错误"))
+		return
+	}
+
+	if err := services.RevokeAdminInvitation(uint(invID)); err != nil {
+		respondInvitationError(c, err)
+		return
+	}
+
+	logInvitationAction(c, "revoke_admin_invitation", uint(invID))
+	c.JSON(http.StatusOK, models.Success(nil))
+}
+
+// redeemAdminInvitation 凭邀请令牌完成管理员注册
+func redeemAdminInvitation(c *gin.Context, req models.AdminRegisterRequest) {
+	admin, err := services.RedeemAdminInvitation(req.InviteToken, req.RegisterRequest)
+	if err != nil {
+		respondInvitationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.Success(admin))
+}
+
+func respondInvitationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvitationNotFound), errors.Is(err, services.ErrRoleNotFound):
+		c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+	case errors.Is(err, services.ErrInvitationNotPending), errors.Is(err, services.ErrUsernameTaken):
+		c.JSON(http.StatusConflict, models.Error(409, err.Error()))
+	case errors.Is(err, services.ErrRoleNotGrantable), errors.Is(err, services.ErrInviterNotAdmin):
+		c.JSON(http.StatusForbidden, models.Error(403, err.Error()))
+	case errors.Is(err, services.ErrInvitationInvalid), errors.Is(err, services.ErrInvitationEmailMismatch):
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, models.Error(500, "操作失败"))
+	}
+}
+
+func logInvitationAction(c *gin.Context, action string, invID uint) {
+	logOperation(c, action, strconv.FormatUint(uint64(invID), 10))
+}
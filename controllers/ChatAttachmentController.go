@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/services"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+// 聊天附件：先上传得到附件ID，再通过 WebSocket 发送附件消息
+
+// UploadChatAttachment 上传附件（multipart 字段 file），大小和类型按 chat.attachments 配置校验
+func UploadChatAttachment(c *gin.Context) {
+	// 额外留 1MB 给 multipart 边界和其他字段
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, config.Cfg.Chat.Attachments.MaxSize+1<<20)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, models.Error(413, services.ErrAttachmentTooLarge.Error()))
+			return
+		}
+		c.JSON(http.StatusBadRequest, models.Error(400, "文件上传失败"))
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "文件上传失败"))
+		return
+	}
+	defer file.Close()
+
+	att, err := services.UploadChatAttachment(c.Request.Context(), currentChatUser(c), fileHeader.Filename, fileHeader.Size, file)
+	if err != nil {
+		respondAttachmentError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, models.Success(att.ToDTO()))
+}
+
+// DownloadChatAttachment 下载附件，仅上传者和能看到该附件消息的用户可下载
+func DownloadChatAttachment(c *gin.Context) {
+	serveChatAttachment(c, false)
+}
+
+// DownloadChatAttachmentThumbnail 图片附件的缩略图
+func DownloadChatAttachmentThumbnail(c *gin.Context) {
+	serveChatAttachment(c, true)
+}
+
+func serveChatAttachment(c *gin.Context, thumbnail bool) {
+	id, err := strconv.ParseUint(c.Param("attachment_id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, models.Error(400, "附件ID格式错误"))
+		return
+	}
+	att, r, err := services.OpenChatAttachment(c.Request.Context(), currentChatUser(c), uint(id), thumbnail)
+	if err != nil {
+		respondAttachmentError(c, err)
+		return
+	}
+	defer r.Close()
+
+	contentType, size, disposition := att.MIMEType, att.Size, "attachment"
+	if thumbnail {
+		contentType, size, disposition = att.ThumbnailType(), -1, "inline"
+	}
+	// 始终带 nosniff，防止浏览器把上传的文件当作页面执行
+	c.DataFromReader(http.StatusOK, size, contentType, r, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType(disposition, map[string]string{"filename": att.FileName}),
+		"X-Content-Type-Options": "nosniff",
+		"Cache-Control":          "private, max-age=86400",
+	})
+}
+
+func respondAttachmentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrAttachmentNotFound):
+		c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+	case errors.Is(err, services.ErrAttachmentTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, models.Error(413, err.Error()))
+	case errors.Is(err, services.ErrAttachmentType):
+		c.JSON(http.StatusUnsupportedMediaType, models.Error(415, err.Error()))
+	case errors.Is(err, services.ErrAttachmentEmpty):
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, models.Error(500, "操作失败"))
+	}
+}
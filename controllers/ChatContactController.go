@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/services"
+	"EmployeeManagementDemo/utils"
+	"EmployeeManagementDemo/websocket"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// 聊天联系人，均以账号ID标识；同部门同事无需申请即为联系人
+
+// ListContacts 联系人列表（含同部门同事）
+func ListContacts(c *gin.Context) {
+	contacts, err := services.ListContacts(currentChatUser(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, "查询联系人失败"))
+		return
+	}
+	c.JSON(http.StatusOK, models.Success(contacts))
+}
+
+// RemoveContact 删除联系人
+func RemoveContact(c *gin.Context) {
+	targetID, ok := parseContactUserID(c)
+	if !ok {
+		return
+	}
+	if err := services.RemoveContact(currentChatUser(c), targetID); err != nil {
+		respondContactError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.Success(nil))
+}
+
+// ListContactRequests 待处理的好友申请，?direction=outgoing 查询自己发出的
+func ListContactRequests(c *gin.Context) {
+	requests, err := services.ListContactRequests(currentChatUser(c), c.Query("direction") == "outgoing")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, "查询好友申请失败"))
+		return
+	}
+	c.JSON(http.StatusOK, models.Success(requests))
+}
+
+// AddContact 发出好友申请，对方已向自己申请时直接成为联系人
+func AddContact(c *gin.Context) {
+	var req models.AddContactRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, utils.TranslateValidationErrors(err)))
+		return
+	}
+
+	userID := currentChatUser(c)
+	accepted, err := services.AddContact(userID, req.UserID, req.Message)
+	if err != nil {
+		respondContactError(c, err)
+		return
+	}
+	if accepted {
+		websocket.DefaultHub.NotifyContact(models.ChatEventContactAccepted, req.UserID, userID, "")
+	} else {
+		websocket.DefaultHub.NotifyContact(models.ChatEventContactRequest, req.UserID, userID, req.Message)
+	}
+	c.JSON(http.StatusOK, models.Success(gin.H{"accepted": accepted}))
+}
+
+// AcceptContact 通过对方的好友申请
+func AcceptContact(c *gin.Context) {
+	requesterID, ok := parseContactUserID(c)
+	if !ok {
+		return
+	}
+	userID := currentChatUser(c)
+	if err := services.AcceptContact(userID, requesterID); err != nil {
+		respondContactError(c, err)
+		return
+	}
+	websocket.DefaultHub.NotifyContact(models.ChatEventContactAccepted, requesterID, userID, "")
+	c.JSON(http.StatusOK, models.Success(nil))
+}
+
+// DeleteContactRequest 拒绝对方的申请或撤回自己发出的申请
+func DeleteContactRequest(c *gin.Context) {
+	otherID, ok := parseContactUserID(c)
+	if !ok {
+		return
+	}
+	if err := services.DeleteContactRequest(currentChatUser(c), otherID); err != nil {
+		respondContactError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.Success(nil))
+}
+
+func ListBlockedUsers(c *gin.Context) {
+	users, err := services.ListBlockedUsers(currentChatUser(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, "查询屏蔽列表失败"))
+		return
+	}
+	c.JSON(http.StatusOK, models.Success(users))
+}
+
+// BlockUser 屏蔽用户：对方不能再发消息和好友申请，已有的联系人关系解除
+func BlockUser(c *gin.Context) {
+	targetID, ok := parseContactUserID(c)
+	if !ok {
+		return
+	}
+	if err := services.BlockUser(currentChatUser(c), targetID); err != nil {
+		respondContactError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.Success(nil))
+}
+
+func UnblockUser(c *gin.Context) {
+	targetID, ok := parseContactUserID(c)
+	if !ok {
+		return
+	}
+	if err := services.UnblockUser(currentChatUser(c), targetID); err != nil {
+		respondContactError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.Success(nil))
+}
+
+func parseContactUserID(c *gin.Context) (int, bool) {
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil || userID <= 0 {
+		c.JSON(http.StatusBadRequest, models.Error(400, "用户ID格式错误"))
+		return 0, false
+	}
+	return userID, true
+}
+
+func respondContactError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound), errors.Is(err, services.ErrContactNotFound),
+		errors.Is(err, services.ErrContactRequestNotFound), errors.Is(err, services.ErrNotBlocked):
+		c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+	case errors.Is(err, services.ErrContactBlocked):
+		c.JSON(http.StatusForbidden, models.Error(403, err.Error()))
+	case errors.Is(err, services.ErrContactExists), errors.Is(err, services.ErrContactBlockedByMe):
+		c.JSON(http.StatusConflict, models.Error(409, err.Error()))
+	case errors.Is(err, services.ErrContactSelf):
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, models.Error(500, "操作失败"))
+	}
+}
@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/services"
+	"EmployeeManagementDemo/utils"
+	"EmployeeManagementDemo/websocket"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// 聊天记录查询（REST），用户以账号ID标识，只能看到自己可见的消息
+
+// ListConversations 会话列表，含最后一条消息和未读数
+func ListConversations(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	convs, err := services.ListConversations(currentChatUser(c), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, "查询会话失败"))
+		return
+	}
+	c.JSON(http.StatusOK, models.Success(convs))
+}
+
+// ListConversationMessages 会话历史，?before=<消息ID>&limit= 游标分页
+func ListConversationMessages(c *gin.Context) {
+	before, limit, ok := parseChatCursor(c)
+	if !ok {
+		return
+	}
+	page, err := services.ListConversationMessages(currentChatUser(c), c.Param("conversation_id"), before, limit)
+	if err != nil {
+		respondChatError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.Success(page))
+}
+
+// SearchChatMessages 全文检索消息，?q=关键词[&conversation_id=][&before=][&limit=]
+func SearchChatMessages(c *gin.Context) {
+	before, limit, ok := parseChatCursor(c)
+	if !ok {
+		return
+	}
+	page, err := services.SearchChatMessages(currentChatUser(c), c.Query("q"), c.Query("conversation_id"), before, limit)
+	if err != nil {
+		respondChatError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.Success(page))
+}
+
+// MarkConversationRead 把会话中发给自己的消息全部标记为已读，并给发送方推送已读回执
+func MarkConversationRead(c *gin.Context) {
+	userID := currentChatUser(c)
+	conversationID := c.Param("conversation_id")
+	msgs, err := services.MarkConversationRead(userID, conversationID)
+	if err != nil {
+		respondChatError(c, err)
+		return
+	}
+	websocket.DefaultHub.NotifyMessagesRead(userID, conversationID, msgs)
+	c.JSON(http.StatusOK, models.Success(gin.H{"read": len(msgs)}))
+}
+
+// ListMessageReceipts 自己发出的消息在各接收方处的送达、已读状态
+func ListMessageReceipts(c *gin.Context) {
+	receipts, err := services.ListMessageReceipts(currentChatUser(c), c.Param("msg_key"))
+	if err != nil {
+		respondChatError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.Success(receipts))
+}
+
+// maxPresenceIDs 单次查询在线状态的账号数上限
+const maxPresenceIDs = 200
+
+// GetPresence 批量查询在线状态，?ids=1,2,3（账号ID）；屏蔽了自己的用户显示为离线
+func GetPresence(c *gin.Context) {
+	var ids []int
+	for _, raw := range strings.Split(c.Query("ids"), ",") {
+		if raw = strings.TrimSpace(raw); raw == "" {
+			continue
+		}
+		id, err := strconv.Atoi(raw)
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, models.Error(400, "ids 参数格式错误"))
+			return
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 || len(ids) > maxPresenceIDs {
+		c.JSON(http.StatusBadRequest, models.Error(400, fmt.Sprintf("ids 须为 1 到 %d 个账号ID", maxPresenceIDs)))
+		return
+	}
+
+	presence, err := websocket.DefaultHub.GetPresence(currentChatUser(c), ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, "查询在线状态失败"))
+		return
+	}
+	c.JSON(http.StatusOK, models.Success(presence))
+}
+
+// SetPresence 设置自己的在线状态（online/away/busy）
+func SetPresence(c *gin.Context) {
+	var req models.SetPresenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, utils.TranslateValidationErrors(err)))
+		return
+	}
+	if err := websocket.DefaultHub.SetPresenceStatus(currentChatUser(c), req.Status); err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, "设置在线状态失败"))
+		return
+	}
+	c.JSON(http.StatusOK, models.Success(nil))
+}
+
+func parseChatCursor(c *gin.Context) (uint, int, bool) {
+	var before uint64
+	if raw := c.Query("before"); raw != "" {
+		var err error
+		if before, err = strconv.ParseUint(raw, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, models.Error(400, "before 参数格式错误"))
+			return 0, 0, false
+		}
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	return uint(before), limit, true
+}
+
+func respondChatError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrConversationInvalid), errors.Is(err, services.ErrChatMessageNotFound):
+		c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+	case errors.Is(err, services.ErrSearchQueryInvalid):
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+	case errors.Is(err, services.ErrChatMessageRecalled), errors.Is(err, services.ErrChatEditExpired),
+		errors.Is(err, services.ErrChatRecallExpired):
+		c.JSON(http.StatusConflict, models.Error(409, err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, models.Error(500, "操作失败"))
+	}
+}
+
+// EditChatMessage 编辑自己发出的消息（限发送后一段时间内）
+func EditChatMessage(c *gin.Context) {
+	var req models.EditChatMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, utils.TranslateValidationErrors(err)))
+		return
+	}
+	msgs, err := services.EditChatMessage(currentChatUser(c), c.Param("msg_key"), req.Content)
+	if err != nil {
+		respondChatError(c, err)
+		return
+	}
+	websocket.DefaultHub.NotifyMessageChanged(models.ChatEventEdited, msgs)
+	c.JSON(http.StatusOK, models.Success(nil))
+}
+
+// RecallChatMessage 撤回自己发出的消息（限发送后一段时间内）
+func RecallChatMessage(c *gin.Context) {
+	msgs, err := services.RecallChatMessage(currentChatUser(c), c.Param("msg_key"))
+	if err != nil {
+		respondChatError(c, err)
+		return
+	}
+	websocket.DefaultHub.NotifyMessageChanged(models.ChatEventRecalled, msgs)
+	c.JSON(http.StatusOK, models.Success(nil))
+}
+
+// DeleteChatMessage 仅对自己删除消息
+func DeleteChatMessage(c *gin.Context) {
+	if err := services.DeleteChatMessageForMe(currentChatUser(c), c.Param("msg_key")); err != nil {
+		respondChatError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.Success(nil))
+}
+
+// RemoveChatMessage 管理员删除违规消息，所有人不再可见
+func RemoveChatMessage(c *gin.Context) {
+	msgKey := c.Param("msg_key")
+	msgs, err := services.RemoveChatMessage(msgKey)
+	if err != nil {
+		respondChatError(c, err)
+		return
+	}
+	websocket.DefaultHub.NotifyMessageChanged(models.ChatEventRemoved, msgs)
+
+	logOperation(c, "remove_chat_message", msgKey)
+	c.JSON(http.StatusOK, models.Success(nil))
+}
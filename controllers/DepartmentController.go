@@ -5,8 +5,12 @@ import (
 	"EmployeeManagementDemo/models"
 	"EmployeeManagementDemo/services"
 	"EmployeeManagementDemo/utils"
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 	"net/http"
+	"strconv"
 )
 
 func CreateDepartment(c *gin.Context) {
@@ -23,9 +27,15 @@ func CreateDepartment(c *gin.Context) {
 		return
 	}
 
-	// 创建新部门
+	// 创建新部门及其部门群
 	department := models.Department{Depart: req.Depart}
-	if err := config.DB.Create(&department).Error; err != nil {
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&department).Error; err != nil {
+			return err
+		}
+		return services.CreateDepartmentChannel(tx, &department)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建失败: " + err.Error()})
 		return
 	}
@@ -72,9 +82,15 @@ func UpdateDepartment(c *gin.Context) {
 		}
 	}
 
-	// 更新字段
+	// 更新字段，部门群随之改名
 	department.Depart = req.Depart
-	if err := config.DB.Save(&department).Error; err != nil {
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&department).Error; err != nil {
+			return err
+		}
+		return services.RenameDepartmentChannel(tx, department.DepID, department.Depart)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新失败: " + err.Error()})
 		return
 	}
@@ -96,8 +112,7 @@ func UpdateDepartment(c *gin.Context) {
 // @Router /departments/{id} [delete]
 func DeleteDepartment(c *gin.Context) {
 	depID := c.Param("dep_id")
-	adminId, err := utils.GetCurrentUserID(c)
-	if err != nil {
+	if _, err := utils.GetCurrentUserID(c); err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "请先登录!"})
 		return
 	}
@@ -110,22 +125,73 @@ func DeleteDepartment(c *gin.Context) {
 		return
 	}
 
-	// 执行删除
-	if err := config.DB.Delete(&models.Department{}, depID).Error; err != nil {
+	var department models.Department
+	if err := config.DB.First(&department, depID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "部门不存在"})
+		return
+	}
+
+	// 执行删除，部门群一并解散
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&department).Error; err != nil {
+			return err
+		}
+		return services.DeleteDepartmentChannel(tx, department.DepID)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除失败: " + err.Error()})
 		return
 	}
 
+	// 原负责人不再负责任何部门时收回 manager 角色
+	if department.ManagerID != nil {
+		if err := services.ReleaseManagerRole(*department.ManagerID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "回收负责人角色失败"})
+			return
+		}
+	}
+
 	// 发送操作日志
-	services.SendLogToRabbitMQ(map[string]interface{}{
-		"user_id":   adminId,
-		"action":    "delete_department",
-		"target_id": depID, // 或更详细的描述
-	})
+	logOperation(c, "delete_department", depID)
 
 	c.JSON(http.StatusOK, gin.H{"message": "部门删除成功"})
 }
 
+// SetDepartmentManager 任命或撤销部门负责人
+func SetDepartmentManager(c *gin.Context) {
+	depID, err := strconv.ParseUint(c.Param("dep_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "部门ID格式错误"))
+		return
+	}
+	if _, err := utils.GetCurrentUserID(c); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "请先登录!"})
+		return
+	}
+
+	var req models.SetDepartmentManagerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, utils.TranslateValidationErrors(err)))
+		return
+	}
+
+	if err := services.SetDepartmentManager(uint(depID), req.EmpID); err != nil {
+		switch {
+		case errors.Is(err, services.ErrDepartmentNotFound):
+			c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+		case errors.Is(err, services.ErrUserNotFound):
+			c.JSON(http.StatusBadRequest, models.Error(400, "员工不存在"))
+		default:
+			c.JSON(http.StatusInternalServerError, models.Error(500, "任命失败"))
+		}
+		return
+	}
+
+	logOperation(c, "set_department_manager", fmt.Sprintf("%d:%d", depID, req.EmpID))
+
+	c.JSON(http.StatusOK, models.Success(nil))
+}
+
 // controllers/department_controller.go
 func GetDepartmentSalaryAverages(c *gin.Context) {
 	data, err := services.GetDepartmentAvgSalaries()
@@ -6,6 +6,7 @@ import (
 	"EmployeeManagementDemo/services"
 	"EmployeeManagementDemo/utils"
 	"database/sql"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"time"
@@ -23,6 +24,10 @@ func Register(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, models.Error(400, "两次密码输入不相同"))
 		return
 	}
+	if err := services.ValidatePassword(req.Password, req.Username); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
 
 	// 密码加密
 	hashedPassword, err := services.HashPassword(req.Password)
@@ -34,13 +39,16 @@ func Register(c *gin.Context) {
 	// 创建员工账号
 	emp := models.Employee{
 		Username: req.Username,
-		Password: hashedPassword,
 		Email:    req.Email,
 		Phone:    req.Phone,
 	}
 
-	if err := services.CreateEmployee(&emp); err != nil {
-		c.JSON(http.StatusConflict, models.Error(409, "用户名已存在"))
+	if err := services.CreateEmployee(&emp, hashedPassword); err != nil {
+		if errors.Is(err, services.ErrUsernameTaken) {
+			c.JSON(http.StatusConflict, models.Error(409, "用户名已存在"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.Error(500, "注册失败"))
 		return
 	}
 
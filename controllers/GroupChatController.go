@@ -0,0 +1,206 @@
+package controllers
+
+import (
+	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/services"
+	"EmployeeManagementDemo/utils"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// 群聊管理，成员均以账号ID标识
+
+// CreateGroup 创建群聊，创建者为群主
+func CreateGroup(c *gin.Context) {
+	var req models.CreateGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, utils.TranslateValidationErrors(err)))
+		return
+	}
+
+	group, err := services.CreateGroup(currentChatUser(c), req)
+	if err != nil {
+		respondGroupError(c, err)
+		return
+	}
+
+	logGroupAction(c, "create_group", group.ID)
+	c.JSON(http.StatusCreated, models.Success(group))
+}
+
+// ListMyGroups 我所在的群聊
+func ListMyGroups(c *gin.Context) {
+	groups, err := services.ListMyGroups(currentChatUser(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, "查询群聊失败"))
+		return
+	}
+	c.JSON(http.StatusOK, models.Success(groups))
+}
+
+func ListGroupMembers(c *gin.Context) {
+	members, err := services.ListGroupMembers(c.Param("group_id"), currentChatUser(c))
+	if err != nil {
+		respondGroupError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.Success(members))
+}
+
+// UpdateGroup 修改群名、群签名
+func UpdateGroup(c *gin.Context) {
+	var req models.UpdateGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, utils.TranslateValidationErrors(err)))
+		return
+	}
+
+	groupID := c.Param("group_id")
+	if err := services.UpdateGroup(groupID, currentChatUser(c), req); err != nil {
+		respondGroupError(c, err)
+		return
+	}
+
+	logGroupAction(c, "update_group", groupID)
+	c.JSON(http.StatusOK, models.Success(nil))
+}
+
+func SetGroupIcon(c *gin.Context) {
+	var req models.SetGroupIconRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, utils.TranslateValidationErrors(err)))
+		return
+	}
+
+	groupID := c.Param("group_id")
+	if err := services.SetGroupIcon(groupID, currentChatUser(c), req.GroupIcon); err != nil {
+		respondGroupError(c, err)
+		return
+	}
+
+	logGroupAction(c, "set_group_icon", groupID)
+	c.JSON(http.StatusOK, models.Success(nil))
+}
+
+func AddGroupMembers(c *gin.Context) {
+	var req models.AddGroupMembersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, utils.TranslateValidationErrors(err)))
+		return
+	}
+
+	groupID := c.Param("group_id")
+	added, err := services.AddGroupMembers(groupID, currentChatUser(c), req.UserIDs)
+	if err != nil {
+		respondGroupError(c, err)
+		return
+	}
+
+	logGroupAction(c, "add_group_members", groupID)
+	c.JSON(http.StatusOK, models.Success(gin.H{"added": added}))
+}
+
+// RemoveGroupMember 移除成员；user_id 为自己时即退群
+func RemoveGroupMember(c *gin.Context) {
+	targetID, ok := parseGroupMemberID(c)
+	if !ok {
+		return
+	}
+
+	groupID := c.Param("group_id")
+	if err := services.RemoveGroupMember(groupID, currentChatUser(c), targetID); err != nil {
+		respondGroupError(c, err)
+		return
+	}
+
+	logGroupAction(c, "remove_group_member", groupID)
+	c.JSON(http.StatusOK, models.Success(nil))
+}
+
+// SetGroupMemberRole 任免群管理员
+func SetGroupMemberRole(c *gin.Context) {
+	targetID, ok := parseGroupMemberID(c)
+	if !ok {
+		return
+	}
+	var req models.SetGroupMemberRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, utils.TranslateValidationErrors(err)))
+		return
+	}
+
+	groupID := c.Param("group_id")
+	if err := services.SetGroupMemberRole(groupID, currentChatUser(c), targetID, req.Role); err != nil {
+		respondGroupError(c, err)
+		return
+	}
+
+	logGroupAction(c, "set_group_member_role", groupID)
+	c.JSON(http.StatusOK, models.Success(nil))
+}
+
+func TransferGroupOwner(c *gin.Context) {
+	var req models.TransferGroupOwnerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, utils.TranslateValidationErrors(err)))
+		return
+	}
+
+	groupID := c.Param("group_id")
+	if err := services.TransferGroupOwner(groupID, currentChatUser(c), req.UserID); err != nil {
+		respondGroupError(c, err)
+		return
+	}
+
+	logGroupAction(c, "transfer_group_owner", groupID)
+	c.JSON(http.StatusOK, models.Success(nil))
+}
+
+// DissolveGroup 解散群聊
+func DissolveGroup(c *gin.Context) {
+	groupID := c.Param("group_id")
+	if err := services.DissolveGroup(groupID, currentChatUser(c)); err != nil {
+		respondGroupError(c, err)
+		return
+	}
+
+	logGroupAction(c, "dissolve_group", groupID)
+	c.JSON(http.StatusOK, models.Success(nil))
+}
+
+// currentChatUser 聊天中以账号ID标识用户
+func currentChatUser(c *gin.Context) int {
+	principalID, _ := utils.GetCurrentPrincipalID(c)
+	return int(principalID)
+}
+
+func parseGroupMemberID(c *gin.Context) (int, bool) {
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil || userID <= 0 {
+		c.JSON(http.StatusBadRequest, models.Error(400, "用户ID格式错误"))
+		return 0, false
+	}
+	return userID, true
+}
+
+func respondGroupError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrGroupNotFound), errors.Is(err, services.ErrGroupMemberNotFound),
+		errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+	case errors.Is(err, services.ErrNotGroupMember), errors.Is(err, services.ErrGroupForbidden),
+		errors.Is(err, services.ErrGroupManaged), errors.Is(err, services.ErrContactBlocked),
+		errors.Is(err, services.ErrGroupMemberNotContact):
+		c.JSON(http.StatusForbidden, models.Error(403, err.Error()))
+	case errors.Is(err, services.ErrGroupOwnerLeave), errors.Is(err, services.ErrGroupFull):
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, models.Error(500, "操作失败"))
+	}
+}
+
+func logGroupAction(c *gin.Context, action, groupID string) {
+	logOperation(c, action, groupID)
+}
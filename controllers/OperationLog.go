@@ -0,0 +1,20 @@
+package controllers
+
+import (
+	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/services"
+	"EmployeeManagementDemo/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// logOperation 记录当前请求操作人的操作日志。操作人从请求上下文中取：
+// 用户登录时为账号ID，API 密钥调用时为密钥ID，并注明操作人类型
+func logOperation(c *gin.Context, action, targetID string) {
+	if role, _ := utils.GetCurrentUserRole(c); role == models.ActorAPIKey {
+		keyID, _ := utils.GetCurrentUserID(c)
+		services.LogOperation(keyID, models.ActorAPIKey, action, targetID)
+		return
+	}
+	principalID, _ := utils.GetCurrentPrincipalID(c)
+	services.LogOperation(principalID, models.ActorUser, action, targetID)
+}
@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/services"
+	"EmployeeManagementDemo/utils"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// ForgotPassword 发送重置密码邮件（无论邮箱是否存在都返回成功）
+func ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, utils.TranslateValidationErrors(err)))
+		return
+	}
+
+	if err := services.RequestPasswordReset(req.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, "服务器错误"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ApiResponse{
+		Code:    200,
+		Message: "如果该邮箱已注册，重置链接已发送",
+	})
+}
+
+// ResetPassword 使用邮件中的令牌设置新密码
+func ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, utils.TranslateValidationErrors(err)))
+		return
+	}
+
+	if err := services.ResetPassword(req.Token, req.NewPassword); err != nil {
+		if errors.Is(err, services.ErrResetTokenInvalid) || errors.Is(err, services.ErrPasswordPolicy) {
+			c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.Error(500, "密码重置失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(nil))
+}
@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/services"
+	"EmployeeManagementDemo/utils"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// ListRoles 角色列表（含权限）
+func ListRoles(c *gin.Context) {
+	roles, err := services.ListRoles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, "查询角色失败"))
+		return
+	}
+	c.JSON(http.StatusOK, models.Success(roles))
+}
+
+// ListPermissions 全部可分配的权限
+func ListPermissions(c *gin.Context) {
+	perms, err := services.ListPermissions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, "查询权限失败"))
+		return
+	}
+	c.JSON(http.StatusOK, models.Success(perms))
+}
+
+func CreateRole(c *gin.Context) {
+	var req models.CreateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, utils.TranslateValidationErrors(err)))
+		return
+	}
+
+	role, err := services.CreateRole(req)
+	if err != nil {
+		respondRoleError(c, err)
+		return
+	}
+
+	logRoleAction(c, "create_role", role.ID)
+	c.JSON(http.StatusCreated, models.Success(role))
+}
+
+func UpdateRole(c *gin.Context) {
+	roleID, ok := parseRoleID(c)
+	if !ok {
+		return
+	}
+	var req models.UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, utils.TranslateValidationErrors(err)))
+		return
+	}
+
+	if err := services.UpdateRole(roleID, req); err != nil {
+		respondRoleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.Success(nil))
+}
+
+func DeleteRole(c *gin.Context) {
+	roleID, ok := parseRoleID(c)
+	if !ok {
+		return
+	}
+	if err := services.DeleteRole(roleID); err != nil {
+		respondRoleError(c, err)
+		return
+	}
+
+	logRoleAction(c, "delete_role", roleID)
+	c.JSON(http.StatusOK, models.Success(nil))
+}
+
+// SetRolePermissions 整体替换角色的权限
+func SetRolePermissions(c *gin.Context) {
+	roleID, ok := parseRoleID(c)
+	if !ok {
+		return
+	}
+	var req models.SetRolePermissionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, utils.TranslateValidationErrors(err)))
+		return
+	}
+
+	if err := services.SetRolePermissions(roleID, req.Permissions); err != nil {
+		respondRoleError(c, err)
+		return
+	}
+
+	logRoleAction(c, "set_role_permissions", roleID)
+	c.JSON(http.StatusOK, models.Success(nil))
+}
+
+func ListRoleMembers(c *gin.Context) {
+	roleID, ok := parseRoleID(c)
+	if !ok {
+		return
+	}
+	members, err := services.ListRoleMembers(roleID)
+	if err != nil {
+		respondRoleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.Success(members))
+}
+
+// AssignRole 给管理员或员工分配角色
+func AssignRole(c *gin.Context) {
+	roleID, ok := parseRoleID(c)
+	if !ok {
+		return
+	}
+	var req models.AssignRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, utils.TranslateValidationErrors(err)))
+		return
+	}
+
+	if err := services.AssignRole(roleID, req.UserType, req.UserID); err != nil {
+		respondRoleError(c, err)
+		return
+	}
+
+	logRoleAction(c, "assign_role", roleID)
+	c.JSON(http.StatusOK, models.Success(nil))
+}
+
+func UnassignRole(c *gin.Context) {
+	roleID, ok := parseRoleID(c)
+	if !ok {
+		return
+	}
+	userType := c.Param("user_type")
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "用户ID格式错误"))
+		return
+	}
+
+	if err := services.UnassignRole(roleID, userType, uint(userID)); err != nil {
+		respondRoleError(c, err)
+		return
+	}
+
+	logRoleAction(c, "unassign_role", roleID)
+	c.JSON(http.StatusOK, models.Success(nil))
+}
+
+// GetMyPermissions 当前用户的权限列表（前端据此控制菜单）
+func GetMyPermissions(c *gin.Context) {
+	userID, _ := utils.GetCurrentUserID(c)
+	role, _ := utils.GetCurrentUserRole(c)
+
+	granted, err := services.GetUserPermissions(role, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, "权限查询失败"))
+		return
+	}
+	codes := make([]string, 0, len(granted))
+	for code := range granted {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	c.JSON(http.StatusOK, models.Success(codes))
+}
+
+func parseRoleID(c *gin.Context) (uint, bool) {
+	roleID, err := strconv.ParseUint(c.Param("role_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "角色ID格式错误"))
+		return 0, false
+	}
+	return uint(roleID), true
+}
+
+func respondRoleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrRoleNotFound), errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+	case errors.Is(err, services.ErrRoleNameExists):
+		c.JSON(http.StatusConflict, models.Error(409, err.Error()))
+	case errors.Is(err, services.ErrRoleBuiltIn), errors.Is(err, services.ErrUnknownPermission):
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, models.Error(500, "操作失败"))
+	}
+}
+
+func logRoleAction(c *gin.Context, action string, roleID uint) {
+	logOperation(c, action, strconv.FormatUint(uint64(roleID), 10))
+}
@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/services"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// SSOLogin 跳转到企业 IdP 的授权页面
+func SSOLogin(c *gin.Context) {
+	authURL, err := services.BeginSSOLogin(c.Request.Context())
+	if err != nil {
+		if errors.Is(err, services.ErrSSODisabled) {
+			c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+			return
+		}
+		c.JSON(http.StatusBadGateway, models.Error(502, "无法连接认证服务器"))
+		return
+	}
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// SSOCallback IdP 回调后由前端携带 code/state 调用，返回结果与 /api/login 一致
+func SSOCallback(c *gin.Context) {
+	if idpErr := c.Query("error"); idpErr != "" {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "认证服务器拒绝登录: "+idpErr))
+		return
+	}
+	code, state := c.Query("code"), c.Query("state")
+	if code == "" || state == "" {
+		c.JSON(http.StatusBadRequest, models.Error(400, "缺少 code 或 state 参数"))
+		return
+	}
+
+	user, err := services.CompleteSSOLogin(c.Request.Context(), state, code)
+	switch {
+	case err == nil:
+	case errors.Is(err, services.ErrSSODisabled):
+		c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+		return
+	case errors.Is(err, services.ErrSSOStateInvalid):
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	case errors.Is(err, services.ErrSSOUserNotFound), errors.Is(err, services.ErrSSOEmailAmbiguous):
+		c.JSON(http.StatusForbidden, models.Error(403, err.Error()))
+		return
+	default:
+		c.JSON(http.StatusUnauthorized, models.Error(401, "单点登录失败"))
+		return
+	}
+
+	finishLogin(c, user)
+}
@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/services"
+	"EmployeeManagementDemo/utils"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// ListMySessions 当前账号的登录会话（各设备）
+func ListMySessions(c *gin.Context) {
+	principalID, _ := utils.GetCurrentPrincipalID(c)
+
+	sessions, err := services.ListUserSessions(principalID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, "查询会话失败"))
+		return
+	}
+	currentID := currentSessionID(c)
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+	c.JSON(http.StatusOK, models.Success(sessions))
+}
+
+// RevokeMySession 下线自己的某个会话（如丢失的设备）
+func RevokeMySession(c *gin.Context) {
+	principalID, _ := utils.GetCurrentPrincipalID(c)
+	sessionID := c.Param("session_id")
+
+	if err := services.RevokeUserSession(principalID, sessionID); err != nil {
+		respondSessionError(c, err)
+		return
+	}
+
+	logSessionAction(c, "revoke_session", sessionID)
+	c.JSON(http.StatusOK, models.Success(nil))
+}
+
+// RevokeMyOtherSessions 下线除当前会话外的所有会话
+func RevokeMyOtherSessions(c *gin.Context) {
+	principalID, _ := utils.GetCurrentPrincipalID(c)
+
+	count, err := services.RevokeUserSessions(principalID, currentSessionID(c))
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+
+	logSessionAction(c, "revoke_other_sessions", "")
+	c.JSON(http.StatusOK, models.Success(gin.H{"revoked": count}))
+}
+
+// ListUserSessions 管理员查看指定账号的会话（user_id 为账号ID）
+func ListUserSessions(c *gin.Context) {
+	principalID, ok := parseSessionOwner(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := services.ListUserSessions(principalID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, "查询会话失败"))
+		return
+	}
+	c.JSON(http.StatusOK, models.Success(sessions))
+}
+
+// RevokeUserSession 管理员下线指定用户的单个会话
+func RevokeUserSession(c *gin.Context) {
+	principalID, ok := parseSessionOwner(c)
+	if !ok {
+		return
+	}
+	sessionID := c.Param("session_id")
+
+	if err := services.RevokeUserSession(principalID, sessionID); err != nil {
+		respondSessionError(c, err)
+		return
+	}
+
+	logSessionAction(c, "revoke_user_session", sessionID)
+	c.JSON(http.StatusOK, models.Success(nil))
+}
+
+// RevokeUserSessions 管理员下线指定用户的全部会话
+func RevokeUserSessions(c *gin.Context) {
+	principalID, ok := parseSessionOwner(c)
+	if !ok {
+		return
+	}
+
+	count, err := services.RevokeUserSessions(principalID, "")
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+
+	logSessionAction(c, "revoke_user_sessions", strconv.FormatUint(uint64(principalID), 10))
+	c.JSON(http.StatusOK, models.Success(gin.H{"revoked": count}))
+}
+
+// sessionMeta 从请求中提取会话的客户端信息
+func sessionMeta(c *gin.Context) services.SessionMeta {
+	return services.SessionMeta{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+}
+
+func currentSessionID(c *gin.Context) string {
+	if claims, ok := c.Value("claims").(*utils.Claims); ok {
+		return claims.ID
+	}
+	return ""
+}
+
+func parseSessionOwner(c *gin.Context) (uint, bool) {
+	principalID, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "用户ID格式错误"))
+		return 0, false
+	}
+	return uint(principalID), true
+}
+
+func respondSessionError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrSessionNotFound) {
+		c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+		return
+	}
+	c.JSON(http.StatusInternalServerError, models.Error(500, "操作失败"))
+}
+
+func logSessionAction(c *gin.Context, action, target string) {
+	logOperation(c, action, target)
+}
@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/services"
+	"EmployeeManagementDemo/utils"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// RefreshToken 使用刷新令牌换取新的访问令牌（刷新令牌同时轮换）
+func RefreshToken(c *gin.Context) {
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, utils.TranslateValidationErrors(err)))
+		return
+	}
+
+	tokens, err := services.RefreshTokenPair(req.RefreshToken, sessionMeta(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrRefreshTokenInvalid), errors.Is(err, services.ErrRefreshTokenReused):
+			c.JSON(http.StatusUnauthorized, models.Error(401, err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, models.Error(500, "令牌刷新失败"))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(tokens))
+}
+
+// GetJWKS 公开访问令牌的校验公钥（标准 JWKS 格式，不包裹统一响应结构）
+func GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{"keys": utils.JWKS()})
+}
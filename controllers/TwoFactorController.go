@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/services"
+	"EmployeeManagementDemo/utils"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// GetTwoFactorStatus 当前用户的两步验证状态
+func GetTwoFactorStatus(c *gin.Context) {
+	userID, _ := utils.GetCurrentUserID(c)
+	role, _ := utils.GetCurrentUserRole(c)
+
+	enabled, err := services.IsTwoFactorEnabled(role, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, "查询失败"))
+		return
+	}
+	c.JSON(http.StatusOK, models.Success(gin.H{
+		"enabled":  enabled,
+		"required": services.TwoFactorRequired(role),
+	}))
+}
+
+// SetupTwoFactor 生成密钥和 otpauth 链接，需调用 EnableTwoFactor 确认后才生效
+func SetupTwoFactor(c *gin.Context) {
+	userID, _ := utils.GetCurrentUserID(c)
+	role, _ := utils.GetCurrentUserRole(c)
+
+	user, err := services.GetUserByRole(role, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.Error(404, "用户不存在"))
+		return
+	}
+	setup, err := services.BeginTwoFactorEnrollment(user)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.Success(setup))
+}
+
+// EnableTwoFactor 校验首个验证码并开启两步验证，返回恢复码
+func EnableTwoFactor(c *gin.Context) {
+	userID, _ := utils.GetCurrentUserID(c)
+	role, _ := utils.GetCurrentUserRole(c)
+
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, utils.TranslateValidationErrors(err)))
+		return
+	}
+
+	codes, err := services.ConfirmTwoFactorEnrollment(role, userID, req.Code)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	logOperation(c, "enable_2fa", "")
+	c.JSON(http.StatusOK, models.Success(gin.H{"recovery_codes": codes}))
+}
+
+func DisableTwoFactor(c *gin.Context) {
+	userID, _ := utils.GetCurrentUserID(c)
+	role, _ := utils.GetCurrentUserRole(c)
+
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, utils.TranslateValidationErrors(err)))
+		return
+	}
+
+	if err := services.DisableTwoFactor(role, userID, req.Code); err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	logOperation(c, "disable_2fa", "")
+	c.JSON(http.StatusOK, models.Success(nil))
+}
+
+func RegenerateRecoveryCodes(c *gin.Context) {
+	userID, _ := utils.GetCurrentUserID(c)
+	role, _ := utils.GetCurrentUserRole(c)
+
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, utils.TranslateValidationErrors(err)))
+		return
+	}
+
+	codes, err := services.RegenerateRecoveryCodes(role, userID, req.Code)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.Success(gin.H{"recovery_codes": codes}))
+}
+
+// SetupLoginTwoFactor 登录时被强制绑定：凭挑战令牌获取密钥
+func SetupLoginTwoFactor(c *gin.Context) {
+	var req models.TwoFactorChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, utils.TranslateValidationErrors(err)))
+		return
+	}
+
+	challenge, err := services.GetLoginChallenge(req.ChallengeToken)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	if !challenge.EnrollmentRequired {
+		respondTwoFactorError(c, services.ErrLoginChallengeNoEnroll)
+		return
+	}
+	user, err := services.GetUserByRole(challenge.Role, challenge.UserID)
+	if err != nil {
+		respondTwoFactorError(c, services.ErrLoginChallengeInvalid)
+		return
+	}
+
+	setup, err := services.BeginTwoFactorEnrollment(user)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.Success(setup))
+}
+
+// VerifyLoginTwoFactor 登录第二步：挑战令牌 + 验证码（或恢复码）换取正式令牌
+func VerifyLoginTwoFactor(c *gin.Context) {
+	var req models.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, utils.TranslateValidationErrors(err)))
+		return
+	}
+
+	challenge, err := services.GetLoginChallenge(req.ChallengeToken)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	var recoveryCodes []string
+	if challenge.EnrollmentRequired {
+		recoveryCodes, err = services.ConfirmTwoFactorEnrollment(challenge.Role, challenge.UserID, req.Code)
+	} else {
+		err = services.VerifyTwoFactorCode(challenge.Role, challenge.UserID, req.Code)
+	}
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	services.CompleteLoginChallenge(challenge)
+
+	user, err := services.GetUserByRole(challenge.Role, challenge.UserID)
+	if err != nil {
+		respondTwoFactorError(c, services.ErrLoginChallengeInvalid)
+		return
+	}
+	respondLoginSuccess(c, user, recoveryCodes)
+}
+
+func respondTwoFactorError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrTwoFactorCodeInvalid), errors.Is(err, services.ErrLoginChallengeInvalid):
+		c.JSON(http.StatusUnauthorized, models.Error(401, err.Error()))
+	case errors.Is(err, services.ErrTwoFactorRequired):
+		c.JSON(http.StatusForbidden, models.Error(403, err.Error()))
+	case errors.Is(err, services.ErrTwoFactorNotEnrolled), errors.Is(err, services.ErrTwoFactorAlreadyOn),
+		errors.Is(err, services.ErrTwoFactorSetupMissing), errors.Is(err, services.ErrLoginChallengeNoEnroll):
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, models.Error(500, "两步验证处理失败"))
+	}
+}
@@ -2,13 +2,19 @@ package controllers
 
 import (
 	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/dao"
 	"EmployeeManagementDemo/models"
 	"EmployeeManagementDemo/services"
 	"EmployeeManagementDemo/utils"
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -24,15 +30,76 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	// 用户名或 IP 处于锁定期内，直接拒绝
+	ip := c.ClientIP()
+	if err := services.CheckLoginAllowed(req.Username, ip); err != nil {
+		if !respondLoginLocked(c, err) {
+			c.JSON(http.StatusInternalServerError, models.Error(500, "服务器错误"))
+		}
+		return
+	}
+
 	// 统一认证逻辑（同时支持管理员和员工）
-	user, err := services.AuthenticateUser(req.Username, req.Password)
+	user, err := services.AuthenticateUser(req.Username, req.Password, req.UserType)
 	if err != nil {
+		if lockErr := services.RecordLoginFailure(req.Username, ip); lockErr != nil {
+			if respondLoginLocked(c, lockErr) {
+				return
+			}
+			// 计数失败时锁定不会生效，记录下来以便发现 Redis 故障
+			log.Printf("登录失败次数记录失败 (%s, %s): %v", req.Username, ip, lockErr)
+		}
 		c.JSON(http.StatusUnauthorized, models.Error(401, "用户名或密码错误"))
 		return
 	}
+	services.ResetLoginFailures(req.Username)
+
+	finishLogin(c, user)
+}
 
-	// 生成JWT
-	token, err := utils.GenerateJWT(user)
+// finishLogin 身份确认后的统一收尾（密码登录与单点登录共用）：
+// 需要两步验证时下发挑战令牌，否则直接签发令牌
+func finishLogin(c *gin.Context, user models.BaseUser) {
+	// 已开启两步验证，或所属角色强制要求两步验证时，先下发挑战令牌
+	enabled, err := services.IsTwoFactorEnabled(user.GetRole(), user.GetID())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, "两步验证状态查询失败"))
+		return
+	}
+	if enabled || services.TwoFactorRequired(user.GetRole()) {
+		challenge, err := services.CreateLoginChallenge(user, !enabled)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.Error(500, "登录挑战生成失败"))
+			return
+		}
+		c.JSON(http.StatusOK, models.Success(models.TwoFactorChallengeDTO{
+			MFARequired:        true,
+			EnrollmentRequired: !enabled,
+			ChallengeToken:     challenge,
+			ExpiresIn:          int64(config.Cfg.TwoFactor.ChallengeTTL.Seconds()),
+		}))
+		return
+	}
+
+	respondLoginSuccess(c, user, nil)
+}
+
+// respondLoginLocked err 为锁定错误时返回 429 并带上 Retry-After，否则不处理并返回 false
+func respondLoginLocked(c *gin.Context, err error) bool {
+	var locked *services.LoginLockedError
+	if !errors.As(err, &locked) {
+		return false
+	}
+	seconds := int64(math.Ceil(locked.Remaining.Seconds()))
+	c.Header("Retry-After", strconv.FormatInt(seconds, 10))
+	c.JSON(http.StatusTooManyRequests, models.Error(429, fmt.Sprintf("登录失败次数过多，请 %d 秒后再试", seconds)))
+	return true
+}
+
+// 签发令牌并返回登录结果（密码登录与两步验证登录共用）
+func respondLoginSuccess(c *gin.Context, user models.BaseUser, recoveryCodes []string) {
+	// 生成访问令牌和刷新令牌
+	tokens, err := services.IssueTokenPair(user, sessionMeta(c))
 	if err != nil {
 		//c.JSON(http.StatusInternalServerError, gin.H{"error": "令牌生成失败"})
 		c.JSON(http.StatusInternalServerError, models.Error(500, "令牌生成失效"))
@@ -50,21 +117,18 @@ func Login(c *gin.Context) {
 	//}
 
 	userDto := models.LoginDTO{
-		Token: token,
+		TokenDTO: *tokens,
 		User: models.User{
-			ID:   user.GetID(),
-			Name: user.GetUsername(),
-			Role: user.GetRole(),
+			ID:          user.GetID(),
+			PrincipalID: user.GetAccountID(),
+			Name:        user.GetUsername(),
+			Role:        user.GetRole(),
 		},
+		RecoveryCodes: recoveryCodes,
 	}
 
-	// 发送登录日志消息
-	logData := map[string]interface{}{
-		"user_id":   user.GetID(),
-		"action":    "login",
-		"target_id": "", // 登录操作无目标对象
-	}
-	services.SendLogToRabbitMQ(logData)
+	// 发送登录日志消息（登录操作无目标对象）
+	services.LogOperation(user.GetAccountID(), models.ActorUser, "login", "")
 
 	//c.JSON(http.StatusOK, responseData)
 	c.JSON(http.StatusOK, models.Success(userDto))
@@ -113,6 +177,7 @@ func UpdateProfile(c *gin.Context) {
 
 	// 根据角色获取用户模型
 	var err error
+	principalID, _ := utils.GetCurrentPrincipalID(c)
 	if role == "admin" {
 		var admin models.Admin
 		if err = config.DB.First(&admin, userID).Error; err != nil {
@@ -124,7 +189,7 @@ func UpdateProfile(c *gin.Context) {
 		admin.AdminEmail = req.Email
 		admin.AdminPhone = req.Phone
 		admin.Avatar = req.Avatar
-		err = config.DB.Save(&admin).Error
+		err = saveProfileWithAccount(principalID, req.Name, &admin)
 	} else {
 		var emp models.Employee
 		if err = config.DB.First(&emp, userID).Error; err != nil {
@@ -136,9 +201,13 @@ func UpdateProfile(c *gin.Context) {
 		emp.Email = req.Email
 		emp.Phone = req.Phone
 		emp.Avatar = req.Avatar
-		err = config.DB.Save(&emp).Error
+		err = saveProfileWithAccount(principalID, req.Name, &emp)
 	}
 
+	if errors.Is(err, services.ErrUsernameTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": "用户名已存在"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新失败: " + err.Error()})
 		return
@@ -148,10 +217,19 @@ func UpdateProfile(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "资料更新成功"})
 }
 
+// saveProfileWithAccount 保存档案，并同步账号用户名（用户名全局唯一）
+func saveProfileWithAccount(principalID uint, username string, profile interface{}) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := services.RenameAccount(tx, principalID, username); err != nil {
+			return err
+		}
+		return tx.Save(profile).Error
+	})
+}
+
 func UpdatePassword(c *gin.Context) {
-	// 从上下文中获取用户身份
-	userID, _ := utils.GetCurrentUserID(c)
-	role, _ := utils.GetCurrentUserRole(c)
+	// 从上下文中获取账号身份
+	principalID, _ := utils.GetCurrentPrincipalID(c)
 
 	// 绑定请求参数
 	var req models.UpdatePasswordRequest
@@ -160,21 +238,9 @@ func UpdatePassword(c *gin.Context) {
 		return
 	}
 
-	// 根据角色查询用户
-	var user models.UserPasswordHolder // 假设有一个公共接口或结构体包含密码字段
-	var dbQuery *gorm.DB
-
-	if role == "admin" {
-		var admin models.Admin
-		dbQuery = config.DB.Where("admin_id = ?", userID).First(&admin)
-		user = &admin // 假设Admin实现了密码接口
-	} else {
-		var emp models.Employee
-		dbQuery = config.DB.Where("emp_id = ?", userID).First(&emp)
-		user = &emp // 假设Employee实现了密码接口
-	}
-
-	if dbQuery.Error != nil {
+	// 密码保存在账号上
+	user, err := dao.GetAccountByID(principalID)
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
 		return
 	}
@@ -185,16 +251,15 @@ func UpdatePassword(c *gin.Context) {
 		return
 	}
 
-	// 加密新密码
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	// 校验密码策略和历史密码后更新
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		return services.ChangeAccountPassword(tx, user.ID, req.NewPassword)
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "密码加密失败"})
-		return
-	}
-
-	// 更新数据库
-	user.SetPassword(string(hashedPassword))
-	if err := config.DB.Save(user).Error; err != nil {
+		if errors.Is(err, services.ErrPasswordPolicy) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "密码更新失败"})
 		return
 	}
@@ -224,13 +289,14 @@ func Logout(c *gin.Context) {
 		return
 	}
 
-	// 发送注销日志消息
-	logData := map[string]interface{}{
-		"user_id":   claims.UserID,
-		"action":    "logout",
-		"target_id": "", // 注销操作无目标对象
+	// 吊销当前会话，防止刷新令牌继续续期
+	if err := services.RevokeSession(claims.ID); err != nil {
+		c.JSON(500, gin.H{"error": "注销失败"})
+		return
 	}
-	services.SendLogToRabbitMQ(logData)
+
+	// 发送注销日志消息（注销操作无目标对象）
+	logOperation(c, "logout", "")
 
 	c.JSON(200, gin.H{"message": "已成功注销"})
 }
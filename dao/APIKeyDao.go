@@ -0,0 +1,46 @@
+package dao
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/models"
+	"time"
+)
+
+func CreateAPIKey(key *models.APIKey) error {
+	return config.DB.Create(key).Error
+}
+
+func GetAPIKeyByID(id uint) (*models.APIKey, error) {
+	var key models.APIKey
+	err := config.DB.First(&key, id).Error
+	return &key, err
+}
+
+func GetAPIKeyByHash(hash string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := config.DB.Where("key_hash = ?", hash).First(&key).Error
+	return &key, err
+}
+
+// ListAPIKeys 密钥列表，最新的在前
+func ListAPIKeys() ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := config.DB.Order("id DESC").Find(&keys).Error
+	return keys, err
+}
+
+// RevokeAPIKey 吊销尚未吊销的密钥，返回是否确实吊销
+func RevokeAPIKey(id uint) (bool, error) {
+	result := config.DB.Model(&models.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+// TouchAPIKey 记录最近使用时间和来源 IP，距上次记录不足 interval 时跳过，避免每次请求都写库
+func TouchAPIKey(id uint, ip string, interval time.Duration) error {
+	now := time.Now()
+	return config.DB.Model(&models.APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ? OR last_used_ip <> ?)", id, now.Add(-interval), ip).
+		Updates(map[string]interface{}{"last_used_at": now, "last_used_ip": ip}).Error
+}
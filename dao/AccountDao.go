@@ -0,0 +1,40 @@
+package dao
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/models"
+	"gorm.io/gorm"
+)
+
+func GetAccountByUsername(username string) (*models.Account, error) {
+	var acc models.Account
+	result := config.DB.Where("username = ?", username).First(&acc)
+	return &acc, result.Error
+}
+
+func GetAccountByID(id uint) (*models.Account, error) {
+	var acc models.Account
+	result := config.DB.First(&acc, id)
+	return &acc, result.Error
+}
+
+// UsernameTaken 用户名是否已被任一账号占用（excludeID 为改名时的自身账号）
+func UsernameTaken(tx *gorm.DB, username string, excludeID uint) (bool, error) {
+	var count int64
+	result := tx.Model(&models.Account{}).
+		Where("username = ? AND id <> ?", username, excludeID).
+		Count(&count)
+	return count > 0, result.Error
+}
+
+func GetAdminByAccountID(accountID uint) (*models.Admin, error) {
+	var admin models.Admin
+	result := config.DB.Where("account_id = ?", accountID).First(&admin)
+	return &admin, result.Error
+}
+
+func GetEmployeeByAccountID(accountID uint) (*models.Employee, error) {
+	var emp models.Employee
+	result := config.DB.Where("account_id = ?", accountID).First(&emp)
+	return &emp, result.Error
+}
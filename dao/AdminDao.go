@@ -22,3 +22,15 @@ func GetAdminByUsername(username string) (*models.Admin, error) {
 	result := config.DB.Where("admin_name = ?", username).First(&admin)
 	return &admin, result.Error
 }
+
+func GetAdminByID(id uint) (*models.Admin, error) {
+	var admin models.Admin
+	result := config.DB.Where("admin_id = ?", id).First(&admin)
+	return &admin, result.Error
+}
+
+func CountAdmins() (int64, error) {
+	var count int64
+	err := config.DB.Model(&models.Admin{}).Count(&count).Error
+	return count, err
+}
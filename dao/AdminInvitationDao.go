@@ -0,0 +1,40 @@
+package dao
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/models"
+	"time"
+)
+
+func CreateAdminInvitation(inv *models.AdminInvitation) error {
+	return config.DB.Create(inv).Error
+}
+
+func DeleteAdminInvitation(id uint) error {
+	return config.DB.Delete(&models.AdminInvitation{}, id).Error
+}
+
+func GetAdminInvitationByID(id uint) (*models.AdminInvitation, error) {
+	var inv models.AdminInvitation
+	err := config.DB.First(&inv, id).Error
+	return &inv, err
+}
+
+// ListAdminInvitations 邀请列表（附带角色名），最新的在前
+func ListAdminInvitations() ([]models.AdminInvitationDTO, error) {
+	var list []models.AdminInvitationDTO
+	err := config.DB.Table("admin_invitations").
+		Select("admin_invitations.*, roles.name AS role_name").
+		Joins("LEFT JOIN roles ON roles.id = admin_invitations.role_id").
+		Order("admin_invitations.id DESC").
+		Scan(&list).Error
+	return list, err
+}
+
+// RevokeAdminInvitation 撤销尚未使用的邀请，返回是否确实撤销
+func RevokeAdminInvitation(id uint) (bool, error) {
+	result := config.DB.Model(&models.AdminInvitation{}).
+		Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,50 @@
+package dao
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/models"
+	"time"
+)
+
+// unsentAttachment 没有任何消息引用的附件（上传后一直没有发送）
+const unsentAttachment = "NOT EXISTS (SELECT 1 FROM chat_messages WHERE chat_messages.attachment_id = chat_attachments.id)"
+
+func CreateChatAttachment(att *models.ChatAttachment) error {
+	return config.DB.Create(att).Error
+}
+
+func GetChatAttachmentByID(id uint) (*models.ChatAttachment, error) {
+	var att models.ChatAttachment
+	err := config.DB.First(&att, id).Error
+	return &att, err
+}
+
+func GetChatAttachmentsByIDs(ids []uint) ([]models.ChatAttachment, error) {
+	var atts []models.ChatAttachment
+	if len(ids) == 0 {
+		return atts, nil
+	}
+	err := config.DB.Where("id IN ?", ids).Find(&atts).Error
+	return atts, err
+}
+
+// ListUnsentChatAttachments before 之前上传、一直没有发送的附件
+func ListUnsentChatAttachments(before time.Time, limit int) ([]models.ChatAttachment, error) {
+	var atts []models.ChatAttachment
+	err := config.DB.Where("created_at < ?", before).Where(unsentAttachment).
+		Order("id").Limit(limit).Find(&atts).Error
+	return atts, err
+}
+
+// DeleteUnsentChatAttachment 附件仍没有消息引用时删除记录，返回是否删除
+func DeleteUnsentChatAttachment(id uint) (bool, error) {
+	result := config.DB.Where("id = ?", id).Where(unsentAttachment).Delete(&models.ChatAttachment{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// ChatAttachmentVisibleTo 用户能否看到引用该附件的消息（即是否为该会话的参与者）
+func ChatAttachmentVisibleTo(userID int, attachmentID uint) (bool, error) {
+	var count int64
+	err := visibleMessages(userID).Where("attachment_id = ?", attachmentID).Limit(1).Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,62 @@
+package dao
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/models"
+)
+
+// ListChatContacts 用户一侧处于某状态的联系人记录（联系人、屏蔽的用户、发出的申请）
+func ListChatContacts(userID int, status string) ([]models.ChatContact, error) {
+	var contacts []models.ChatContact
+	err := config.DB.Where("user_id = ? AND status = ?", userID, status).Order("updated_at DESC").Find(&contacts).Error
+	return contacts, err
+}
+
+// ListIncomingContactRequests 收到的好友申请
+func ListIncomingContactRequests(userID int) ([]models.ChatContact, error) {
+	var requests []models.ChatContact
+	err := config.DB.Where("contact_id = ? AND status = ?", userID, models.ContactPending).
+		Order("created_at DESC").Find(&requests).Error
+	return requests, err
+}
+
+// HasChatContactStatus 是否存在 userID -> contactID 的某状态记录
+func HasChatContactStatus(userID, contactID int, status string) (bool, error) {
+	var count int64
+	err := config.DB.Model(&models.ChatContact{}).
+		Where("user_id = ? AND contact_id = ? AND status = ?", userID, contactID, status).Count(&count).Error
+	return count > 0, err
+}
+
+// ListBlockers ids 中屏蔽了 userID 的账号
+func ListBlockers(userID int, ids []int) ([]int, error) {
+	var blockers []int
+	if len(ids) == 0 {
+		return blockers, nil
+	}
+	err := config.DB.Model(&models.ChatContact{}).
+		Where("user_id IN ? AND contact_id = ? AND status = ?", ids, userID, models.ContactBlocked).
+		Pluck("user_id", &blockers).Error
+	return blockers, err
+}
+
+// ListDepartmentColleagues 与账号同部门的其他员工的账号ID
+func ListDepartmentColleagues(accountID int) ([]int, error) {
+	var ids []int
+	err := config.DB.Table("employees AS e1").
+		Joins("JOIN employees AS e2 ON e2.dep_id = e1.dep_id AND e2.deleted_at IS NULL").
+		Where("e1.account_id = ? AND e1.dep_id <> 0 AND e1.deleted_at IS NULL", accountID).
+		Where("e2.account_id IS NOT NULL AND e2.account_id <> ?", accountID).
+		Pluck("e2.account_id", &ids).Error
+	return ids, err
+}
+
+// SameDepartment 两个账号对应的员工是否在同一部门
+func SameDepartment(a, b int) (bool, error) {
+	var count int64
+	err := config.DB.Table("employees AS e1").
+		Joins("JOIN employees AS e2 ON e2.dep_id = e1.dep_id AND e2.deleted_at IS NULL").
+		Where("e1.account_id = ? AND e1.dep_id <> 0 AND e1.deleted_at IS NULL AND e2.account_id = ?", a, b).
+		Count(&count).Error
+	return count > 0, err
+}
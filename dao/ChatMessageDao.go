@@ -0,0 +1,216 @@
+package dao
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// ConversationSummary 会话汇总：最后一条消息ID和未读数
+type ConversationSummary struct {
+	ConversationID string
+	LastID         uint
+	Unread         int
+}
+
+// visibleMessages 用户可见的消息：发给自己的（含群消息中自己的副本），以及自己发出的单聊消息，不含自己删除的
+func visibleMessages(userID int) *gorm.DB {
+	return config.DB.Model(&models.ChatMessage{}).
+		Where("((chat_messages.recipient_id = ? AND chat_messages.deleted_by_recipient = ?) OR "+
+			"(chat_messages.send_id = ? AND chat_messages.group_id = '' AND chat_messages.deleted_by_sender = ?))",
+			userID, false, userID, false)
+}
+
+// ListConversationSummaries 用户的会话，按最后一条消息倒序
+func ListConversationSummaries(userID, limit int) ([]ConversationSummary, error) {
+	var rows []ConversationSummary
+	err := visibleMessages(userID).
+		Select("conversation_id, MAX(id) AS last_id, "+
+			"SUM(CASE WHEN recipient_id = ? AND send_id <> ? AND `read` = 0 THEN 1 ELSE 0 END) AS unread", userID, userID).
+		Where("conversation_id <> ''").
+		Group("conversation_id").
+		Order("last_id DESC").
+		Limit(limit).
+		Scan(&rows).Error
+	return rows, err
+}
+
+func GetChatMessagesByIDs(ids []uint) ([]models.ChatMessage, error) {
+	var msgs []models.ChatMessage
+	if len(ids) == 0 {
+		return msgs, nil
+	}
+	err := config.DB.Where("id IN ?", ids).Find(&msgs).Error
+	return msgs, err
+}
+
+// ListConversationMessages 会话中用户可见的消息，before 为 0 时从最新开始
+func ListConversationMessages(userID int, conversationID string, before uint, limit int) ([]models.ChatMessage, error) {
+	var msgs []models.ChatMessage
+	query := visibleMessages(userID).Where("conversation_id = ?", conversationID)
+	if before > 0 {
+		query = query.Where("id < ?", before)
+	}
+	err := query.Order("id DESC").Limit(limit).Find(&msgs).Error
+	return msgs, err
+}
+
+// ListConversationMessagesBefore 会话中用户可见的、before 之前发出的消息，按时间倒序
+func ListConversationMessagesBefore(userID int, conversationID string, before time.Time, limit int) ([]models.ChatMessage, error) {
+	var msgs []models.ChatMessage
+	err := visibleMessages(userID).
+		Where("conversation_id = ? AND created_at < ?", conversationID, before).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&msgs).Error
+	return msgs, err
+}
+
+// SearchChatMessages 全文检索用户可见的消息（短语匹配），conversationID 为空时检索全部会话
+func SearchChatMessages(userID int, phrase, conversationID string, before uint, limit int) ([]models.ChatMessage, error) {
+	var msgs []models.ChatMessage
+	query := visibleMessages(userID).Where("MATCH(content) AGAINST(? IN BOOLEAN MODE)", phrase)
+	if conversationID != "" {
+		query = query.Where("conversation_id = ?", conversationID)
+	}
+	if before > 0 {
+		query = query.Where("id < ?", before)
+	}
+	err := query.Order("id DESC").Limit(limit).Find(&msgs).Error
+	return msgs, err
+}
+
+// CountChatMessagesFrom 会话中某人发出的消息数
+func CountChatMessagesFrom(conversationID string, senderID int) (int64, error) {
+	var count int64
+	err := config.DB.Model(&models.ChatMessage{}).
+		Where("conversation_id = ? AND send_id = ?", conversationID, senderID).Count(&count).Error
+	return count, err
+}
+
+// GetUsernames 按账号ID查询用户名
+func GetUsernames(ids []int) (map[int]string, error) {
+	names := make(map[int]string, len(ids))
+	if len(ids) == 0 {
+		return names, nil
+	}
+	var accounts []models.Account
+	if err := config.DB.Select("id, username").Where("id IN ?", ids).Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	for _, acc := range accounts {
+		names[int(acc.ID)] = acc.Username
+	}
+	return names, nil
+}
+
+// GetGroupNames 按群ID查询群名（含已解散的群，历史会话仍需展示）
+func GetGroupNames(ids []string) (map[string]string, error) {
+	names := make(map[string]string, len(ids))
+	if len(ids) == 0 {
+		return names, nil
+	}
+	var groups []models.Group
+	if err := config.DB.Unscoped().Select("id, group_name").Where("id IN ?", ids).Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	for _, g := range groups {
+		names[g.ID] = g.GroupName
+	}
+	return names, nil
+}
+
+// GetUndeliveredMessages 尚未送达接收方的消息（离线期间收到的），按时间正序
+func GetUndeliveredMessages(recipientID int) ([]models.ChatMessage, error) {
+	var msgs []models.ChatMessage
+	err := config.DB.Where("recipient_id = ? AND status = ?", recipientID, models.MsgStatusSent).
+		Order("id").Find(&msgs).Error
+	return msgs, err
+}
+
+// MarkChatMessagesDelivered 标记已送达，只推进仍为 sent 的消息
+func MarkChatMessagesDelivered(ids []uint, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return config.DB.Model(&models.ChatMessage{}).
+		Where("id IN ? AND status = ?", ids, models.MsgStatusSent).
+		Updates(map[string]interface{}{"status": models.MsgStatusDelivered, "delivered_at": at}).Error
+}
+
+// MarkChatMessagesRead 把接收方 recipientID 的未读消息标记为已读并返回这些消息；
+// scope 限定范围（消息ID或会话）
+func MarkChatMessagesRead(recipientID int, scope func(*gorm.DB) *gorm.DB, at time.Time) ([]models.ChatMessage, error) {
+	var msgs []models.ChatMessage
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		query := scope(tx.Model(&models.ChatMessage{})).
+			Where("recipient_id = ? AND send_id <> ? AND `read` = ?", recipientID, recipientID, false)
+		if err := query.Clauses(clause.Locking{Strength: "UPDATE"}).Find(&msgs).Error; err != nil {
+			return err
+		}
+		if len(msgs) == 0 {
+			return nil
+		}
+		ids := make([]uint, 0, len(msgs))
+		for i := range msgs {
+			ids = append(ids, msgs[i].ID)
+		}
+		return tx.Model(&models.ChatMessage{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"read":         true,
+			"status":       models.MsgStatusRead,
+			"read_at":      at,
+			"delivered_at": gorm.Expr("COALESCE(delivered_at, ?)", at),
+		}).Error
+	})
+	return msgs, err
+}
+
+// ListMessageReceipts 发送方一条消息在各接收方处的状态
+func ListMessageReceipts(senderID int, msgKey string) ([]models.MessageReceiptDTO, error) {
+	var receipts []models.MessageReceiptDTO
+	err := config.DB.Model(&models.ChatMessage{}).
+		Select("chat_messages.recipient_id AS user_id, accounts.username, chat_messages.status, "+
+			"chat_messages.delivered_at, chat_messages.read_at").
+		Joins("LEFT JOIN accounts ON accounts.id = chat_messages.recipient_id").
+		Where("chat_messages.msg_key = ? AND chat_messages.send_id = ? AND chat_messages.recipient_id <> ?", msgKey, senderID, senderID).
+		Order("chat_messages.recipient_id").
+		Scan(&receipts).Error
+	return receipts, err
+}
+
+// GetChatMessagesByKey 一条消息的全部副本（群消息每个接收方一份，另含发送方副本）
+func GetChatMessagesByKey(msgKey string) ([]models.ChatMessage, error) {
+	var msgs []models.ChatMessage
+	err := config.DB.Where("msg_key = ?", msgKey).Order("id").Find(&msgs).Error
+	return msgs, err
+}
+
+// UpdateChatMessagesByKey 更新一条消息的全部副本
+func UpdateChatMessagesByKey(msgKey string, updates map[string]interface{}) error {
+	return config.DB.Model(&models.ChatMessage{}).Where("msg_key = ?", msgKey).Updates(updates).Error
+}
+
+// HideChatMessage 仅对用户自己隐藏消息：作为接收方（含群消息的发送方副本）或单聊的发送方
+func HideChatMessage(userID int, msgKey string) (int64, error) {
+	var affected int64
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.ChatMessage{}).Where("msg_key = ? AND recipient_id = ?", msgKey, userID).
+			Update("deleted_by_recipient", true)
+		if res.Error != nil {
+			return res.Error
+		}
+		affected += res.RowsAffected
+		res = tx.Model(&models.ChatMessage{}).Where("msg_key = ? AND send_id = ? AND group_id = ''", msgKey, userID).
+			Update("deleted_by_sender", true)
+		affected += res.RowsAffected
+		return res.Error
+	})
+	return affected, err
+}
+
+// DeleteChatMessagesByKey 删除一条消息的全部副本（软删除）
+func DeleteChatMessagesByKey(msgKey string) error {
+	return config.DB.Where("msg_key = ?", msgKey).Delete(&models.ChatMessage{}).Error
+}
@@ -0,0 +1,36 @@
+package dao
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/models"
+	"gorm.io/gorm"
+)
+
+// DepartmentScope 按数据范围过滤查询，column 为带表名的部门列（如 employees.dep_id）
+func DepartmentScope(scope *models.DataScope, column string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if scope.All {
+			return db
+		}
+		if len(scope.DepIDs) == 0 {
+			return db.Where("1 = 0")
+		}
+		return db.Where(column+" IN ?", scope.DepIDs)
+	}
+}
+
+// GetManagedDepartmentIDs 查询员工担任负责人的部门
+func GetManagedDepartmentIDs(empID uint) ([]uint, error) {
+	var ids []uint
+	err := config.DB.Model(&models.Department{}).
+		Where("manager_id = ?", empID).
+		Pluck("dep_id", &ids).Error
+	return ids, err
+}
+
+// CountManagedDepartments 员工担任负责人的部门数量
+func CountManagedDepartments(tx *gorm.DB, empID uint) (int64, error) {
+	var count int64
+	err := tx.Model(&models.Department{}).Where("manager_id = ?", empID).Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,46 @@
+package dao
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/models"
+)
+
+// ListGroupsByMember 账号所在的全部群聊及其在群内的角色
+func ListGroupsByMember(userID int) ([]models.GroupDTO, error) {
+	var groups []models.GroupDTO
+	err := config.DB.Model(&models.Group{}).
+		Select("`groups`.*, users_groups.role AS my_role").
+		Joins("JOIN users_groups ON users_groups.group_id = `groups`.id").
+		Where("users_groups.user_id = ?", userID).
+		Order("`groups`.created_at DESC").
+		Scan(&groups).Error
+	return groups, err
+}
+
+// GetGroupMember 查询群成员，不在群内时返回 gorm.ErrRecordNotFound
+func GetGroupMember(groupID string, userID int) (*models.UsersGroup, error) {
+	var member models.UsersGroup
+	err := config.DB.Where("group_id = ? AND user_id = ?", groupID, userID).First(&member).Error
+	return &member, err
+}
+
+// ListGroupMembers 群成员列表：群主、管理员在前，其余按入群时间
+func ListGroupMembers(groupID string) ([]models.GroupMemberDTO, error) {
+	var members []models.GroupMemberDTO
+	err := config.DB.Model(&models.UsersGroup{}).
+		Select("users_groups.user_id, accounts.username, users_groups.role, users_groups.created_at AS joined_at").
+		Joins("LEFT JOIN accounts ON accounts.id = users_groups.user_id").
+		Where("users_groups.group_id = ?", groupID).
+		Order("FIELD(users_groups.role, 'owner', 'admin', 'member'), users_groups.created_at").
+		Scan(&members).Error
+	return members, err
+}
+
+// GetGroupMemberIDs 群内全部成员的账号ID
+func GetGroupMemberIDs(groupID string) ([]int, error) {
+	var ids []int
+	err := config.DB.Model(&models.UsersGroup{}).
+		Where("group_id = ?", groupID).
+		Pluck("user_id", &ids).Error
+	return ids, err
+}
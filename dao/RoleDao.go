@@ -0,0 +1,101 @@
+package dao
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/models"
+	"gorm.io/gorm"
+)
+
+// GetUserPermissionCodes 查询用户通过角色获得的全部权限编码
+func GetUserPermissionCodes(userType string, userID uint) ([]string, error) {
+	var codes []string
+	err := config.DB.Model(&models.UserRole{}).
+		Distinct("permissions.code").
+		Joins("JOIN role_permissions ON role_permissions.role_id = user_roles.role_id").
+		Joins("JOIN permissions ON permissions.id = role_permissions.permission_id").
+		Where("user_roles.user_type = ? AND user_roles.user_id = ?", userType, userID).
+		Pluck("permissions.code", &codes).Error
+	return codes, err
+}
+
+// GetUserDataScopes 查询用户各角色的数据范围
+func GetUserDataScopes(userType string, userID uint) ([]string, error) {
+	var scopes []string
+	err := config.DB.Model(&models.UserRole{}).
+		Distinct("roles.data_scope").
+		Joins("JOIN roles ON roles.id = user_roles.role_id").
+		Where("user_roles.user_type = ? AND user_roles.user_id = ?", userType, userID).
+		Pluck("roles.data_scope", &scopes).Error
+	return scopes, err
+}
+
+func ListRoles() ([]models.Role, error) {
+	var roles []models.Role
+	err := config.DB.Preload("Permissions").Order("id").Find(&roles).Error
+	return roles, err
+}
+
+func GetRoleByID(id uint) (*models.Role, error) {
+	var role models.Role
+	err := config.DB.Preload("Permissions").First(&role, id).Error
+	return &role, err
+}
+
+func GetRoleByName(name string) (*models.Role, error) {
+	var role models.Role
+	err := config.DB.Where("name = ?", name).First(&role).Error
+	return &role, err
+}
+
+func ListPermissions() ([]models.Permission, error) {
+	var perms []models.Permission
+	err := config.DB.Order("code").Find(&perms).Error
+	return perms, err
+}
+
+func GetPermissionsByCodes(codes []string) ([]models.Permission, error) {
+	var perms []models.Permission
+	if len(codes) == 0 {
+		return perms, nil
+	}
+	err := config.DB.Where("code IN ?", codes).Find(&perms).Error
+	return perms, err
+}
+
+// ReplaceRolePermissions 整体替换角色的权限
+func ReplaceRolePermissions(tx *gorm.DB, role *models.Role, perms []models.Permission) error {
+	return tx.Model(role).Association("Permissions").Replace(perms)
+}
+
+// DeleteRole 删除角色及其权限、用户关联
+func DeleteRole(role *models.Role) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(role).Association("Permissions").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Where("role_id = ?", role.ID).Delete(&models.UserRole{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(role).Error
+	})
+}
+
+func ListRoleMembers(roleID uint) ([]models.UserRole, error) {
+	var members []models.UserRole
+	err := config.DB.Where("role_id = ?", roleID).Order("id").Find(&members).Error
+	return members, err
+}
+
+// AssignRole 分配角色（已存在时忽略）
+func AssignRole(userType string, userID, roleID uint) error {
+	ur := models.UserRole{UserType: userType, UserID: userID, RoleID: roleID}
+	return config.DB.Where(ur).FirstOrCreate(&ur).Error
+}
+
+// UnassignRole 取消分配，返回是否确实删除了记录
+func UnassignRole(userType string, userID, roleID uint) (bool, error) {
+	result := config.DB.
+		Where("user_type = ? AND user_id = ? AND role_id = ?", userType, userID, roleID).
+		Delete(&models.UserRole{})
+	return result.RowsAffected > 0, result.Error
+}
@@ -6,7 +6,11 @@ import (
 	"EmployeeManagementDemo/models"
 	"EmployeeManagementDemo/routes"
 	"EmployeeManagementDemo/services"
+	"EmployeeManagementDemo/utils"
+	"EmployeeManagementDemo/websocket"
+	"context"
 	"encoding/json"
+	"errors"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	amqp "github.com/rabbitmq/amqp091-go"
@@ -14,6 +18,7 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"go.uber.org/zap"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -45,6 +50,29 @@ func main() {
 	// 新增配置加载（必须放在最前面）
 	config.LoadConfig()
 
+	// 加载 JWT 签名密钥
+	if err := utils.InitJWTKeys(); err != nil {
+		log.Fatalf("JWT 密钥加载失败: %v", err)
+	}
+
+	// 初始化邮件发送器
+	services.InitMailSender()
+
+	// 加载密码策略（禁用密码列表）
+	if err := services.InitPasswordPolicy(); err != nil {
+		log.Fatalf("密码策略加载失败: %v", err)
+	}
+
+	// 初始化登录认证方式（本地密码 / LDAP）
+	if err := services.InitAuthProviders(); err != nil {
+		log.Fatalf("认证方式初始化失败: %v", err)
+	}
+
+	// 初始化聊天附件存储（本地目录 / S3）
+	if err := services.InitAttachmentStorage(); err != nil {
+		log.Fatalf("附件存储初始化失败: %v", err)
+	}
+
 	// 初始化 MySQL 并自动迁移表结构
 	setupDatabase()
 
@@ -55,6 +83,19 @@ func main() {
 
 	// 启动日志消费者
 	services.StartLogConsumer()
+
+	// 启动聊天调度协程，并订阅会话吊销通知（踢人/注销时断开对应的 WebSocket 连接），随服务关闭
+	// DefaultHub 须在注册路由之前创建
+	hubCtx, stopHub := context.WithCancel(config.Ctx)
+	websocket.DefaultHub = websocket.NewHub(models.NewClientManager(), config.Rdb)
+	hubDone := make(chan struct{})
+	go func() {
+		defer close(hubDone)
+		websocket.DefaultHub.Start(hubCtx)
+	}()
+	go websocket.DefaultHub.WatchRevocations(hubCtx)
+	// 定期清理上传后一直没有发送的聊天附件，随服务关闭
+	go services.StartAttachmentCleanup(hubCtx)
 	log.Println("消息队列访问地址：\nhttp://localhost:15673/")
 
 	// 初始化 Gin 引擎
@@ -97,10 +138,10 @@ func main() {
 	setupRoutes(router)
 
 	// 启动 HTTP 服务器
-	go startServer(router)
+	srv := startServer(router)
 
 	// 优雅关机处理
-	waitForShutdown()
+	waitForShutdown(srv, stopHub, hubDone)
 
 	// 初始化 Redis（按需启用）
 	// setupRedis()
@@ -118,6 +159,7 @@ func setupDatabase() {
 
 	// 调整迁移顺序确保基础表先创建
 	err := config.DB.AutoMigrate(
+		&models.Account{},
 		&models.Admin{},
 		&models.Department{},
 		&models.Employee{},
@@ -126,6 +168,19 @@ func setupDatabase() {
 		&models.OperationLog{},
 		&models.ChatMessage{},
 		&models.Group{},
+		&models.UsersGroup{},
+		&models.Permission{},
+		&models.Role{},
+		&models.UserRole{},
+		&models.TwoFactorAuth{},
+		&models.RecoveryCode{},
+		&models.PasswordResetToken{},
+		&models.AdminInvitation{},
+		&models.ExternalIdentity{},
+		&models.PasswordHistory{},
+		&models.APIKey{},
+		&models.ChatAttachment{},
+		&models.ChatContact{},
 	)
 	if err != nil {
 		log.Fatalf("数据库迁移失败: %v", err)
@@ -133,6 +188,31 @@ func setupDatabase() {
 		log.Println("所有表已创建/更新")
 	}
 
+	// 为已有管理员/员工创建统一账号（重名时改名并记录日志）
+	if err := services.MigrateAccounts(); err != nil {
+		log.Fatalf("账号迁移失败: %v", err)
+	}
+
+	// 历史聊天消息补写会话ID、消息状态
+	if err := services.MigrateChatMessages(); err != nil {
+		log.Fatalf("聊天消息迁移失败: %v", err)
+	}
+
+	// 补建部门群并按员工所在部门对齐成员
+	if err := services.SyncDepartmentChannels(); err != nil {
+		log.Fatalf("部门群同步失败: %v", err)
+	}
+
+	// 同步权限目录与内置角色
+	if err := services.SeedRBAC(); err != nil {
+		log.Fatalf("RBAC 初始化失败: %v", err)
+	}
+
+	// 还没有管理员时必须配置初始化密钥
+	if err := services.CheckAdminBootstrapSecret(); err != nil {
+		log.Fatalf("管理员初始化密钥检查失败: %v", err)
+	}
+
 }
 
 // 注册路由
@@ -146,22 +226,33 @@ func setupRoutes(router *gin.Engine) {
 }
 
 // 启动 HTTP 服务器
-func startServer(router *gin.Engine) {
+func startServer(router *gin.Engine) *http.Server {
 	port := ":8080" // 默认端口
-	log.Printf("服务启动中，监听端口 %s", port)
-	if err := router.Run(port); err != nil {
-		log.Fatalf("服务器启动失败: %v", err)
-	}
+	srv := &http.Server{Addr: port, Handler: router}
+	go func() {
+		log.Printf("服务启动中，监听端口 %s", port)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("服务器启动失败: %v", err)
+		}
+	}()
+	return srv
 }
 
-// 优雅关机处理
-func waitForShutdown() {
+// 优雅关机处理：先停止接收新请求，再断开全部 WebSocket 连接
+func waitForShutdown(srv *http.Server, stopHub context.CancelFunc, hubDone <-chan struct{}) {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	<-sigChan
 	log.Println("接收到关机信号，服务正在关闭...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("HTTP 服务关闭超时: %v", err)
+	}
+	stopHub()
+	<-hubDone
 	// 这里可以添加资源释放逻辑（如关闭数据库连接）
-	os.Exit(0)
 }
 
 // 封装 Redis 初始化
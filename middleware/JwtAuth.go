@@ -8,8 +8,19 @@ import (
 	"strings"
 )
 
+// passwordExpiredRoutes 密码过期时仍可访问的接口（方法 + 路由）
+var passwordExpiredRoutes = map[string]bool{
+	http.MethodPut + " /api/profile/password": true,
+	http.MethodPost + " /api/logout":          true,
+}
+
 func JWTAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// 已由 APIKeyAuth 认证
+		if authenticatedByAPIKey(c) {
+			c.Next()
+			return
+		}
 
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -39,6 +50,13 @@ func JWTAuth() gin.HandlerFunc {
 			return
 		}
 
+		// 密码已过期的会话只能修改密码或注销
+		if claims.MustChangePassword && !passwordExpiredRoutes[c.Request.Method+" "+c.FullPath()] {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "密码已过期，请先修改密码", "must_change_password": true})
+			return
+		}
+
+		c.Set("principalID", claims.PrincipalID)
 		c.Set("userID", claims.UserID)
 		c.Set("userRole", claims.Role)
 		// 新增：
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestJWTAuthRestrictsExpiredPassword(t *testing.T) {
+	old := config.Cfg
+	t.Cleanup(func() { config.Cfg = old })
+	config.Cfg.JWT = config.JWTConfig{AccessTTL: time.Minute, Secret: strings.Repeat("k", 32)}
+	if err := utils.InitJWTKeys(); err != nil {
+		t.Fatal(err)
+	}
+	accountID := uint(1)
+	user := &models.Employee{EmpID: 1, AccountID: &accountID}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	api := r.Group("/api", JWTAuth())
+	api.GET("/profile", ok)
+	api.PUT("/profile/password", ok)
+	api.POST("/logout", ok)
+
+	status := func(token, method, path string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	expired, err := utils.GenerateJWT(user, "session", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/api/profile", http.StatusForbidden},
+		{http.MethodPut, "/api/profile/password", http.StatusOK},
+		{http.MethodPost, "/api/logout", http.StatusOK},
+	}
+	for _, tc := range cases {
+		if got := status(expired, tc.method, tc.path); got != tc.want {
+			t.Errorf("密码过期时 %s %s 返回 %d，期望 %d", tc.method, tc.path, got, tc.want)
+		}
+	}
+
+	valid, err := utils.GenerateJWT(user, "session", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := status(valid, http.MethodGet, "/api/profile"); got != http.StatusOK {
+		t.Fatalf("密码未过期时应可访问其他接口，得到 %d", got)
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"EmployeeManagementDemo/services"
+	"EmployeeManagementDemo/utils"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// RequirePermission 要求当前用户拥有全部指定权限（需放在 JWTAuth 之后）
+func RequirePermission(perms ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted, err := loadPermissions(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "权限查询失败"})
+			return
+		}
+		for _, p := range perms {
+			if !granted[p] {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "无权限访问: 缺少 " + p})
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// 同一请求内只查询一次权限，结果缓存在上下文中
+func loadPermissions(c *gin.Context) (map[string]bool, error) {
+	if cached, ok := c.Get("userPermissions"); ok {
+		return cached.(map[string]bool), nil
+	}
+	userID, err := utils.GetCurrentUserID(c)
+	if err != nil {
+		return map[string]bool{}, nil
+	}
+	role, err := utils.GetCurrentUserRole(c)
+	if err != nil {
+		return map[string]bool{}, nil
+	}
+	granted, err := services.GetUserPermissions(role, userID)
+	if err != nil {
+		return nil, err
+	}
+	c.Set("userPermissions", granted)
+	return granted, nil
+}
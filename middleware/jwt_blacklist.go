@@ -2,9 +2,9 @@
 package middleware
 
 import (
-	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/services"
 	"EmployeeManagementDemo/utils"
-	"fmt"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"strings"
 )
@@ -12,7 +12,12 @@ import (
 // middleware/jwt_blacklist.go
 func CheckJWTBlacklist() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 1. 检查 Token 是否在黑名单
+		// API 密钥没有会话，吊销后立即失效，无需再检查
+		if authenticatedByAPIKey(c) {
+			c.Next()
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			c.AbortWithStatusJSON(401, gin.H{"error": "未提供令牌"})
@@ -21,18 +26,7 @@ func CheckJWTBlacklist() gin.HandlerFunc {
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
-		// 检查 Token 黑名单
-		exists, err := config.Rdb.Exists(config.Ctx, "jwt_blacklist:"+tokenString).Result()
-		if err != nil {
-			c.AbortWithStatusJSON(500, gin.H{"error": "服务器错误"})
-			return
-		}
-		if exists == 1 {
-			c.AbortWithStatusJSON(401, gin.H{"error": "令牌已失效"})
-			return
-		}
-
-		// 2. 检查用户是否被踢出（需依赖 JWTAuth 设置的 claims）
+		// 需依赖 JWTAuth 设置的 claims
 		claimsInterface, ex := c.Get("claims")
 		if !ex {
 			c.AbortWithStatusJSON(401, gin.H{"error": "令牌无效"})
@@ -45,16 +39,15 @@ func CheckJWTBlacklist() gin.HandlerFunc {
 			return
 		}
 
-		// 检查用户是否被踢出
-		userID := claims.UserID
-		invalidateKey := "user_invalid:" + fmt.Sprint(userID)
-		kickTime, err := config.Rdb.Get(config.Ctx, invalidateKey).Int64()
-		if err == nil { // 存在踢出记录
-			tokenIssueTime := claims.IssuedAt.Unix()
-			if tokenIssueTime < kickTime {
-				c.AbortWithStatusJSON(401, gin.H{"error": "用户已被踢出"})
+		// 黑名单、踢出、会话吊销检查（与 WebSocket 握手共用）
+		if err := services.CheckAccessToken(tokenString, claims); err != nil {
+			if errors.Is(err, services.ErrAccessTokenInvalid) || errors.Is(err, services.ErrAccessTokenRevoked) ||
+				errors.Is(err, services.ErrUserKicked) || errors.Is(err, services.ErrSessionRevoked) {
+				c.AbortWithStatusJSON(401, gin.H{"error": err.Error()})
 				return
 			}
+			c.AbortWithStatusJSON(500, gin.H{"error": "服务器错误"})
+			return
 		}
 
 		c.Next()
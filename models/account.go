@@ -0,0 +1,38 @@
+// models/account.go
+package models
+
+import (
+	"golang.org/x/crypto/bcrypt"
+	"time"
+)
+
+// Account 统一登录身份：用户名全局唯一，密码只保存在这里。
+// 管理员、员工档案通过 AccountID 关联到账号，ID 即主体ID（principal ID）
+type Account struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Username  string    `gorm:"type:varchar(20);not null;uniqueIndex" json:"username"`
+	Password  string    `gorm:"type:varchar(200);not null;default:''" json:"-"` // 为空表示尚未设置密码（如管理员录入的员工），需走找回密码
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	PasswordChangedAt *time.Time `json:"password_changed_at"` // 最近一次设置密码的时间，用于强制定期更换
+}
+
+func (Account) TableName() string {
+	return "accounts"
+}
+
+func (a *Account) CheckPassword(password string) bool {
+	if a.Password == "" {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(a.Password), []byte(password)) == nil
+}
+
+func (a *Account) GetPassword() string {
+	return a.Password
+}
+
+func (a *Account) SetPassword(pwd string) {
+	a.Password = pwd
+}
@@ -1,14 +1,12 @@
 package models
 
-import "golang.org/x/crypto/bcrypt"
-
 type Admin struct {
-	AdminID       uint   `gorm:"primaryKey;autoIncrement;column:admin_id"` // 主键自增
-	AdminName     string `gorm:"type:varchar(20);not null;unique"`         // 不能为空且唯一
-	AdminPassword string `gorm:"type:varchar(200);not null"`               // 不能为空
-	AdminPhone    string `gorm:"type:char(11);not null"`                   // 固定11位手机号
-	AdminEmail    string `gorm:"type:varchar(50);unique"`                  // 邮箱唯一
-	Avatar        string `gorm:"type:varchar(100)"`                        // 头像路径
+	AdminID    uint   `gorm:"primaryKey;autoIncrement;column:admin_id"` // 主键自增
+	AccountID  *uint  `gorm:"uniqueIndex"`                              // 关联的登录账号
+	AdminName  string `gorm:"type:varchar(20);not null;unique"`         // 与账号用户名保持一致
+	AdminPhone string `gorm:"type:char(11);not null"`                   // 固定11位手机号
+	AdminEmail string `gorm:"type:varchar(50);unique"`                  // 邮箱唯一
+	Avatar     string `gorm:"type:varchar(100)"`                        // 头像路径
 
 }
 
@@ -23,16 +21,18 @@ func (Admin) TableName() string {
 //	return "InnoDB" // 必须使用支持外键的引擎
 //}
 
-func (a *Admin) CheckPassword(password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(a.AdminPassword), []byte(password))
-	return err == nil
-}
-
 // models/admin.go
 func (a *Admin) GetID() uint {
 	return a.AdminID
 }
 
+func (a *Admin) GetAccountID() uint {
+	if a.AccountID == nil {
+		return 0
+	}
+	return *a.AccountID
+}
+
 func (a *Admin) GetUsername() string {
 	return a.AdminName
 }
@@ -56,12 +56,3 @@ func (a *Admin) ToProfileResponse() AdminProfileResponse {
 		Phone:     a.AdminPhone,
 	}
 }
-
-// models/admin.go
-func (a *Admin) GetPassword() string {
-	return a.AdminPassword
-}
-
-func (a *Admin) SetPassword(pwd string) {
-	a.AdminPassword = pwd
-}
@@ -0,0 +1,44 @@
+// models/admin_invitation.go
+package models
+
+import "time"
+
+// 邀请状态（由时间字段推导，不落库）
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationRevoked  = "revoked"
+	InvitationExpired  = "expired"
+)
+
+// AdminInvitation 管理员邀请，一次性使用，令牌只保存摘要
+type AdminInvitation struct {
+	ID              uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	Email           string     `gorm:"type:varchar(50);not null;index" json:"email"`
+	RoleID          uint       `gorm:"not null" json:"role_id"`    // 注册后授予的角色
+	InvitedBy       uint       `gorm:"not null" json:"invited_by"` // 发出邀请的管理员
+	TokenHash       string     `gorm:"type:char(64);not null;uniqueIndex" json:"-"`
+	ExpiresAt       time.Time  `gorm:"not null" json:"expires_at"`
+	AcceptedAt      *time.Time `json:"accepted_at"`
+	AcceptedAdminID *uint      `json:"accepted_admin_id"`
+	RevokedAt       *time.Time `json:"revoked_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+func (AdminInvitation) TableName() string {
+	return "admin_invitations"
+}
+
+// Status 计算邀请当前状态
+func (i *AdminInvitation) Status(now time.Time) string {
+	switch {
+	case i.AcceptedAt != nil:
+		return InvitationAccepted
+	case i.RevokedAt != nil:
+		return InvitationRevoked
+	case now.After(i.ExpiresAt):
+		return InvitationExpired
+	default:
+		return InvitationPending
+	}
+}
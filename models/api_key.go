@@ -0,0 +1,66 @@
+// models/api_key.go
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// 操作人类型（写入操作日志）
+const (
+	ActorUser      = "user"      // 登录用户（管理员/员工），操作人ID为账号ID
+	ActorAPIKey    = "api_key"   // 服务间调用的 API 密钥，操作人ID为密钥ID
+	ActorAnonymous = "anonymous" // 未登录（如登录失败被锁定），操作人ID为 0
+)
+
+// API 密钥状态（由时间字段推导，不落库）
+const (
+	APIKeyActive  = "active"
+	APIKeyRevoked = "revoked"
+	APIKeyExpired = "expired"
+)
+
+// APIKeyScopes 允许授予 API 密钥的权限。角色、邀请和密钥管理只能由登录用户操作
+var APIKeyScopes = []string{
+	PermDepartmentStats,
+	PermEmployeeRead, PermEmployeeWrite, PermEmployeeExport, PermEmployeeImport,
+	PermLeaveRead, PermAttendanceRead,
+}
+
+// APIKey 服务间调用（薪资、门禁等系统）使用的密钥，只保存摘要
+type APIKey struct {
+	ID         uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name       string     `gorm:"type:varchar(50);not null" json:"name"`
+	Prefix     string     `gorm:"type:varchar(16);not null" json:"prefix"` // 密钥明文前缀，便于辨认
+	KeyHash    string     `gorm:"type:char(64);not null;uniqueIndex" json:"-"`
+	Scopes     string     `gorm:"type:varchar(500);not null" json:"-"` // 权限编码，逗号分隔
+	CreatedBy  uint       `gorm:"not null" json:"created_by"`          // 创建者账号ID
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `gorm:"type:varchar(45)" json:"last_used_ip"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+func (k *APIKey) ScopeList() []string {
+	if k.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(k.Scopes, ",")
+}
+
+// Status 计算密钥当前状态
+func (k *APIKey) Status(now time.Time) string {
+	switch {
+	case k.RevokedAt != nil:
+		return APIKeyRevoked
+	case now.After(k.ExpiresAt):
+		return APIKeyExpired
+	default:
+		return APIKeyActive
+	}
+}
@@ -0,0 +1,40 @@
+// models/chat_attachment.go
+package models
+
+import "time"
+
+// ChatAttachment 聊天附件，文件保存在存储后端，这里只记录元数据。
+// 上传者可随时下载；发到会话后，能看到该消息的用户也可下载
+type ChatAttachment struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UploaderID   int       `gorm:"not null;index" json:"uploader_id"` // 上传者账号ID
+	FileName     string    `gorm:"type:varchar(255);not null" json:"file_name"`
+	MIMEType     string    `gorm:"column:mime_type;type:varchar(100);not null" json:"mime_type"` // 按文件内容识别
+	Size         int64     `gorm:"not null" json:"size"`
+	StorageKey   string    `gorm:"type:varchar(255);not null" json:"-"`
+	ThumbnailKey string    `gorm:"type:varchar(255);not null;default:''" json:"-"` // 图片缩略图，非图片或无法解码时为空
+	Width        int       `json:"width,omitempty"`                                // 图片宽高（像素）
+	Height       int       `json:"height,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (a *ChatAttachment) ToDTO() *ChatAttachmentDTO {
+	return &ChatAttachmentDTO{
+		ID:           a.ID,
+		FileName:     a.FileName,
+		MIMEType:     a.MIMEType,
+		Size:         a.Size,
+		HasThumbnail: a.ThumbnailKey != "",
+		Width:        a.Width,
+		Height:       a.Height,
+	}
+}
+
+// ThumbnailType 缩略图格式：可能带透明通道的图片用 PNG，其余用 JPEG
+func (a *ChatAttachment) ThumbnailType() string {
+	switch a.MIMEType {
+	case "image/png", "image/gif", "image/webp":
+		return "image/png"
+	}
+	return "image/jpeg"
+}
@@ -0,0 +1,21 @@
+// models/chat_contact.go
+package models
+
+import "time"
+
+// 联系人关系状态
+const (
+	ContactPending  = "pending"  // UserID 向 ContactID 发出了好友申请
+	ContactAccepted = "accepted" // 互为联系人（双方各一条）
+	ContactBlocked  = "blocked"  // UserID 屏蔽了 ContactID
+)
+
+// ChatContact 聊天联系人，以账号ID标识，每个方向一条记录
+type ChatContact struct {
+	UserID    int       `gorm:"primaryKey;autoIncrement:false" json:"user_id"`
+	ContactID int       `gorm:"primaryKey;autoIncrement:false;index" json:"contact_id"`
+	Status    string    `gorm:"type:varchar(20);not null" json:"status"`
+	Message   string    `gorm:"type:varchar(200);not null;default:''" json:"message"` // 好友申请附言
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -1,86 +1,275 @@
 package models
 
 import (
+	"fmt"
 	"github.com/gorilla/websocket"
 	"gorm.io/gorm"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
-// ChatMessage 数据库存储消息结构体，用于持久化历史记录
+// ChatMessage 数据库存储消息结构体，用于持久化历史记录。
+// 群消息按接收人各存一份，发送人另存一份 RecipientID 为自己的副本（已读），
+// 因此用户可见的消息为：RecipientID 是自己的，或自己发出的单聊消息
 type ChatMessage struct {
 	gorm.Model
-	Direction   string //这条消息是从谁发给谁的
-	SendID      int    //发送者id
-	RecipientID int    //接受者id
-	GroupID     string //群id，该消息要发到哪个群里面去
-	Content     string //内容
-	Read        bool   //是否读了这条消息
+	ConversationID string `gorm:"type:varchar(64);index;not null;default:''"` //会话ID，见 DirectConversationID / GroupConversationID
+	Direction      string //这条消息是从谁发给谁的
+	SendID         int    `gorm:"index"` //发送者账号id
+	RecipientID    int    `gorm:"index"` //接受者账号id
+	GroupID        string //群id，该消息要发到哪个群里面去
+	Content        string `gorm:"index:idx_chat_messages_content,class:FULLTEXT,option:WITH PARSER ngram"` //内容，全文索引（ngram 分词，支持中文）
+	Read           bool   //是否读了这条消息（与 Status 为 read 一致）
+
+	MsgKey      string     `gorm:"type:varchar(32);index;not null;default:''"` //消息标识，群消息的各份副本相同，回执据此对应到发送方的消息
+	Status      string     `gorm:"type:varchar(10);not null;default:'sent'"`   //消息状态：sent / delivered / read
+	DeliveredAt *time.Time //送达接收方的时间
+	ReadAt      *time.Time //接收方确认已读的时间
+
+	EditedAt           *time.Time //最近一次编辑的时间
+	RecalledAt         *time.Time //撤回时间，撤回后内容清空
+	DeletedBySender    bool       `gorm:"not null;default:false"` //发送方已删除（仅对自己隐藏）
+	DeletedByRecipient bool       `gorm:"not null;default:false"` //接收方已删除（仅对自己隐藏）
+
+	AttachmentID *uint `gorm:"index"` //附件消息引用的附件，Content 为附言
+}
+
+// 消息状态：已发送（已落库）、已送达（推送到接收方至少一个连接）、已读（接收方确认）
+const (
+	MsgStatusSent      = "sent"
+	MsgStatusDelivered = "delivered"
+	MsgStatusRead      = "read"
+)
+
+func (m *ChatMessage) ToDTO() ChatMessageDTO {
+	return ChatMessageDTO{
+		ID:             m.ID,
+		ConversationID: m.ConversationID,
+		SenderID:       m.SendID,
+		RecipientID:    m.RecipientID,
+		GroupID:        m.GroupID,
+		Content:        m.Content,
+		Read:           m.Read,
+		MsgKey:         m.MsgKey,
+		Status:         m.Status,
+		DeliveredAt:    m.DeliveredAt,
+		ReadAt:         m.ReadAt,
+		Edited:         m.EditedAt != nil,
+		EditedAt:       m.EditedAt,
+		Recalled:       m.RecalledAt != nil,
+		CreatedAt:      m.CreatedAt,
+	}
+}
+
+// DirectConversationID 单聊会话ID：u:<较小账号ID>:<较大账号ID>
+func DirectConversationID(a, b int) string {
+	if a > b {
+		a, b = b, a
+	}
+	return fmt.Sprintf("u:%d:%d", a, b)
+}
+
+// GroupConversationID 群聊会话ID：g:<群ID>
+func GroupConversationID(groupID string) string {
+	return "g:" + groupID
+}
+
+// ParseConversationID 解析会话ID，单聊返回双方账号ID，群聊返回群ID
+func ParseConversationID(id string) (peers [2]int, groupID string, ok bool) {
+	switch {
+	case strings.HasPrefix(id, "u:"):
+		parts := strings.Split(id[2:], ":")
+		if len(parts) != 2 {
+			return peers, "", false
+		}
+		a, errA := strconv.Atoi(parts[0])
+		b, errB := strconv.Atoi(parts[1])
+		if errA != nil || errB != nil || a <= 0 || b <= 0 || DirectConversationID(a, b) != id {
+			return peers, "", false
+		}
+		return [2]int{a, b}, "", true
+	case strings.HasPrefix(id, "g:") && len(id) > 2:
+		return peers, id[2:], true
+	}
+	return peers, "", false
 }
 
 // Group 群聊结构体
 type Group struct {
-	ID           string ` gorm:"primaryKey"` //群id
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
-	DeletedAt    gorm.DeletedAt `gorm:"index"`
-	GroupName    string         `json:"group_name"`    //群名
-	GroupContent string         `json:"group_content"` //群签名
-	GroupIcon    string         `json:"group_icon"`    //群头像
-	GroupNum     int            //群人数
-	GroupOwnerId int            //群主id
-	Users        []User         `gorm:"many2many:users_groups;"` //群成员
+	ID           string         `gorm:"primaryKey;type:varchar(191)" json:"id"` //群id
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+	GroupName    string         `json:"group_name"`                              //群名
+	GroupContent string         `json:"group_content"`                           //群签名
+	GroupIcon    string         `json:"group_icon"`                              //群头像
+	GroupNum     int            `json:"group_num"`                               //群人数，随成员增减同步更新
+	GroupOwnerId int            `json:"group_owner_id"`                          //群主账号ID，部门群为 0
+	DepID        *uint          `gorm:"column:dep_id;uniqueIndex" json:"dep_id"` //部门群所属部门，成员随员工所在部门自动维护
 }
 
+// 群内角色：群主唯一，可任免管理员、转让群主、解散群；管理员可改群资料、增删普通成员
+const (
+	GroupRoleOwner  = "owner"
+	GroupRoleAdmin  = "admin"
+	GroupRoleMember = "member"
+)
+
+// UsersGroup 群成员，UserId 为账号ID
 type UsersGroup struct {
-	GroupId string `json:"group_id"`
-	UserId  int    `json:"user_id"`
+	GroupId   string    `gorm:"primaryKey;type:varchar(191)" json:"group_id"`
+	UserId    int       `gorm:"primaryKey;index" json:"user_id"`
+	Role      string    `gorm:"type:varchar(10);not null;default:'member'" json:"role"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 // ReplyMsg 用于处理请求后返回一些数据
 type ReplyMsg struct {
-	From    string `json:"from"`
-	Code    int    `json:"code"`
-	Content string `json:"content"`
+	From           string `json:"from"`
+	Code           int    `json:"code"`
+	Content        string `json:"content"`
+	ID             uint   `json:"id,omitempty"` //消息ID，已读确认时回传
+	MsgKey         string `json:"msg_key,omitempty"`
+	ConversationID string `json:"conversation_id,omitempty"`
+	SenderID       int    `json:"sender_id,omitempty"`
+
+	Attachment *ChatAttachmentDTO `json:"attachment,omitempty"`
 }
 
 // SendMsg 发送消息的类型
 type SendMsg struct {
-	Type        int    `json:"type"`
-	RecipientID int    `json:"recipient_id"` //接受者id
-	Content     string `json:"content"`
+	Type           int    `json:"type"`
+	RecipientID    int    `json:"recipient_id"` //接受者id
+	Content        string `json:"content"`
+	MessageIDs     []uint `json:"message_ids"`     //已读确认的消息ID
+	ConversationID string `json:"conversation_id"` //整个会话标记已读、输入状态所在的会话
+	AttachmentID   uint   `json:"attachment_id"`   //附件消息引用的附件（先经 REST 接口上传）
+}
+
+// 聊天事件类型
+const (
+	ChatEventSent             = "sent"              //消息已落库（回给发送方，含 msg_key）
+	ChatEventDelivered        = "delivered"         //消息已送达某个接收方
+	ChatEventRead             = "read"              //消息已被某个接收方读取
+	ChatEventConversationRead = "conversation_read" //自己在其他设备上把会话标记为已读
+	ChatEventTyping           = "typing"            //对方正在输入 / 停止输入
+	ChatEventEdited           = "edited"            //消息被发送方编辑（含新内容）
+	ChatEventRecalled         = "recalled"          //消息被发送方撤回
+	ChatEventRemoved          = "removed"           //消息被管理员删除
+	ChatEventPresence         = "presence"          //联系人在线状态变化
+	ChatEventContactRequest   = "contact_request"   //收到好友申请
+	ChatEventContactAccepted  = "contact_accepted"  //好友申请已通过
+)
+
+// 在线状态：online/offline 由连接自动维护，away/busy 由用户设置，离线后保留、再次上线时生效
+const (
+	PresenceOnline  = "online"
+	PresenceAway    = "away"
+	PresenceBusy    = "busy"
+	PresenceOffline = "offline"
+)
+
+// ChatEvent 推送给客户端的事件帧（回执、输入状态），与聊天消息帧 ReplyMsg 以 event 字段区分，不落库
+type ChatEvent struct {
+	Event          string    `json:"event"`
+	ConversationID string    `json:"conversation_id,omitempty"`
+	MsgKey         string    `json:"msg_key,omitempty"`
+	MessageID      uint      `json:"message_id,omitempty"` //接收方那一份消息的ID
+	UserID         int       `json:"user_id,omitempty"`    //触发事件的账号（接收方、读者、输入者）
+	Typing         bool      `json:"typing,omitempty"`
+	Content        string    `json:"content,omitempty"` //编辑后的内容、好友申请附言
+	Status         string    `json:"status,omitempty"`  //在线状态
+	At             time.Time `json:"at"`
 }
 
-// Client 用户类
+// Client 一个 WebSocket 连接。连接只由写协程写入，其他协程通过 Enqueue 投递已编码好的帧
 type Client struct {
-	ID          string          //消息的去向
-	RecipientID int             //接受者id
-	SendID      int             //发送人的id
-	GroupID     string          //群聊id
-	Socket      *websocket.Conn //websocket连接对象
-	Send        chan []byte     //发送消息用的管道
+	ID        string          //连接标识（账号ID->）
+	SendID    int             //发送人的id（账号ID）
+	SessionID string          //建立连接所用令牌的会话ID，会话被吊销时断开
+	Socket    *websocket.Conn //websocket连接对象
+	Send      chan []byte     //待发送的帧（有界缓冲）
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewClient 创建连接，bufferSize 为发送缓冲区大小
+func NewClient(sendID int, sessionID string, conn *websocket.Conn, bufferSize int) *Client {
+	return &Client{
+		ID:        strconv.Itoa(sendID) + "->",
+		SendID:    sendID,
+		SessionID: sessionID,
+		Socket:    conn,
+		Send:      make(chan []byte, bufferSize),
+		done:      make(chan struct{}),
+	}
 }
 
-// Broadcast 广播类，包括广播内容和源用户
+// Enqueue 非阻塞投递，连接已关闭或缓冲区已满时返回 false
+func (c *Client) Enqueue(frame []byte) bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+	select {
+	case c.Send <- frame:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close 通知写协程发送关闭帧并断开连接，可重复调用
+func (c *Client) Close() {
+	c.closeOnce.Do(func() { close(c.done) })
+}
+
+// Done 连接关闭后可读
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Broadcast 一条待投递的消息。收发双方和群ID随消息传递，不依赖（会被并发修改的）连接状态
 type Broadcast struct {
-	Client  *Client
-	Message []byte
-	Type    int
+	Client      *Client // 发送方连接，用于回执
+	RecipientID int
+	GroupID     string
+	MsgKey      string
+	Attachment  *ChatAttachment // 附件消息引用的附件
+	Message     []byte
+	Type        int
+}
+
+// RelayFrame 投递给某个账号在本实例上全部连接的帧（本实例的消息、事件，或其他实例转发来的消息）
+type RelayFrame struct {
+	RecipientID int    `json:"recipient_id"`
+	Frame       []byte `json:"frame"`
+
+	Delivered chan bool `json:"-"` // 非空时调度协程投递后写入是否有连接接收，须有缓冲
 }
 
-// ClientManager 用户管理,用于管理用户的连接及断开连接
+// ClientManager 用户管理,用于管理用户的连接及断开连接。Clients 只由 websocket 调度协程读写，
+// 其他协程经通道交给它处理
 type ClientManager struct {
-	Clients    map[string]*Client
-	Broadcast  chan *Broadcast
-	Reply      chan *Client
+	NodeID     string                       // 实例ID，多实例部署时用于在线状态登记和消息转发，为空时创建调度实例时生成
+	Clients    map[int]map[*Client]struct{} // 账号ID -> 该账号在本实例上的全部连接（多设备）
+	Relay      chan *RelayFrame
 	Register   chan *Client
 	Unregister chan *Client
+	Quit       chan struct{} // 调度协程退出后关闭，之后的注册/投递直接放弃
 }
 
-// Manager 创建一个用户管理对象
-var Manager = ClientManager{
-	Clients:    make(map[string]*Client), // 参与连接的用户，出于性能的考虑，需要设置最大连接数
-	Broadcast:  make(chan *Broadcast),
-	Register:   make(chan *Client), //新建立的连接访放入这里面
-	Reply:      make(chan *Client),
-	Unregister: make(chan *Client), //新断开的连接放入这里面
+// NewClientManager 创建一个用户管理对象
+func NewClientManager() *ClientManager {
+	return &ClientManager{
+		Clients:    make(map[int]map[*Client]struct{}), // 参与连接的用户，出于性能的考虑，需要设置最大连接数
+		Relay:      make(chan *RelayFrame),
+		Register:   make(chan *Client), //新建立的连接访放入这里面
+		Unregister: make(chan *Client), //新断开的连接放入这里面
+		Quit:       make(chan struct{}),
+	}
 }
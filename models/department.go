@@ -2,8 +2,9 @@
 package models
 
 type Department struct {
-	DepID  uint   `gorm:"primaryKey;autoIncrement;column:dep_id" json:"dep_id"` // 主键自增
-	Depart string `gorm:"type:varchar(20);not null;uniqueIndex;" json:"depart"` // 部门名称唯一
+	DepID     uint   `gorm:"primaryKey;autoIncrement;column:dep_id" json:"dep_id"` // 主键自增
+	Depart    string `gorm:"type:varchar(20);not null;uniqueIndex;" json:"depart"` // 部门名称唯一
+	ManagerID *uint  `gorm:"column:manager_id;index" json:"manager_id"`            // 部门负责人（员工ID），可为空
 
 }
 
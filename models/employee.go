@@ -1,15 +1,14 @@
 package models
 
 import (
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 type Employee struct {
 	EmpID     uint           `gorm:"primaryKey;autoIncrement;column:emp_id" json:"emp_id"`
+	AccountID *uint          `gorm:"uniqueIndex" json:"account_id"` // 关联的登录账号
 	DepID     uint           `gorm:"column:dep_id;index;comment:所属部门ID" json:"dep_id"`
-	Username  string         `gorm:"type:varchar(20);not null;unique;column:username" json:"username"`
-	Password  string         `gorm:"type:varchar(200);not null" json:"password"`
+	Username  string         `gorm:"type:varchar(20);not null;unique;column:username" json:"username"` // 与账号用户名保持一致
 	Position  string         `gorm:"type:varchar(50)" json:"position"`
 	Gender    string         `gorm:"type:enum('男','女','其他');default:'其他'" json:"gender"`
 	Email     string         `gorm:"type:varchar(50)" json:"email"`
@@ -25,17 +24,18 @@ func (Employee) TableName() string {
 	return "employees"
 }
 
-// CheckPassword models/employee.go
-func (e *Employee) CheckPassword(password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(e.Password), []byte(password))
-	return err == nil
-}
-
 // GetID models/employee.go
 func (e *Employee) GetID() uint {
 	return e.EmpID
 }
 
+func (e *Employee) GetAccountID() uint {
+	if e.AccountID == nil {
+		return 0
+	}
+	return *e.AccountID
+}
+
 func (e *Employee) GetUsername() string {
 	return e.Username
 }
@@ -53,12 +53,3 @@ func (e *Employee) ToProfileResponse() EmployeeProfileResponse {
 		DepID:    e.DepID,
 	}
 }
-
-// models/employee.go
-func (e *Employee) GetPassword() string {
-	return e.Password
-}
-
-func (e *Employee) SetPassword(pwd string) {
-	e.Password = pwd
-}
@@ -0,0 +1,19 @@
+// models/external_identity.go
+package models
+
+import "time"
+
+// ExternalIdentity 外部身份（OIDC issuer + subject）与本地账号的绑定
+type ExternalIdentity struct {
+	ID          uint   `gorm:"primaryKey;autoIncrement"`
+	Provider    string `gorm:"type:varchar(191);not null;uniqueIndex:idx_ext_provider_subject"` // IdP issuer
+	Subject     string `gorm:"type:varchar(191);not null;uniqueIndex:idx_ext_provider_subject"`
+	AccountID   uint   `gorm:"not null;index"`
+	Email       string `gorm:"type:varchar(100)"`
+	CreatedAt   time.Time
+	LastLoginAt time.Time
+}
+
+func (ExternalIdentity) TableName() string {
+	return "external_identities"
+}
@@ -10,6 +10,7 @@ import (
 type LeaveRequest struct {
 	ID         uint      `gorm:"primaryKey;autoIncrement;column:id"`
 	AdminID    *uint     `gorm:"column:admin_id;index"`        // 使用指针，允许为空
+	ApproverID *uint     `gorm:"column:approver_id;index"`     // 部门负责人（员工）审批时记录其员工ID
 	EmpID      uint      `gorm:"column:emp_id;index;not null"` // 使用指针，允许为空
 	Reason     string    `gorm:"type:text;size:100;not null"`
 	StartTime  time.Time `gorm:"type:datetime;not null"` // 非指针
@@ -5,9 +5,10 @@ import "time"
 
 type OperationLog struct {
 	ID        uint   `gorm:"primaryKey"`
-	UserID    uint   `gorm:"index"`    // 操作人ID（如果是登录/注销，即用户自己）
-	Action    string `gorm:"size:100"` // 操作类型：login, logout, kick_user
-	TargetID  string `gorm:"size:100"` // 被操作对象ID（如被踢用户ID，登录时留空）
+	UserID    uint   `gorm:"index"`                           // 操作人ID：用户为账号ID（跨管理员/员工唯一），API 密钥为密钥ID，未登录为 0
+	ActorType string `gorm:"size:20;not null;default:'user'"` // 操作人类型：user、api_key 或 anonymous
+	Action    string `gorm:"size:100"`                        // 操作类型：login, logout, kick_user
+	TargetID  string `gorm:"size:100"`                        // 被操作对象ID（如被踢用户ID，登录时留空）
 	CreatedAt time.Time
 }
 
@@ -0,0 +1,16 @@
+// models/password_history.go
+package models
+
+import "time"
+
+// PasswordHistory 账号用过的密码（bcrypt 哈希），用于禁止重复使用最近的密码
+type PasswordHistory struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	AccountID uint      `gorm:"not null;index" json:"account_id"`
+	Password  string    `gorm:"type:varchar(200);not null" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (PasswordHistory) TableName() string {
+	return "password_histories"
+}
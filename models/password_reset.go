@@ -0,0 +1,19 @@
+// models/password_reset.go
+package models
+
+import "time"
+
+// PasswordResetToken 找回密码的一次性令牌（只保存摘要）
+type PasswordResetToken struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement"`
+	UserType  string     `gorm:"type:varchar(20);not null;index:idx_reset_user"`
+	UserID    uint       `gorm:"not null;index:idx_reset_user"`
+	TokenHash string     `gorm:"type:char(64);not null;uniqueIndex"`
+	ExpiresAt time.Time  `gorm:"not null"`
+	UsedAt    *time.Time // 使用后即失效
+	CreatedAt time.Time
+}
+
+func (PasswordResetToken) TableName() string {
+	return "password_reset_tokens"
+}
@@ -5,21 +5,28 @@ import "time"
 // controllers/LoginAuth.go
 type RegisterRequest struct {
 	Username        string `json:"username" binding:"required,min=4,max=20"`
-	Password        string `json:"password" binding:"required,min=6,max=20"`
-	ConfirmPassword string `json:"confirmPassword" binding:"required,min=6,max=20"`
+	Password        string `json:"password" binding:"required"` // 长度、字符类型等由密码策略校验
+	ConfirmPassword string `json:"confirmPassword" binding:"required"`
 	Email           string `json:"email" binding:"required,email"`
 	Phone           string `json:"phone" binding:"required,len=11"`
 }
 
 type AdminRegisterRequest struct {
 	RegisterRequest
-	SecretKey string `json:"secret_key" binding:"required"` // 必须携带密钥
+	InviteToken string `json:"invite_token"` // 邀请令牌
+	SecretKey   string `json:"secret_key"`   // 初始化密钥，仅在尚无管理员时有效
 }
 
 // controllers/LoginAuth.go
 type LoginRequest struct {
 	Username string `json:"username" binding:"required,min=4,max=20"`
-	Password string `json:"password" binding:"required,min=6,max=20"`
+	Password string `json:"password" binding:"required"`
+	UserType string `json:"user_type" binding:"omitempty,oneof=admin employee"` // 账号同时关联管理员和员工档案时指定登录身份，默认管理员
+}
+
+// RefreshTokenRequest 刷新令牌请求
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
 // models/request.go
@@ -31,8 +38,8 @@ type UpdateProfileRequest struct {
 }
 
 type UpdatePasswordRequest struct {
-	OldPassword string `json:"old_password" binding:"required"`       // 必须提供旧密码
-	NewPassword string `json:"new_password" binding:"required,min=6"` // 新密码至少6位
+	OldPassword string `json:"old_password" binding:"required"` // 必须提供旧密码
+	NewPassword string `json:"new_password" binding:"required"` // 新密码需符合密码策略
 }
 
 // 创建部门请求
@@ -76,3 +83,129 @@ type ApproveLeaveRequest struct {
 	Status  string `json:"status" binding:"required,oneof=approved rejected"` // 审批结果
 	Comment string `json:"comment" binding:"omitempty,max=200"`               // 审批意见（可选）
 }
+
+// 创建角色请求
+type CreateRoleRequest struct {
+	Name        string   `json:"name" binding:"required,min=2,max=50"`
+	Description string   `json:"description" binding:"omitempty,max=200"`
+	DataScope   string   `json:"data_scope" binding:"omitempty,oneof=all department"` // 默认 all
+	Permissions []string `json:"permissions"`                                         // 权限编码列表
+}
+
+// 更新角色请求
+type UpdateRoleRequest struct {
+	Description string `json:"description" binding:"omitempty,max=200"`
+	DataScope   string `json:"data_scope" binding:"omitempty,oneof=all department"`
+}
+
+// 设置角色权限请求（整体替换）
+type SetRolePermissionsRequest struct {
+	Permissions []string `json:"permissions"`
+}
+
+// 给用户分配角色请求
+type AssignRoleRequest struct {
+	UserType string `json:"user_type" binding:"required,oneof=admin employee"`
+	UserID   uint   `json:"user_id" binding:"required"`
+}
+
+// 任命部门负责人请求（emp_id 为 0 表示撤销）
+type SetDepartmentManagerRequest struct {
+	EmpID uint `json:"emp_id"`
+}
+
+// 两步验证码请求（TOTP 或恢复码）
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// 登录第二步：用挑战令牌 + 验证码换取正式令牌
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+// 登录中强制绑定两步验证时获取密钥
+type TwoFactorChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+}
+
+// 解除登录锁定请求
+type UnlockLoginRequest struct {
+	Username string `json:"username"`
+	IP       string `json:"ip" binding:"omitempty,ip"`
+}
+
+// 忘记密码请求
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// 重置密码请求
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// 创建 API 密钥
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required,max=50"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"required,min=1,max=365"` // 密钥必须设置有效期
+}
+
+// 创建管理员邀请
+type CreateAdminInvitationRequest struct {
+	Email  string `json:"email" binding:"required,email"`
+	RoleID uint   `json:"role_id" binding:"required"`
+}
+
+// 创建群聊请求，创建者即群主
+type CreateGroupRequest struct {
+	GroupName    string `json:"group_name" binding:"required,max=50"`
+	GroupContent string `json:"group_content" binding:"omitempty,max=200"`
+	GroupIcon    string `json:"group_icon" binding:"omitempty,url,max=500"`
+	MemberIDs    []int  `json:"member_ids" binding:"omitempty,max=500,dive,gt=0"` // 初始成员账号ID
+}
+
+// 修改群资料请求（未传的字段保持不变）
+type UpdateGroupRequest struct {
+	GroupName    *string `json:"group_name" binding:"omitempty,min=1,max=50"`
+	GroupContent *string `json:"group_content" binding:"omitempty,max=200"`
+}
+
+// 设置群头像请求
+type SetGroupIconRequest struct {
+	GroupIcon string `json:"group_icon" binding:"required,url,max=500"`
+}
+
+// 添加群成员请求
+type AddGroupMembersRequest struct {
+	UserIDs []int `json:"user_ids" binding:"required,min=1,max=500,dive,gt=0"` // 账号ID
+}
+
+// 设置群成员角色请求（群主只能通过转让产生）
+type SetGroupMemberRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=admin member"`
+}
+
+// 转让群主请求
+type TransferGroupOwnerRequest struct {
+	UserID int `json:"user_id" binding:"required,gt=0"` // 新群主账号ID，须为群成员
+}
+
+// 编辑聊天消息请求
+type EditChatMessageRequest struct {
+	Content string `json:"content" binding:"required,max=5000"`
+}
+
+// 设置在线状态请求（离线由连接自动维护，不能手动设置）
+type SetPresenceRequest struct {
+	Status string `json:"status" binding:"required,oneof=online away busy"`
+}
+
+// 好友申请请求
+type AddContactRequest struct {
+	UserID  int    `json:"user_id" binding:"required,gt=0"` // 对方账号ID
+	Message string `json:"message" binding:"max=200"`       // 附言
+}
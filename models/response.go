@@ -38,15 +38,40 @@ type EmployeeProfileResponse struct {
 
 // User 定义用户实体
 type User struct {
-	ID   uint   `json:"id"`
-	Name string `json:"name"`
-	Role string `json:"role"`
+	ID          uint   `json:"id"`
+	PrincipalID uint   `json:"principal_id"` // 账号ID，会话、聊天等均以此标识用户
+	Name        string `json:"name"`
+	Role        string `json:"role"`
+}
+
+// TokenDTO 令牌对响应DTO（登录、刷新共用）
+type TokenDTO struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"` // 访问令牌有效期（秒）
+
+	MustChangePassword bool `json:"must_change_password"` // 密码已超过有效期，令牌只能用于修改密码和注销
 }
 
 // LoginDTO 定义登录响应DTO
 type LoginDTO struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+	TokenDTO
+	User          User     `json:"user"`
+	RecoveryCodes []string `json:"recovery_codes,omitempty"` // 登录时完成两步验证绑定才会返回
+}
+
+// TwoFactorChallengeDTO 密码校验通过但仍需两步验证时的登录响应
+type TwoFactorChallengeDTO struct {
+	MFARequired        bool   `json:"mfa_required"`
+	EnrollmentRequired bool   `json:"enrollment_required"` // 角色强制要求但尚未绑定，需先调用 /login/2fa/setup
+	ChallengeToken     string `json:"challenge_token"`
+	ExpiresIn          int64  `json:"expires_in"`
+}
+
+// TwoFactorSetupDTO 两步验证绑定信息
+type TwoFactorSetupDTO struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauth_uri"` // 用于生成二维码
 }
 
 // models/department.go
@@ -68,3 +93,148 @@ type EmployeeWithDepNameDTO struct {
 	Employee
 	DepName string `json:"dep_name"` // 仅用于接收联表查询结果
 }
+
+// AttendanceRecordDTO 管理端考勤查询结果（附带员工姓名与部门）
+type AttendanceRecordDTO struct {
+	SignRecord
+	Username string `json:"username"`
+	DepID    uint   `json:"dep_id"`
+}
+
+// AdminInvitationDTO 邀请列表项
+type AdminInvitationDTO struct {
+	AdminInvitation
+	RoleName string `json:"role_name"`
+	Status   string `json:"status"`
+}
+
+// APIKeyDTO API 密钥列表项
+type APIKeyDTO struct {
+	APIKey
+	ScopeList []string `json:"scopes"`
+	Status    string   `json:"status"`
+}
+
+// CreatedAPIKeyDTO 创建密钥的响应，明文密钥只在此时返回一次
+type CreatedAPIKeyDTO struct {
+	APIKeyDTO
+	Key string `json:"key"`
+}
+
+// SessionDTO 登录会话（一次登录对应一个会话，刷新令牌不会产生新会话）
+type SessionDTO struct {
+	ID        string    `json:"id"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+	LastSeen  time.Time `json:"last_seen"`
+	Current   bool      `json:"current"` // 是否为发起请求的会话
+}
+
+// GroupDTO 我的群聊列表项
+type GroupDTO struct {
+	Group
+	MyRole string `json:"my_role"`
+}
+
+// GroupMemberDTO 群成员
+type GroupMemberDTO struct {
+	UserID   int       `json:"user_id"` // 账号ID
+	Username string    `json:"username"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// 联系人来源
+const (
+	ContactSourceContact    = "contact"    // 通过好友申请添加
+	ContactSourceDepartment = "department" // 同部门同事，无需申请
+)
+
+// ContactDTO 联系人（或屏蔽的用户），since 为成为联系人（或屏蔽）的时间，同部门同事为空
+type ContactDTO struct {
+	UserID   int        `json:"user_id"` // 账号ID
+	Username string     `json:"username"`
+	Source   string     `json:"source,omitempty"`
+	Since    *time.Time `json:"since"`
+}
+
+// ContactRequestDTO 好友申请，user_id 为对方账号ID（收到的申请为申请人，发出的申请为被申请人）
+type ContactRequestDTO struct {
+	UserID    int       `json:"user_id"`
+	Username  string    `json:"username"`
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ChatMessageDTO 聊天消息
+type ChatMessageDTO struct {
+	ID             uint       `json:"id"`
+	ConversationID string     `json:"conversation_id"`
+	SenderID       int        `json:"sender_id"`    // 账号ID
+	RecipientID    int        `json:"recipient_id"` // 账号ID
+	GroupID        string     `json:"group_id,omitempty"`
+	Content        string     `json:"content"`
+	Read           bool       `json:"read"`
+	MsgKey         string     `json:"msg_key"`
+	Status         string     `json:"status"` // sent / delivered / read
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	ReadAt         *time.Time `json:"read_at"`
+	Edited         bool       `json:"edited"`
+	EditedAt       *time.Time `json:"edited_at"`
+	Recalled       bool       `json:"recalled"` // 已撤回，content 为空
+	CreatedAt      time.Time  `json:"created_at"`
+
+	Attachment *ChatAttachmentDTO `json:"attachment,omitempty"`
+}
+
+// MessageReceiptDTO 消息在某个接收方处的状态（群消息每个成员一条）
+type MessageReceiptDTO struct {
+	UserID      int        `json:"user_id"` // 接收方账号ID
+	Username    string     `json:"username"`
+	Status      string     `json:"status"`
+	DeliveredAt *time.Time `json:"delivered_at"`
+	ReadAt      *time.Time `json:"read_at"`
+}
+
+// PresenceDTO 用户的在线状态，last_seen 为最近一次在线的时间（从未上线为空）
+type PresenceDTO struct {
+	UserID   int        `json:"user_id"` // 账号ID
+	Status   string     `json:"status"`
+	Devices  int        `json:"devices"` // 在线连接数（多设备）
+	LastSeen *time.Time `json:"last_seen"`
+}
+
+// 会话类型
+const (
+	ConversationTypeUser  = "user"
+	ConversationTypeGroup = "group"
+)
+
+// ConversationDTO 会话列表项
+type ConversationDTO struct {
+	ID          string         `json:"id"`
+	Type        string         `json:"type"`
+	PeerID      int            `json:"peer_id,omitempty"` // 单聊对方账号ID
+	GroupID     string         `json:"group_id,omitempty"`
+	Name        string         `json:"name"` // 对方用户名或群名
+	LastMessage ChatMessageDTO `json:"last_message"`
+	UnreadCount int            `json:"unread_count"`
+}
+
+// ChatMessagePageDTO 消息分页，按消息ID倒序；next_before 作为下一页的 before 参数，为 0 表示没有更多
+type ChatMessagePageDTO struct {
+	Messages   []ChatMessageDTO `json:"messages"`
+	NextBefore uint             `json:"next_before"`
+}
+
+// ChatAttachmentDTO 附件信息，下载地址为 /api/chat/attachments/:id（缩略图 /thumbnail）
+type ChatAttachmentDTO struct {
+	ID           uint   `json:"id"`
+	FileName     string `json:"file_name"`
+	MIMEType     string `json:"mime_type"`
+	Size         int64  `json:"size"`
+	HasThumbnail bool   `json:"has_thumbnail"`
+	Width        int    `json:"width,omitempty"`
+	Height       int    `json:"height,omitempty"`
+}
@@ -0,0 +1,149 @@
+// models/role.go
+package models
+
+import "time"
+
+// 权限编码，格式为 资源:操作
+const (
+	PermDepartmentWrite = "department:write"
+	PermDepartmentStats = "department:stats"
+	PermEmployeeRead    = "employee:read"
+	PermEmployeeWrite   = "employee:write"
+	PermEmployeeExport  = "employee:export"
+	PermEmployeeImport  = "employee:import"
+	PermLeaveRead       = "leave:read"
+	PermLeaveApprove    = "leave:approve"
+	PermAttendanceRead  = "attendance:read"
+	PermUserKick        = "user:kick"
+	PermUserUnlock      = "user:unlock"
+	PermRoleManage      = "role:manage"
+	PermAdminInvite     = "admin:invite"
+	PermAPIKeyManage    = "apikey:manage"
+	PermChatModerate    = "chat:moderate"
+)
+
+// 内置角色名
+const (
+	RoleSuperAdmin = "super_admin"
+	RoleHR         = "hr"
+	RoleAuditor    = "auditor"
+	RoleManager    = "manager" // 部门负责人，任命时自动授予
+)
+
+// 数据范围：all 可见全部数据，department 仅可见自己负责部门的数据
+const (
+	DataScopeAll        = "all"
+	DataScopeDepartment = "department"
+)
+
+// 用户类型（对应 Claims.Role）
+const (
+	UserTypeAdmin    = "admin"
+	UserTypeEmployee = "employee"
+)
+
+// PermissionCatalog 系统内全部权限，启动时同步到数据库
+var PermissionCatalog = []Permission{
+	{Code: PermDepartmentWrite, Description: "创建/修改/删除部门"},
+	{Code: PermDepartmentStats, Description: "查看部门统计（平均薪资、人数）"},
+	{Code: PermEmployeeRead, Description: "查看员工列表"},
+	{Code: PermEmployeeWrite, Description: "创建/修改/删除员工"},
+	{Code: PermEmployeeExport, Description: "导出员工（含薪资）"},
+	{Code: PermEmployeeImport, Description: "批量导入员工"},
+	{Code: PermLeaveRead, Description: "查看请假记录"},
+	{Code: PermLeaveApprove, Description: "审批请假"},
+	{Code: PermAttendanceRead, Description: "查看员工考勤"},
+	{Code: PermUserKick, Description: "强制用户下线"},
+	{Code: PermUserUnlock, Description: "解除登录锁定"},
+	{Code: PermRoleManage, Description: "管理角色与权限分配"},
+	{Code: PermAdminInvite, Description: "邀请新管理员"},
+	{Code: PermAPIKeyManage, Description: "管理服务间调用的 API 密钥"},
+	{Code: PermChatModerate, Description: "删除违规聊天消息"},
+}
+
+// BuiltInRole 内置角色定义
+type BuiltInRole struct {
+	Description string
+	DataScope   string
+	Permissions []string
+}
+
+// BuiltInRoles 内置角色及其初始权限（super_admin 始终拥有全部权限）
+var BuiltInRoles = map[string]BuiltInRole{
+	RoleHR: {
+		Description: "人事，管理部门、员工和请假",
+		DataScope:   DataScopeAll,
+		Permissions: []string{
+			PermDepartmentWrite, PermDepartmentStats,
+			PermEmployeeRead, PermEmployeeWrite, PermEmployeeExport, PermEmployeeImport,
+			PermLeaveRead, PermLeaveApprove, PermAttendanceRead,
+		},
+	},
+	RoleAuditor: {
+		Description: "审计，只读",
+		DataScope:   DataScopeAll,
+		Permissions: []string{PermDepartmentStats, PermEmployeeRead, PermLeaveRead, PermAttendanceRead},
+	},
+	RoleManager: {
+		Description: "部门负责人，仅限所负责部门",
+		DataScope:   DataScopeDepartment,
+		Permissions: []string{PermEmployeeRead, PermLeaveRead, PermLeaveApprove, PermAttendanceRead},
+	},
+}
+
+// DataScope 当前用户可访问的数据范围
+type DataScope struct {
+	All    bool   // 不限部门
+	DepIDs []uint // All 为 false 时，仅限这些部门
+}
+
+// Contains 部门是否在数据范围内
+func (s *DataScope) Contains(depID uint) bool {
+	if s.All {
+		return true
+	}
+	for _, id := range s.DepIDs {
+		if id == depID {
+			return true
+		}
+	}
+	return false
+}
+
+type Permission struct {
+	ID          uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	Code        string `gorm:"type:varchar(50);not null;uniqueIndex" json:"code"`
+	Description string `gorm:"type:varchar(100)" json:"description"`
+}
+
+func (Permission) TableName() string {
+	return "permissions"
+}
+
+type Role struct {
+	ID          uint         `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name        string       `gorm:"type:varchar(50);not null;uniqueIndex" json:"name"`
+	Description string       `gorm:"type:varchar(200)" json:"description"`
+	BuiltIn     bool         `gorm:"not null;default:false" json:"built_in"`                    // 内置角色不可删除
+	DataScope   string       `gorm:"type:varchar(20);not null;default:'all'" json:"data_scope"` // all / department
+	Permissions []Permission `gorm:"many2many:role_permissions;" json:"permissions"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+func (Role) TableName() string {
+	return "roles"
+}
+
+// UserRole 用户与角色的关联，管理员和员工分属两张表，用 UserType 区分
+type UserRole struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserType  string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_user_role" json:"user_type"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_user_role" json:"user_id"`
+	RoleID    uint      `gorm:"not null;uniqueIndex:idx_user_role;index" json:"role_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (UserRole) TableName() string {
+	return "user_roles"
+}
@@ -0,0 +1,35 @@
+// models/two_factor.go
+package models
+
+import "time"
+
+// TwoFactorAuth 用户的 TOTP 两步验证配置
+type TwoFactorAuth struct {
+	ID           uint       `gorm:"primaryKey;autoIncrement" json:"-"`
+	UserType     string     `gorm:"type:varchar(20);not null;uniqueIndex:idx_2fa_user" json:"user_type"`
+	UserID       uint       `gorm:"not null;uniqueIndex:idx_2fa_user" json:"user_id"`
+	Secret       string     `gorm:"type:varchar(64);not null" json:"-"`
+	Enabled      bool       `gorm:"not null;default:false" json:"enabled"` // 绑定确认前为 false
+	LastUsedStep int64      `gorm:"not null;default:0" json:"-"`           // 最近一次使用的时间步，防止验证码重放
+	EnabledAt    *time.Time `json:"enabled_at"`
+	CreatedAt    time.Time  `json:"-"`
+	UpdatedAt    time.Time  `json:"-"`
+}
+
+func (TwoFactorAuth) TableName() string {
+	return "two_factor_auths"
+}
+
+// RecoveryCode 一次性恢复码（只保存摘要）
+type RecoveryCode struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	UserType  string `gorm:"type:varchar(20);not null;index:idx_recovery_user"`
+	UserID    uint   `gorm:"not null;index:idx_recovery_user"`
+	CodeHash  string `gorm:"type:char(64);not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
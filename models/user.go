@@ -6,10 +6,10 @@ type UserPasswordHolder interface {
 	SetPassword(string)
 }
 
-// 定义用户接口（统一Admin和Employee的认证行为）
+// 定义用户接口（统一Admin和Employee档案的行为，密码校验由 Account 负责）
 type BaseUser interface {
 	GetID() uint
+	GetAccountID() uint // 主体ID，跨管理员/员工唯一
 	GetUsername() string
 	GetRole() string
-	CheckPassword(password string) bool
 }
@@ -4,6 +4,7 @@ package routes
 import (
 	"EmployeeManagementDemo/controllers"
 	"EmployeeManagementDemo/middleware"
+	"EmployeeManagementDemo/models"
 	"EmployeeManagementDemo/websocket"
 	"github.com/gin-gonic/gin"
 )
@@ -14,15 +15,25 @@ func SetupAuthRoutes(r *gin.Engine) {
 	//r.POST("/api/register", controllers.Register)            // 注册接口（员工自助）
 	//r.POST("/api/admin/register", controllers.AdminRegister) // 管理员创建账号（需鉴权）
 
+	// 令牌校验公钥，供其他服务验证访问令牌
+	r.GET("/.well-known/jwks.json", controllers.GetJWKS)
+
 	// 公开接口（无需鉴权）
 	publicGroup := r.Group("/api")
 	{
 		// 登录注册
 		publicGroup.POST("/login", controllers.Login)
-		publicGroup.POST("/register", controllers.Register)            // 员工自助注册
-		publicGroup.POST("/admin/register", controllers.AdminRegister) // 管理员注册（需要密钥，但不需要登录）
-
-		publicGroup.GET("/ws", websocket.WsHandle) // 新增WebSocket路由
+		publicGroup.POST("/login/2fa", controllers.VerifyLoginTwoFactor)      // 两步验证登录第二步
+		publicGroup.POST("/login/2fa/setup", controllers.SetupLoginTwoFactor) // 角色强制要求时，登录中绑定两步验证
+		publicGroup.POST("/register", controllers.Register)                   // 员工自助注册
+		publicGroup.POST("/admin/register", controllers.AdminRegister)        // 管理员注册（凭邀请令牌；无管理员时凭初始化密钥）
+		publicGroup.POST("/token/refresh", controllers.RefreshToken)          // 刷新令牌换取新的访问令牌
+		publicGroup.POST("/password/forgot", controllers.ForgotPassword)      // 忘记密码，发送重置邮件
+		publicGroup.POST("/password/reset", controllers.ResetPassword)        // 凭邮件令牌重置密码
+		publicGroup.GET("/sso/login", controllers.SSOLogin)                   // 跳转企业 IdP 单点登录
+		publicGroup.GET("/sso/callback", controllers.SSOCallback)             // 单点登录回调，换取本系统令牌
+
+		publicGroup.GET("/ws", websocket.DefaultHub.WsHandle) // WebSocket 路由，握手时自行校验令牌（?token= 或子协议）
 	}
 
 	userGroup := r.Group("/api")
@@ -34,8 +45,64 @@ func SetupAuthRoutes(r *gin.Engine) {
 		userGroup.GET("/profile", controllers.GetProfile)
 		userGroup.PUT("/profile", controllers.UpdateProfile)
 
+		userGroup.GET("/profile/permissions", controllers.GetMyPermissions) // 当前用户权限列表
+
+		// 登录会话（设备）管理
+		userGroup.GET("/profile/sessions", controllers.ListMySessions)
+		userGroup.DELETE("/profile/sessions", controllers.RevokeMyOtherSessions) // 下线其他所有设备
+		userGroup.DELETE("/profile/sessions/:session_id", controllers.RevokeMySession)
+
+		// 两步验证
+		userGroup.GET("/profile/2fa", controllers.GetTwoFactorStatus)
+		userGroup.POST("/profile/2fa/setup", controllers.SetupTwoFactor)
+		userGroup.POST("/profile/2fa/enable", controllers.EnableTwoFactor)
+		userGroup.POST("/profile/2fa/disable", controllers.DisableTwoFactor)
+		userGroup.POST("/profile/2fa/recovery-codes", controllers.RegenerateRecoveryCodes)
+
 		userGroup.POST("/logout", controllers.Logout) // 用户注销接口
 
+		// 聊天记录（会话ID：单聊 u:<账号ID>:<账号ID>，群聊 g:<群ID>）
+		userGroup.GET("/chat/conversations", controllers.ListConversations)
+		userGroup.GET("/chat/conversations/:conversation_id/messages", controllers.ListConversationMessages)
+		userGroup.PUT("/chat/conversations/:conversation_id/read", controllers.MarkConversationRead) // 整个会话标记已读
+		userGroup.GET("/chat/messages/search", controllers.SearchChatMessages)
+		userGroup.GET("/chat/messages/:msg_key/receipts", controllers.ListMessageReceipts) // 各接收方的送达、已读状态
+		userGroup.PUT("/chat/messages/:msg_key", controllers.EditChatMessage)              // 编辑（限时）
+		userGroup.POST("/chat/messages/:msg_key/recall", controllers.RecallChatMessage)    // 撤回（限时）
+		userGroup.DELETE("/chat/messages/:msg_key", controllers.DeleteChatMessage)         // 仅对自己删除
+
+		// 聊天附件（上传后通过 WebSocket 附件消息发送，仅会话参与者可下载）
+		userGroup.POST("/chat/attachments", controllers.UploadChatAttachment)
+		userGroup.GET("/chat/attachments/:attachment_id", controllers.DownloadChatAttachment)
+		userGroup.GET("/chat/attachments/:attachment_id/thumbnail", controllers.DownloadChatAttachmentThumbnail)
+
+		// 在线状态（online/away/busy/offline，?ids= 为账号ID列表）
+		userGroup.GET("/chat/presence", controllers.GetPresence)
+		userGroup.PUT("/chat/presence", controllers.SetPresence)
+
+		// 联系人（同部门同事无需申请；非联系人发消息受 chat.non_contact_limit 限制）
+		userGroup.GET("/chat/contacts", controllers.ListContacts)
+		userGroup.DELETE("/chat/contacts/:user_id", controllers.RemoveContact)
+		userGroup.GET("/chat/contacts/requests", controllers.ListContactRequests)
+		userGroup.POST("/chat/contacts/requests", controllers.AddContact)
+		userGroup.POST("/chat/contacts/requests/:user_id/accept", controllers.AcceptContact)
+		userGroup.DELETE("/chat/contacts/requests/:user_id", controllers.DeleteContactRequest) // 拒绝或撤回申请
+		userGroup.GET("/chat/contacts/blocked", controllers.ListBlockedUsers)
+		userGroup.PUT("/chat/contacts/blocked/:user_id", controllers.BlockUser)
+		userGroup.DELETE("/chat/contacts/blocked/:user_id", controllers.UnblockUser)
+
+		// 群聊管理（群内权限由群主/管理员角色控制，user_id 为账号ID）
+		userGroup.GET("/chat/groups", controllers.ListMyGroups)
+		userGroup.POST("/chat/groups", controllers.CreateGroup)
+		userGroup.PUT("/chat/groups/:group_id", controllers.UpdateGroup)
+		userGroup.DELETE("/chat/groups/:group_id", controllers.DissolveGroup) // 解散群聊（仅群主）
+		userGroup.PUT("/chat/groups/:group_id/icon", controllers.SetGroupIcon)
+		userGroup.PUT("/chat/groups/:group_id/owner", controllers.TransferGroupOwner) // 转让群主
+		userGroup.GET("/chat/groups/:group_id/members", controllers.ListGroupMembers)
+		userGroup.POST("/chat/groups/:group_id/members", controllers.AddGroupMembers)
+		userGroup.DELETE("/chat/groups/:group_id/members/:user_id", controllers.RemoveGroupMember) // 移除自己即退群
+		userGroup.PUT("/chat/groups/:group_id/members/:user_id/role", controllers.SetGroupMemberRole)
+
 	}
 
 	// 需要登录的接口（需要鉴权）
@@ -55,31 +122,70 @@ func SetupAuthRoutes(r *gin.Engine) {
 
 	}
 
-	// 需要管理员权限的接口（鉴权 + 管理员角色）
+	// 管理接口（鉴权 + 细粒度权限），按权限而非 admin/employee 身份放行
 	adminGroup := r.Group("/api/admin")
-	adminGroup.Use(middleware.JWTAuth(), middleware.CheckJWTBlacklist(), middleware.RequireRole("admin"))
+	adminGroup.Use(middleware.APIKeyAuth(), middleware.JWTAuth(), middleware.CheckJWTBlacklist()) // 也接受 API 密钥（服务间调用）
 	{
 		//adminGroup.GET("/employees", controllers.ListEmployees)
 
 		// routers/router.go
-		adminGroup.GET("/departments/salary-averages", controllers.GetDepartmentSalaryAverages)
+		adminGroup.GET("/departments/salary-averages", middleware.RequirePermission(models.PermDepartmentStats), controllers.GetDepartmentSalaryAverages)
 		// routers/router.go
-		adminGroup.GET("/departments/headcounts", controllers.GetDepartmentHeadcounts)
-		adminGroup.POST("/departments", controllers.CreateDepartment)           // 创建部门
-		adminGroup.PUT("/departments/:dep_id", controllers.UpdateDepartment)    // 更新部门
-		adminGroup.DELETE("/departments/:dep_id", controllers.DeleteDepartment) // 删除部门
-		adminGroup.POST("/employees", controllers.CreateEmployee)               // POST   /api/employees
-		adminGroup.PUT("/employees/:emp_id", controllers.UpdateEmployee)        // PUT    /api/employees/:emp_id
-		adminGroup.DELETE("/employees/:emp_id", controllers.DeleteEmployee)     // DELETE /api/employees/:emp_id
-		adminGroup.GET("/employees/export", controllers.ExportEmployees)
-		adminGroup.POST("/employees/import", controllers.ImportEmployees)
-		adminGroup.GET("/employees", controllers.GetEmployees) // GET    /api/employees
-
-		adminGroup.PUT("/leave/:id/approve", controllers.ApproveLeaveRequest) // 审批
-		adminGroup.GET("/leaves", controllers.GetAllLeaveRequests)            // 查看所有记录
+		adminGroup.GET("/departments/headcounts", middleware.RequirePermission(models.PermDepartmentStats), controllers.GetDepartmentHeadcounts)
+		adminGroup.POST("/departments", middleware.RequirePermission(models.PermDepartmentWrite), controllers.CreateDepartment)           // 创建部门
+		adminGroup.PUT("/departments/:dep_id", middleware.RequirePermission(models.PermDepartmentWrite), controllers.UpdateDepartment)    // 更新部门
+		adminGroup.DELETE("/departments/:dep_id", middleware.RequirePermission(models.PermDepartmentWrite), controllers.DeleteDepartment) // 删除部门
+		adminGroup.PUT("/departments/:dep_id/manager", middleware.RequirePermission(models.PermDepartmentWrite), controllers.SetDepartmentManager)
+		adminGroup.POST("/employees", middleware.RequirePermission(models.PermEmployeeWrite), controllers.CreateEmployee)           // POST   /api/employees
+		adminGroup.PUT("/employees/:emp_id", middleware.RequirePermission(models.PermEmployeeWrite), controllers.UpdateEmployee)    // PUT    /api/employees/:emp_id
+		adminGroup.DELETE("/employees/:emp_id", middleware.RequirePermission(models.PermEmployeeWrite), controllers.DeleteEmployee) // DELETE /api/employees/:emp_id
+		adminGroup.GET("/employees/export", middleware.RequirePermission(models.PermEmployeeExport), controllers.ExportEmployees)
+		adminGroup.POST("/employees/import", middleware.RequirePermission(models.PermEmployeeImport), controllers.ImportEmployees)
+		adminGroup.GET("/employees", middleware.RequirePermission(models.PermEmployeeRead), controllers.GetEmployees) // GET    /api/employees
+
+		adminGroup.PUT("/leave/:id/approve", middleware.RequirePermission(models.PermLeaveApprove), controllers.ApproveLeaveRequest) // 审批
+		adminGroup.GET("/leaves", middleware.RequirePermission(models.PermLeaveRead), controllers.GetAllLeaveRequests)               // 查看所有记录
+		adminGroup.GET("/attendance", middleware.RequirePermission(models.PermAttendanceRead), controllers.GetAttendanceRecords)     // 查看员工考勤
 
 		// 管理员踢人接口（需要管理员权限）
-		adminGroup.PUT("/users/:user_id/kick", controllers.KickUser)
+		adminGroup.PUT("/users/:user_id/kick", middleware.RequirePermission(models.PermUserKick), controllers.KickUser)             // user_id 为账号ID
+		adminGroup.GET("/users/:user_id/sessions", middleware.RequirePermission(models.PermUserKick), controllers.ListUserSessions) // user_id 为账号ID
+		adminGroup.DELETE("/users/:user_id/sessions", middleware.RequirePermission(models.PermUserKick), controllers.RevokeUserSessions)
+		adminGroup.DELETE("/users/:user_id/sessions/:session_id", middleware.RequirePermission(models.PermUserKick), controllers.RevokeUserSession)
+		adminGroup.POST("/login-locks/unlock", middleware.RequirePermission(models.PermUserUnlock), controllers.UnlockLogin) // 解除登录锁定
+
+		// 管理员邀请
+		inviteGroup := adminGroup.Group("", middleware.RequirePermission(models.PermAdminInvite))
+		{
+			inviteGroup.GET("/invitations", controllers.ListAdminInvitations)
+			inviteGroup.POST("/invitations", controllers.CreateAdminInvitation)
+			inviteGroup.DELETE("/invitations/:invitation_id", controllers.RevokeAdminInvitation)
+		}
+
+		// API 密钥（该权限不能授予密钥本身，只能由登录用户管理）
+		apiKeyGroup := adminGroup.Group("", middleware.RequirePermission(models.PermAPIKeyManage))
+		{
+			apiKeyGroup.GET("/api-keys", controllers.ListAPIKeys)
+			apiKeyGroup.POST("/api-keys", controllers.CreateAPIKey)
+			apiKeyGroup.DELETE("/api-keys/:key_id", controllers.RevokeAPIKey)
+		}
+
+		// 聊天消息审核
+		adminGroup.DELETE("/chat/messages/:msg_key", middleware.RequirePermission(models.PermChatModerate), controllers.RemoveChatMessage)
+
+		// 角色与权限管理
+		roleGroup := adminGroup.Group("", middleware.RequirePermission(models.PermRoleManage))
+		{
+			roleGroup.GET("/permissions", controllers.ListPermissions)
+			roleGroup.GET("/roles", controllers.ListRoles)
+			roleGroup.POST("/roles", controllers.CreateRole)
+			roleGroup.PUT("/roles/:role_id", controllers.UpdateRole)
+			roleGroup.DELETE("/roles/:role_id", controllers.DeleteRole)
+			roleGroup.PUT("/roles/:role_id/permissions", controllers.SetRolePermissions)
+			roleGroup.GET("/roles/:role_id/members", controllers.ListRoleMembers)
+			roleGroup.POST("/roles/:role_id/members", controllers.AssignRole)
+			roleGroup.DELETE("/roles/:role_id/members/:user_type/:user_id", controllers.UnassignRole)
+		}
 
 	}
 
@@ -0,0 +1,133 @@
+// services/APIKeyService.go
+package services
+
+import (
+	"EmployeeManagementDemo/dao"
+	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/utils"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// API 密钥格式：emk_ + 48 位十六进制，服务端只保存 SHA-256 摘要
+const (
+	apiKeyPrefix        = "emk_"
+	apiKeyTouchInterval = time.Minute
+)
+
+var (
+	ErrAPIKeyInvalid       = errors.New("API 密钥无效或已过期")
+	ErrAPIKeyNotFound      = errors.New("API 密钥不存在")
+	ErrAPIKeyRevoked       = errors.New("API 密钥已被吊销")
+	ErrAPIKeyScopeInvalid  = errors.New("该权限不能授予 API 密钥")
+	ErrAPIKeyScopeTooBroad = errors.New("不能授予自己没有的权限")
+)
+
+// CreateAPIKey 创建 API 密钥，创建者只能授予自己已拥有的权限
+func CreateAPIKey(userType string, userID, principalID uint, req models.CreateAPIKeyRequest) (*models.CreatedAPIKeyDTO, error) {
+	allowed := make(map[string]bool, len(models.APIKeyScopes))
+	for _, s := range models.APIKeyScopes {
+		allowed[s] = true
+	}
+	granted, err := GetUserPermissions(userType, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	scopes := make([]string, 0, len(req.Scopes))
+	for _, s := range req.Scopes {
+		if seen[s] {
+			continue
+		}
+		if !allowed[s] {
+			return nil, fmt.Errorf("%w: %s", ErrAPIKeyScopeInvalid, s)
+		}
+		if !granted[s] {
+			return nil, fmt.Errorf("%w: %s", ErrAPIKeyScopeTooBroad, s)
+		}
+		seen[s] = true
+		scopes = append(scopes, s)
+	}
+	sort.Strings(scopes)
+
+	secret, err := utils.RandomToken(24)
+	if err != nil {
+		return nil, err
+	}
+	raw := apiKeyPrefix + secret
+	key := models.APIKey{
+		Name:      strings.TrimSpace(req.Name),
+		Prefix:    raw[:len(apiKeyPrefix)+8],
+		KeyHash:   utils.HashToken(raw),
+		Scopes:    strings.Join(scopes, ","),
+		CreatedBy: principalID,
+		ExpiresAt: time.Now().AddDate(0, 0, req.ExpiresInDays),
+	}
+	if err := dao.CreateAPIKey(&key); err != nil {
+		return nil, err
+	}
+	return &models.CreatedAPIKeyDTO{APIKeyDTO: toAPIKeyDTO(key, time.Now()), Key: raw}, nil
+}
+
+func ListAPIKeys() ([]models.APIKeyDTO, error) {
+	keys, err := dao.ListAPIKeys()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	list := make([]models.APIKeyDTO, 0, len(keys))
+	for _, k := range keys {
+		list = append(list, toAPIKeyDTO(k, now))
+	}
+	return list, nil
+}
+
+// RevokeAPIKey 吊销密钥，立即生效
+func RevokeAPIKey(id uint) error {
+	revoked, err := dao.RevokeAPIKey(id)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return nil
+	}
+	if _, err := dao.GetAPIKeyByID(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrAPIKeyNotFound
+		}
+		return err
+	}
+	return ErrAPIKeyRevoked
+}
+
+// AuthenticateAPIKey 校验请求携带的密钥并记录最近使用情况
+func AuthenticateAPIKey(raw, ip string) (*models.APIKey, error) {
+	if !strings.HasPrefix(raw, apiKeyPrefix) {
+		return nil, ErrAPIKeyInvalid
+	}
+	key, err := dao.GetAPIKeyByHash(utils.HashToken(raw))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAPIKeyInvalid
+		}
+		return nil, err
+	}
+	if key.Status(time.Now()) != models.APIKeyActive {
+		return nil, ErrAPIKeyInvalid
+	}
+
+	if err := dao.TouchAPIKey(key.ID, ip, apiKeyTouchInterval); err != nil {
+		log.Printf("记录 API 密钥 %d 使用时间失败: %v", key.ID, err)
+	}
+	return key, nil
+}
+
+func toAPIKeyDTO(key models.APIKey, now time.Time) models.APIKeyDTO {
+	return models.APIKeyDTO{APIKey: key, ScopeList: key.ScopeList(), Status: key.Status(now)}
+}
@@ -0,0 +1,218 @@
+// services/AccountService.go
+package services
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/dao"
+	"EmployeeManagementDemo/models"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"time"
+)
+
+// 账号层：用户名全局唯一，管理员/员工档案通过 account_id 关联，
+// 会话、踢人、聊天等统一使用账号ID（主体ID）标识用户
+
+var (
+	ErrUsernameTaken      = errors.New("用户名已存在")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// CreateAccount 在事务中创建账号，hashedPassword 为空表示暂不设置密码
+func CreateAccount(tx *gorm.DB, username, hashedPassword string) (*models.Account, error) {
+	taken, err := dao.UsernameTaken(tx, username, 0)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, ErrUsernameTaken
+	}
+	acc := &models.Account{Username: username, Password: hashedPassword}
+	if hashedPassword != "" {
+		now := time.Now()
+		acc.PasswordChangedAt = &now
+	}
+	if err := tx.Create(acc).Error; err != nil {
+		return nil, err
+	}
+	if err := recordPasswordHistory(tx, acc.ID, hashedPassword); err != nil {
+		return nil, err
+	}
+	return acc, nil
+}
+
+// RenameAccount 修改账号用户名（档案改名时同步调用）
+func RenameAccount(tx *gorm.DB, accountID uint, username string) error {
+	if accountID == 0 {
+		return nil
+	}
+	taken, err := dao.UsernameTaken(tx, username, accountID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrUsernameTaken
+	}
+	return tx.Model(&models.Account{}).Where("id = ?", accountID).Update("username", username).Error
+}
+
+// UsernameExists 用户名是否已被占用
+func UsernameExists(username string) (bool, error) {
+	return dao.UsernameTaken(config.DB, username, 0)
+}
+
+// SetAccountPassword 更新账号密码（已加密）并记录密码历史，不做策略校验
+func SetAccountPassword(tx *gorm.DB, accountID uint, hashedPassword string) error {
+	err := tx.Model(&models.Account{}).Where("id = ?", accountID).
+		Updates(map[string]interface{}{"password": hashedPassword, "password_changed_at": time.Now()}).Error
+	if err != nil {
+		return err
+	}
+	return recordPasswordHistory(tx, accountID, hashedPassword)
+}
+
+// GetAccountProfile 加载账号关联的档案；userType 为空时优先管理员档案
+func GetAccountProfile(accountID uint, userType string) (models.BaseUser, error) {
+	if userType == "" || userType == models.UserTypeAdmin {
+		admin, err := dao.GetAdminByAccountID(accountID)
+		if err == nil {
+			return admin, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+	if userType == "" || userType == models.UserTypeEmployee {
+		emp, err := dao.GetEmployeeByAccountID(accountID)
+		if err == nil {
+			return emp, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+	return nil, ErrInvalidCredentials
+}
+
+// legacyProfileTable 账号层上线前的档案表：用户名和密码保存在档案表中
+type legacyProfileTable struct {
+	model       interface{}
+	label       string // 日志中的档案类型
+	idCol       string
+	nameCol     string
+	passwordCol string // 迁移完成后删除
+	suffix      string // 重名时追加的后缀
+}
+
+// legacyProfile 尚未关联账号的档案
+type legacyProfile struct {
+	ID       uint
+	Username string
+	Password string
+}
+
+// MigrateAccounts 为尚未关联账号的管理员和员工创建账号，把密码迁移过去，并删除档案表中不再使用的密码列。
+// 管理员优先保留原用户名；与已有账号重名的员工改名为 <原名>_emp（必要时追加序号），每次改名都记录日志
+func MigrateAccounts() error {
+	renamed := 0
+	for _, t := range []legacyProfileTable{
+		{model: &models.Admin{}, label: "管理员", idCol: "admin_id", nameCol: "admin_name", passwordCol: "admin_password", suffix: "admin"},
+		{model: &models.Employee{}, label: "员工", idCol: "emp_id", nameCol: "username", passwordCol: "password", suffix: "emp"},
+	} {
+		n, err := migrateLegacyProfiles(t)
+		if err != nil {
+			return err
+		}
+		renamed += n
+	}
+	if renamed > 0 {
+		log.Printf("账号迁移: 共 %d 个用户名因重名被修改，相关用户需使用新用户名登录", renamed)
+	}
+
+	// 升级前已有密码的账号从现在开始计算密码有效期
+	return config.DB.Model(&models.Account{}).
+		Where("password_changed_at IS NULL AND password <> ''").
+		Update("password_changed_at", time.Now()).Error
+}
+
+// migrateLegacyProfiles 迁移一张档案表，返回改名的数量
+func migrateLegacyProfiles(t legacyProfileTable) (int, error) {
+	migrator := config.DB.Migrator()
+	hasPassword := migrator.HasColumn(t.model, t.passwordCol)
+	passwordExpr := "'' AS password"
+	if hasPassword {
+		passwordExpr = t.passwordCol + " AS password"
+	}
+
+	var profiles []legacyProfile
+	if err := config.DB.Model(t.model).
+		Select(t.idCol + " AS id, " + t.nameCol + " AS username, " + passwordExpr).
+		Where("account_id IS NULL").Scan(&profiles).Error; err != nil {
+		return 0, err
+	}
+
+	renamed := 0
+	for _, p := range profiles {
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			name, err := availableUsername(tx, p.Username, t.suffix)
+			if err != nil {
+				return err
+			}
+			acc := models.Account{Username: name, Password: p.Password}
+			if err := tx.Create(&acc).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(t.model).Where(t.idCol+" = ?", p.ID).
+				Updates(map[string]interface{}{"account_id": acc.ID, t.nameCol: name}).Error; err != nil {
+				return err
+			}
+			if name != p.Username {
+				renamed++
+				log.Printf("账号迁移: %s %d 用户名 %s 重名，已改为 %s", t.label, p.ID, p.Username, name)
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, fmt.Errorf("迁移%s %d 失败: %w", t.label, p.ID, err)
+		}
+	}
+
+	if hasPassword {
+		if err := migrator.DropColumn(t.model, t.passwordCol); err != nil {
+			return 0, fmt.Errorf("删除%s密码列失败: %w", t.label, err)
+		}
+		log.Printf("账号迁移: 已删除%s表中不再使用的 %s 列", t.label, t.passwordCol)
+	}
+	return renamed, nil
+}
+
+// availableUsername 返回未被占用的用户名，冲突时追加后缀，总长度不超过 20
+func availableUsername(tx *gorm.DB, base, suffix string) (string, error) {
+	for i := 0; ; i++ {
+		name := base
+		if i > 0 {
+			tag := "_" + suffix
+			if i > 1 {
+				tag += fmt.Sprint(i)
+			}
+			name = truncateRunes(base, 20-len(tag)) + tag
+		}
+		taken, err := dao.UsernameTaken(tx, name, 0)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return name, nil
+		}
+	}
+}
+
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
@@ -0,0 +1,184 @@
+// services/AdminInvitationService.go
+package services
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/dao"
+	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/utils"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvitationInvalid       = errors.New("邀请无效或已过期")
+	ErrInvitationNotFound      = errors.New("邀请不存在")
+	ErrInvitationNotPending    = errors.New("邀请已被使用或撤销")
+	ErrInvitationEmailMismatch = errors.New("注册邮箱与邀请邮箱不一致")
+	ErrRoleNotGrantable        = errors.New("不能邀请权限超出自己的角色")
+	ErrInviterNotAdmin         = errors.New("只有管理员可以发出邀请")
+)
+
+// AdminBootstrapAllowed 系统中还没有任何管理员时，允许凭初始化密钥注册第一个管理员
+func AdminBootstrapAllowed() (bool, error) {
+	count, err := dao.CountAdmins()
+	return count == 0, err
+}
+
+// invitingAdmin 按账号加载邀请人的管理员资料（员工、API 密钥不能发出邀请）
+func invitingAdmin(accountID uint, userType string) (*models.Admin, error) {
+	if userType != models.UserTypeAdmin || accountID == 0 {
+		return nil, ErrInviterNotAdmin
+	}
+	admin, err := dao.GetAdminByAccountID(accountID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInviterNotAdmin
+		}
+		return nil, err
+	}
+	return admin, nil
+}
+
+// CheckAdminBootstrapSecret 系统中还没有管理员时必须配置初始化密钥，否则无法创建第一个管理员
+func CheckAdminBootstrapSecret() error {
+	allowed, err := AdminBootstrapAllowed()
+	if err != nil {
+		return err
+	}
+	if allowed && config.Cfg.Admin.RegisterSecret == "" {
+		return errors.New("系统中还没有管理员，请通过 APP_ADMIN_REGISTER_SECRET 或 admin.register_secret_file 配置初始化密钥")
+	}
+	return nil
+}
+
+// CreateAdminInvitation 发出管理员邀请并发送邮件。inviterAccountID、inviterType 为当前登录账号及其身份，
+// 邀请人须为管理员，且只能授予自己已拥有的权限，防止借邀请提权
+func CreateAdminInvitation(inviterAccountID uint, inviterType, email string, roleID uint) (*models.AdminInvitation, error) {
+	inviter, err := invitingAdmin(inviterAccountID, inviterType)
+	if err != nil {
+		return nil, err
+	}
+	role, err := getRole(roleID)
+	if err != nil {
+		return nil, err
+	}
+	granted, err := GetUserPermissions(models.UserTypeAdmin, inviter.AdminID)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range role.Permissions {
+		if !granted[p.Code] {
+			return nil, ErrRoleNotGrantable
+		}
+	}
+
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	inv := &models.AdminInvitation{
+		Email:     strings.TrimSpace(email),
+		RoleID:    role.ID,
+		InvitedBy: inviter.AdminID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(config.Cfg.Admin.InvitationTTL),
+	}
+	if err := dao.CreateAdminInvitation(inv); err != nil {
+		return nil, err
+	}
+
+	if err := Mailer.Send(inv.Email, "管理员邀请", buildInvitationMail(role.Name, token)); err != nil {
+		// 邮件没发出去，邀请也就没有意义
+		_ = dao.DeleteAdminInvitation(inv.ID)
+		return nil, fmt.Errorf("邀请邮件发送失败: %w", err)
+	}
+	return inv, nil
+}
+
+func ListAdminInvitations() ([]models.AdminInvitationDTO, error) {
+	list, err := dao.ListAdminInvitations()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i := range list {
+		list[i].Status = list[i].AdminInvitation.Status(now)
+	}
+	return list, nil
+}
+
+// RevokeAdminInvitation 撤销尚未使用的邀请
+func RevokeAdminInvitation(id uint) error {
+	revoked, err := dao.RevokeAdminInvitation(id)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return nil
+	}
+	if _, err := dao.GetAdminInvitationByID(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvitationNotFound
+		}
+		return err
+	}
+	return ErrInvitationNotPending
+}
+
+// RedeemAdminInvitation 凭邀请令牌注册管理员，并授予邀请中指定的角色
+func RedeemAdminInvitation(token string, req models.RegisterRequest) (*models.Admin, error) {
+	var inv models.AdminInvitation
+	if err := config.DB.Where("token_hash = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?",
+		utils.HashToken(token), time.Now()).First(&inv).Error; err != nil {
+		return nil, ErrInvitationInvalid
+	}
+	if !strings.EqualFold(strings.TrimSpace(req.Email), inv.Email) {
+		return nil, ErrInvitationEmailMismatch
+	}
+	hashed, err := HashPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+	admin := models.Admin{
+		AdminName:  req.Username,
+		AdminEmail: inv.Email,
+		AdminPhone: req.Phone,
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := createAdminWithTx(tx, &admin, hashed); err != nil {
+			return err
+		}
+		// 条件更新保证邀请只能被使用一次
+		now := time.Now()
+		result := tx.Model(&models.AdminInvitation{}).
+			Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", inv.ID, now).
+			Updates(map[string]interface{}{"accepted_at": now, "accepted_admin_id": admin.AdminID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvitationInvalid
+		}
+		ur := models.UserRole{UserType: models.UserTypeAdmin, UserID: admin.AdminID, RoleID: inv.RoleID}
+		return tx.Create(&ur).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	LogOperation(admin.GetAccountID(), models.ActorUser, "redeem_admin_invitation", strconv.FormatUint(uint64(inv.ID), 10))
+	return &admin, nil
+}
+
+func buildInvitationMail(roleName, token string) string {
+	link := config.Cfg.Admin.InvitationURL + "?token=" + url.QueryEscape(token)
+	return fmt.Sprintf("您好：\n\n您被邀请成为员工管理系统的管理员（角色：%s），请在 %d 小时内点击以下链接完成注册：\n\n%s\n\n该链接只能使用一次。如果您不认识发出邀请的人，请忽略本邮件。\n",
+		roleName, int(config.Cfg.Admin.InvitationTTL.Hours()), link)
+}
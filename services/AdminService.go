@@ -1,23 +1,33 @@
 package services
 
 import (
-	"EmployeeManagementDemo/dao"
+	"EmployeeManagementDemo/config"
 	"EmployeeManagementDemo/models"
+	"crypto/subtle"
+	"gorm.io/gorm"
 )
 
-// ValidateAdminSecret 验证管理员密钥
+// ValidateAdminSecret 验证管理员密钥，未配置密钥时一律拒绝
 func ValidateAdminSecret(secret string) bool {
-	// 从配置读取密钥（示例值，实际应从安全配置加载）
-	const validSecret = "tangfire"
-	return secret == validSecret
+	validSecret := config.Cfg.Admin.RegisterSecret
+	if validSecret == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(secret), []byte(validSecret)) == 1
 }
 
-// CheckAdminNameExists 检查管理员用户名是否存在
-func CheckAdminNameExists(username string) (bool, error) {
-	return dao.CheckAdminNameExists(username)
+// CreateAdmin 创建管理员及其登录账号（用户名全局唯一，重名返回 ErrUsernameTaken）
+func CreateAdmin(admin *models.Admin, hashedPassword string) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		return createAdminWithTx(tx, admin, hashedPassword)
+	})
 }
 
-// CreateAdmin 创建管理员
-func CreateAdmin(admin *models.Admin) error {
-	return dao.CreateAdmin(admin)
+func createAdminWithTx(tx *gorm.DB, admin *models.Admin, hashedPassword string) error {
+	acc, err := CreateAccount(tx, admin.AdminName, hashedPassword)
+	if err != nil {
+		return err
+	}
+	admin.AccountID = &acc.ID
+	return tx.Create(admin).Error
 }
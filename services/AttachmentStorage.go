@@ -0,0 +1,128 @@
+package services
+
+import (
+	"EmployeeManagementDemo/config"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// AttachmentStorage 附件存储后端，按配置选择实现。key 由系统生成，不含用户输入
+type AttachmentStorage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Attachments 全局附件存储，由 InitAttachmentStorage 初始化
+var Attachments AttachmentStorage
+
+var ErrAttachmentObjectMissing = errors.New("附件文件不存在")
+
+// InitAttachmentStorage 根据 chat.attachments.driver 初始化附件存储
+func InitAttachmentStorage() error {
+	cfg := config.Cfg.Chat.Attachments
+	switch cfg.Driver {
+	case "s3":
+		client, err := minio.New(cfg.S3.Endpoint, &minio.Options{
+			Creds:  credentials.NewStaticV4(cfg.S3.AccessKey, cfg.S3.SecretKey, ""),
+			Secure: cfg.S3.UseSSL,
+			Region: cfg.S3.Region,
+		})
+		if err != nil {
+			return fmt.Errorf("S3 客户端初始化失败: %w", err)
+		}
+		Attachments = &S3AttachmentStorage{Client: client, Bucket: cfg.S3.Bucket}
+	case "local", "":
+		if err := os.MkdirAll(cfg.LocalDir, 0o750); err != nil {
+			return fmt.Errorf("附件目录创建失败: %w", err)
+		}
+		Attachments = &LocalAttachmentStorage{Dir: cfg.LocalDir}
+	default:
+		return fmt.Errorf("未知的附件存储: %s", cfg.Driver)
+	}
+	return nil
+}
+
+// LocalAttachmentStorage 保存在本地目录（单实例或共享盘部署）
+type LocalAttachmentStorage struct {
+	Dir string
+}
+
+func (s *LocalAttachmentStorage) path(key string) string {
+	return filepath.Join(s.Dir, filepath.FromSlash(key))
+}
+
+func (s *LocalAttachmentStorage) Put(_ context.Context, key string, r io.Reader, _ int64, _ string) error {
+	path := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	// 先写临时文件再改名，避免并发下载读到写了一半的文件
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *LocalAttachmentStorage) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	f, err := os.Open(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrAttachmentObjectMissing
+	}
+	return f, err
+}
+
+func (s *LocalAttachmentStorage) Delete(_ context.Context, key string) error {
+	err := os.Remove(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// S3AttachmentStorage 保存在兼容 S3 的对象存储，多实例部署时使用
+type S3AttachmentStorage struct {
+	Client *minio.Client
+	Bucket string
+}
+
+func (s *S3AttachmentStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	_, err := s.Client.PutObject(ctx, s.Bucket, key, r, size, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+func (s *S3AttachmentStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	obj, err := s.Client.GetObject(ctx, s.Bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	// GetObject 不会立即请求，Stat 一次以便把不存在的对象区分出来
+	if _, err := obj.Stat(); err != nil {
+		obj.Close()
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, ErrAttachmentObjectMissing
+		}
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (s *S3AttachmentStorage) Delete(ctx context.Context, key string) error {
+	return s.Client.RemoveObject(ctx, s.Bucket, key, minio.RemoveObjectOptions{})
+}
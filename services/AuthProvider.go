@@ -0,0 +1,74 @@
+// services/AuthProvider.go
+package services
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/dao"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// AuthProvider 用户名密码认证方式，按 auth.providers 配置的顺序依次尝试
+type AuthProvider interface {
+	Name() string
+	// Authenticate 校验用户名密码，成功返回本地账号ID；用户名或密码错误时返回 ErrInvalidCredentials
+	Authenticate(username, password string) (uint, error)
+}
+
+var authProviders []AuthProvider
+
+// InitAuthProviders 按配置初始化认证方式，未知的认证方式直接报错
+func InitAuthProviders() error {
+	providers := make([]AuthProvider, 0, len(config.Cfg.Auth.Providers))
+	for _, name := range config.Cfg.Auth.Providers {
+		switch name {
+		case "local":
+			providers = append(providers, localAuthProvider{})
+		case "ldap":
+			p, err := newLDAPAuthProvider(config.Cfg.LDAP)
+			if err != nil {
+				return err
+			}
+			providers = append(providers, p)
+		default:
+			return fmt.Errorf("未知的认证方式: %s", name)
+		}
+	}
+	if len(providers) == 0 {
+		return errors.New("至少需要配置一种认证方式")
+	}
+	authProviders = providers
+	return nil
+}
+
+// localAuthProvider 本地账号密码（bcrypt）
+type localAuthProvider struct{}
+
+func (localAuthProvider) Name() string { return "local" }
+
+func (localAuthProvider) Authenticate(username, password string) (uint, error) {
+	acc, err := dao.GetAccountByUsername(username)
+	if err != nil || !acc.CheckPassword(password) {
+		return 0, ErrInvalidCredentials
+	}
+	return acc.ID, nil
+}
+
+// authenticateAccount 依次尝试各认证方式，第一个成功的为准。
+// 某个认证方式不可用（如目录服务连接失败）时记录日志并继续尝试下一个
+func authenticateAccount(username, password string) (uint, error) {
+	if password == "" {
+		return 0, ErrInvalidCredentials
+	}
+	for _, p := range authProviders {
+		accountID, err := p.Authenticate(username, password)
+		if err == nil {
+			return accountID, nil
+		}
+		if !errors.Is(err, ErrInvalidCredentials) {
+			log.Printf("认证方式 %s 出错: %v", p.Name(), err)
+		}
+	}
+	return 0, ErrInvalidCredentials
+}
@@ -0,0 +1,355 @@
+package services
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/dao"
+	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/utils"
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // 注册 webp 解码
+	"gorm.io/gorm"
+	"image"
+	_ "image/gif" // 注册 gif 解码
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	maxThumbnailPixels        = 40_000_000 // 超过该像素数的图片不生成缩略图，防止解码耗尽内存
+	attachmentCleanupInterval = time.Hour  // 未发送附件的清理间隔
+	attachmentCleanupBatch    = 100
+)
+
+// officeTypes Office 文档的扩展名对应的类型：新格式是包含 [Content_Types].xml 和 part 目录的 zip 包，
+// 旧格式（part 为空）是 OLE 复合文档
+var officeTypes = map[string]struct{ mimeType, part string }{
+	".docx": {"application/vnd.openxmlformats-officedocument.wordprocessingml.document", "word/"},
+	".xlsx": {"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "xl/"},
+	".pptx": {"application/vnd.openxmlformats-officedocument.presentationml.presentation", "ppt/"},
+	".doc":  {"application/msword", ""},
+	".xls":  {"application/vnd.ms-excel", ""},
+	".ppt":  {"application/vnd.ms-powerpoint", ""},
+}
+
+// oleMagic OLE 复合文档的文件头
+var oleMagic = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+var (
+	ErrAttachmentEmpty    = errors.New("文件为空")
+	ErrAttachmentTooLarge = errors.New("文件超过大小限制")
+	ErrAttachmentType     = errors.New("不支持的文件类型")
+	ErrAttachmentNotFound = errors.New("附件不存在")
+)
+
+// UploadChatAttachment 保存上传的附件：按文件内容识别类型并校验大小和类型，图片另存一份缩略图
+func UploadChatAttachment(ctx context.Context, uploaderID int, fileName string, size int64, file io.ReadSeeker) (*models.ChatAttachment, error) {
+	cfg := config.Cfg.Chat.Attachments
+	if size <= 0 {
+		return nil, ErrAttachmentEmpty
+	}
+	if size > cfg.MaxSize {
+		return nil, ErrAttachmentTooLarge
+	}
+
+	mimeType, err := detectMIMEType(file, size, fileName)
+	if err != nil {
+		return nil, err
+	}
+	if !mimeAllowed(mimeType, cfg.AllowedTypes) {
+		return nil, ErrAttachmentType
+	}
+
+	suffix, err := utils.RandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	att := &models.ChatAttachment{
+		UploaderID: uploaderID,
+		FileName:   filepath.Base(fileName),
+		MIMEType:   mimeType,
+		Size:       size,
+		StorageKey: time.Now().Format("2006/01/02/") + suffix,
+	}
+	if err := Attachments.Put(ctx, att.StorageKey, io.LimitReader(file, size), size, mimeType); err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(mimeType, "image/") {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		storeThumbnail(ctx, att, file, cfg.ThumbnailSize)
+	}
+
+	if err := dao.CreateChatAttachment(att); err != nil {
+		deleteAttachmentObjects(ctx, att)
+		return nil, err
+	}
+	return att, nil
+}
+
+// PurgeUnsentChatAttachments 删除 before 之前上传、一直没有消息引用的附件（记录和文件），返回删除的个数
+func PurgeUnsentChatAttachments(ctx context.Context, before time.Time) (int, error) {
+	purged := 0
+	for {
+		atts, err := dao.ListUnsentChatAttachments(before, attachmentCleanupBatch)
+		if err != nil {
+			return purged, err
+		}
+		for i := range atts {
+			// 查询之后可能刚被发送，删除时再确认一次没有引用
+			deleted, err := dao.DeleteUnsentChatAttachment(atts[i].ID)
+			if err != nil {
+				return purged, err
+			}
+			if deleted {
+				deleteAttachmentObjects(ctx, &atts[i])
+				purged++
+			}
+		}
+		if len(atts) < attachmentCleanupBatch {
+			return purged, nil
+		}
+	}
+}
+
+// StartAttachmentCleanup 定期清理上传后超过 chat.attachments.unsent_ttl 仍未发送的附件，ctx 取消时退出。
+// 删除前确认没有引用，多个实例同时清理也不会误删
+func StartAttachmentCleanup(ctx context.Context) {
+	ttl := config.Cfg.Chat.Attachments.UnsentTTL
+	if ttl <= 0 {
+		return
+	}
+	ticker := time.NewTicker(attachmentCleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := PurgeUnsentChatAttachments(ctx, time.Now().Add(-ttl))
+			if err != nil {
+				log.Printf("未发送附件清理失败: %v", err)
+			}
+			if n > 0 {
+				log.Printf("已清理 %d 个未发送的附件", n)
+			}
+		}
+	}
+}
+
+// deleteAttachmentObjects 删除附件文件和缩略图
+func deleteAttachmentObjects(ctx context.Context, att *models.ChatAttachment) {
+	if err := Attachments.Delete(ctx, att.StorageKey); err != nil {
+		log.Printf("附件文件删除失败: %s, %v", att.StorageKey, err)
+	}
+	if att.ThumbnailKey != "" {
+		if err := Attachments.Delete(ctx, att.ThumbnailKey); err != nil {
+			log.Printf("附件文件删除失败: %s, %v", att.ThumbnailKey, err)
+		}
+	}
+}
+
+// OpenChatAttachment 打开附件（或其缩略图）供下载：上传者，或能看到引用该附件的消息的用户才可下载
+func OpenChatAttachment(ctx context.Context, userID int, id uint, thumbnail bool) (*models.ChatAttachment, io.ReadCloser, error) {
+	att, err := dao.GetChatAttachmentByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrAttachmentNotFound
+		}
+		return nil, nil, err
+	}
+	if att.UploaderID != userID {
+		visible, err := dao.ChatAttachmentVisibleTo(userID, id)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !visible {
+			return nil, nil, ErrAttachmentNotFound // 不区分无权和不存在
+		}
+	}
+
+	key := att.StorageKey
+	if thumbnail {
+		if att.ThumbnailKey == "" {
+			return nil, nil, ErrAttachmentNotFound
+		}
+		key = att.ThumbnailKey
+	}
+	r, err := Attachments.Get(ctx, key)
+	if errors.Is(err, ErrAttachmentObjectMissing) {
+		return nil, nil, ErrAttachmentNotFound
+	}
+	return att, r, err
+}
+
+// GetOwnChatAttachment 发送附件消息前校验：只能发送自己上传的附件
+func GetOwnChatAttachment(userID int, id uint) (*models.ChatAttachment, error) {
+	att, err := dao.GetChatAttachmentByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAttachmentNotFound
+		}
+		return nil, err
+	}
+	if att.UploaderID != userID {
+		return nil, ErrAttachmentNotFound
+	}
+	return att, nil
+}
+
+// ChatMessageAttachments 查询消息引用的附件，按附件ID索引
+func ChatMessageAttachments(msgs []models.ChatMessage) (map[uint]*models.ChatAttachment, error) {
+	var ids []uint
+	for _, m := range msgs {
+		if m.AttachmentID != nil {
+			ids = append(ids, *m.AttachmentID)
+		}
+	}
+	atts, err := dao.GetChatAttachmentsByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*models.ChatAttachment, len(atts))
+	for i := range atts {
+		byID[atts[i].ID] = &atts[i]
+	}
+	return byID, nil
+}
+
+// fillChatAttachments 为附件消息补上附件信息
+func fillChatAttachments(msgs []models.ChatMessage, dtos []models.ChatMessageDTO) error {
+	byID, err := ChatMessageAttachments(msgs)
+	if err != nil {
+		return err
+	}
+	for i, m := range msgs {
+		if m.AttachmentID != nil && byID[*m.AttachmentID] != nil {
+			dtos[i].Attachment = byID[*m.AttachmentID].ToDTO()
+		}
+	}
+	return nil
+}
+
+// detectMIMEType 按文件内容识别类型（图片、PDF、zip、纯文本看文件头）。扩展名只用来区分 Office 文档，
+// 且须内容相符：新格式须是含对应目录的 zip 包，旧格式须是 OLE 复合文档，否则按内容识别的结果处理
+func detectMIMEType(file io.ReadSeeker, size int64, fileName string) (string, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	head = head[:n]
+
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	office, isOffice := officeTypes[strings.ToLower(filepath.Ext(fileName))]
+	if !isOffice {
+		return sniffed, nil
+	}
+	switch {
+	case office.part == "" && bytes.HasPrefix(head, oleMagic):
+		return office.mimeType, nil
+	case office.part != "" && sniffed == "application/zip":
+		ok, err := isOfficePackage(file, size, office.part)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return office.mimeType, nil
+		}
+	}
+	return sniffed, nil
+}
+
+// isOfficePackage zip 包中是否有 [Content_Types].xml 和 part 目录下的文件
+func isOfficePackage(file io.ReadSeeker, size int64, part string) (bool, error) {
+	ra, ok := file.(io.ReaderAt)
+	if !ok {
+		return false, nil
+	}
+	zr, err := zip.NewReader(ra, size)
+	if err != nil {
+		return false, nil // 不是完整的 zip 包
+	}
+	hasTypes, hasPart := false, false
+	for _, f := range zr.File {
+		switch {
+		case f.Name == "[Content_Types].xml":
+			hasTypes = true
+		case strings.HasPrefix(f.Name, part):
+			hasPart = true
+		}
+	}
+	return hasTypes && hasPart, nil
+}
+
+func mimeAllowed(mimeType string, allowed []string) bool {
+	for _, pattern := range allowed {
+		if ok, _ := path.Match(pattern, mimeType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// storeThumbnail 生成并保存缩略图，失败时只是没有缩略图，不影响上传
+func storeThumbnail(ctx context.Context, att *models.ChatAttachment, file io.ReadSeeker, maxSide int) {
+	cfgImg, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return
+	}
+	att.Width, att.Height = cfgImg.Width, cfgImg.Height
+	if maxSide <= 0 || cfgImg.Width*cfgImg.Height > maxThumbnailPixels {
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return
+	}
+	src, _, err := image.Decode(file)
+	if err != nil {
+		return
+	}
+
+	w, h := cfgImg.Width, cfgImg.Height
+	if w > maxSide || h > maxSide {
+		if w >= h {
+			w, h = maxSide, h*maxSide/w
+		} else {
+			w, h = w*maxSide/h, maxSide
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, max(w, 1), max(h, 1)))
+	draw.ApproxBiLinear.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Over, nil)
+
+	var buf bytes.Buffer
+	contentType := att.ThumbnailType()
+	if contentType == "image/png" {
+		err = png.Encode(&buf, dst)
+	} else {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80})
+	}
+	if err != nil {
+		return
+	}
+
+	key := att.StorageKey + "_thumb"
+	if err := Attachments.Put(ctx, key, &buf, int64(buf.Len()), contentType); err != nil {
+		return
+	}
+	att.ThumbnailKey = key
+}
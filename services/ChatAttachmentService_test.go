@@ -0,0 +1,224 @@
+package services
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/testutil"
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"testing"
+	"time"
+)
+
+func setupAttachments(t *testing.T) {
+	testutil.Setup(t)
+	config.Cfg.Chat.Attachments = config.AttachmentConfig{
+		MaxSize: 1 << 20,
+		AllowedTypes: []string{
+			"image/*", "text/plain", "application/pdf", "application/zip",
+			"application/msword", "application/vnd.openxmlformats-officedocument.*",
+		},
+		ThumbnailSize: 320,
+	}
+	old := Attachments
+	Attachments = &LocalAttachmentStorage{Dir: t.TempDir()}
+	t.Cleanup(func() { Attachments = old })
+}
+
+func upload(t *testing.T, uploaderID int, name string, data []byte) (*models.ChatAttachment, error) {
+	t.Helper()
+	return UploadChatAttachment(context.Background(), uploaderID, name, int64(len(data)), bytes.NewReader(data))
+}
+
+func pngBytes(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func zipBytes(t *testing.T, names ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		if _, err := zw.Create(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestUploadChatAttachmentChecksSize(t *testing.T) {
+	setupAttachments(t)
+	if _, err := upload(t, 1, "empty.txt", nil); !errors.Is(err, ErrAttachmentEmpty) {
+		t.Fatalf("空文件应返回 ErrAttachmentEmpty，得到 %v", err)
+	}
+	if _, err := upload(t, 1, "big.txt", bytes.Repeat([]byte("a"), 1<<20+1)); !errors.Is(err, ErrAttachmentTooLarge) {
+		t.Fatalf("超过大小限制应返回 ErrAttachmentTooLarge，得到 %v", err)
+	}
+}
+
+func TestUploadChatAttachmentDetectsTypeFromContent(t *testing.T) {
+	setupAttachments(t)
+	ole := append([]byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}, make([]byte, 504)...)
+	cases := []struct {
+		name     string
+		data     []byte
+		mimeType string // 为空表示应拒绝
+	}{
+		{"photo.pdf", pngBytes(t, 4, 4), "image/png"},
+		{"notes.txt", []byte("hello"), "text/plain"},
+		{"page.pdf", []byte("<html><script>alert(1)</script></html>"), ""},
+		{"report.docx", zipBytes(t, "[Content_Types].xml", "word/document.xml"), "application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+		{"report.docx", []byte("not a document"), "text/plain"},
+		{"sheet.xlsx", zipBytes(t, "[Content_Types].xml", "word/document.xml"), "application/zip"},
+		{"legacy.doc", ole, "application/msword"},
+		{"legacy.doc", bytes.Repeat([]byte{0x00, 0xFF}, 256), ""},
+	}
+	for _, tc := range cases {
+		att, err := upload(t, 1, tc.name, tc.data)
+		if tc.mimeType == "" {
+			if !errors.Is(err, ErrAttachmentType) {
+				t.Errorf("%s 应被拒绝，得到 %v", tc.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s 上传失败: %v", tc.name, err)
+			continue
+		}
+		if att.MIMEType != tc.mimeType {
+			t.Errorf("%s 识别为 %s，期望 %s", tc.name, att.MIMEType, tc.mimeType)
+		}
+	}
+}
+
+func TestUploadChatAttachmentStoresThumbnail(t *testing.T) {
+	setupAttachments(t)
+	att, err := upload(t, 1, "wide.png", pngBytes(t, 800, 400))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if att.Width != 800 || att.Height != 400 || att.ThumbnailKey == "" {
+		t.Fatalf("图片应记录宽高并生成缩略图，得到 %+v", att)
+	}
+
+	_, r, err := OpenChatAttachment(context.Background(), 1, att.ID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	thumb, _, err := image.DecodeConfig(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if thumb.Width != 320 || thumb.Height != 160 {
+		t.Fatalf("缩略图为 %dx%d，期望 320x160", thumb.Width, thumb.Height)
+	}
+
+	doc, err := upload(t, 1, "notes.txt", []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := OpenChatAttachment(context.Background(), 1, doc.ID, true); !errors.Is(err, ErrAttachmentNotFound) {
+		t.Fatalf("非图片没有缩略图，得到 %v", err)
+	}
+}
+
+func TestOpenChatAttachmentOnlyForParticipants(t *testing.T) {
+	setupAttachments(t)
+	ctx := context.Background()
+	att, err := upload(t, 1, "notes.txt", []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	canOpen := func(userID int) bool {
+		_, r, err := OpenChatAttachment(ctx, userID, att.ID, false)
+		if err == nil {
+			data, _ := io.ReadAll(r)
+			r.Close()
+			return string(data) == "hello"
+		}
+		if !errors.Is(err, ErrAttachmentNotFound) {
+			t.Fatal(err)
+		}
+		return false
+	}
+
+	if !canOpen(1) || canOpen(2) {
+		t.Fatal("发送前只有上传者可以下载")
+	}
+	msg := models.ChatMessage{
+		ConversationID: models.DirectConversationID(1, 2), SendID: 1, RecipientID: 2,
+		MsgKey: "k", Status: models.MsgStatusSent, AttachmentID: &att.ID,
+	}
+	if err := config.DB.Create(&msg).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !canOpen(2) {
+		t.Fatal("接收方应可以下载")
+	}
+	if canOpen(3) {
+		t.Fatal("会话之外的用户不能下载")
+	}
+	// 接收方删除消息后不再能下载
+	config.DB.Model(&msg).Update("deleted_by_recipient", true)
+	if canOpen(2) {
+		t.Fatal("删除消息后不应再能下载")
+	}
+}
+
+func TestPurgeUnsentChatAttachments(t *testing.T) {
+	setupAttachments(t)
+	ctx := context.Background()
+	unsent, err := upload(t, 1, "old.png", pngBytes(t, 8, 8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent, err := upload(t, 1, "sent.txt", []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.DB.Create(&models.ChatMessage{
+		ConversationID: models.DirectConversationID(1, 2), SendID: 1, RecipientID: 2,
+		MsgKey: "k", Status: models.MsgStatusSent, AttachmentID: &sent.ID,
+	})
+	recent, err := upload(t, 1, "recent.txt", []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.DB.Model(&models.ChatAttachment{}).Where("id IN ?", []uint{unsent.ID, sent.ID}).
+		Update("created_at", time.Now().Add(-48*time.Hour))
+
+	n, err := PurgeUnsentChatAttachments(ctx, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("应清理 1 个附件，清理了 %d 个", n)
+	}
+	if _, _, err := OpenChatAttachment(ctx, 1, unsent.ID, false); !errors.Is(err, ErrAttachmentNotFound) {
+		t.Fatalf("过期未发送的附件应被删除，得到 %v", err)
+	}
+	for _, key := range []string{unsent.StorageKey, unsent.ThumbnailKey} {
+		if _, err := Attachments.Get(ctx, key); !errors.Is(err, ErrAttachmentObjectMissing) {
+			t.Fatalf("附件文件 %s 应被删除，得到 %v", key, err)
+		}
+	}
+	for _, att := range []*models.ChatAttachment{sent, recent} {
+		if _, _, err := OpenChatAttachment(ctx, 1, att.ID, false); err != nil {
+			t.Fatalf("已发送或未过期的附件 %s 不应被删除: %v", att.FileName, err)
+		}
+	}
+}
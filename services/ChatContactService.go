@@ -0,0 +1,430 @@
+package services
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/dao"
+	"EmployeeManagementDemo/models"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// 聊天联系人：好友申请通过后双方互为联系人；同部门同事无需申请即视为联系人。
+// 屏蔽对方后对方不能再给自己发消息或发好友申请
+
+var (
+	ErrContactSelf            = errors.New("不能添加自己为联系人")
+	ErrContactExists          = errors.New("已经是联系人")
+	ErrContactNotFound        = errors.New("联系人不存在")
+	ErrContactRequestNotFound = errors.New("好友申请不存在")
+	ErrContactBlocked         = errors.New("对方拒绝接收")
+	ErrContactBlockedByMe     = errors.New("已屏蔽对方，请先取消屏蔽")
+	ErrNotBlocked             = errors.New("未屏蔽该用户")
+	ErrNonContactLimit        = errors.New("对方不是你的联系人，对方回复前不能继续发送")
+	ErrChatRecipientInvalid   = errors.New("接收方不存在")
+	ErrChatSelf               = errors.New("不能给自己发消息")
+)
+
+// 非联系人消息名额：会话中发送方已用的条数，键不存在时按已落库的条数初始化。
+// 发送前原子地占用一个名额，并发发送也不会超过上限
+const (
+	nonContactQuotaPrefix = "chat_non_contact:"
+	nonContactQuotaTTL    = 7 * 24 * time.Hour
+)
+
+// 占用一个名额，返回 1 成功，0 已达上限
+var reserveNonContactScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	redis.call('SET', KEYS[1], ARGV[1], 'EX', ARGV[3])
+end
+if redis.call('INCR', KEYS[1]) > tonumber(ARGV[2]) then
+	redis.call('DECR', KEYS[1])
+	return 0
+end
+return 1
+`)
+
+func nonContactQuotaKey(conversationID string, senderID int) string {
+	return fmt.Sprintf("%s%s:%d", nonContactQuotaPrefix, conversationID, senderID)
+}
+
+// AddContact 发出好友申请；对方已向自己发出申请时直接互为联系人，返回 true
+func AddContact(userID, targetID int, message string) (bool, error) {
+	if userID == targetID {
+		return false, ErrContactSelf
+	}
+	accepted := false
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := checkAccountsExist(tx, []int{targetID}); err != nil {
+			return err
+		}
+		mine, theirs, err := lockContactPair(tx, userID, targetID)
+		if err != nil {
+			return err
+		}
+		switch {
+		case theirs != nil && theirs.Status == models.ContactBlocked:
+			return ErrContactBlocked
+		case mine != nil && mine.Status == models.ContactBlocked:
+			return ErrContactBlockedByMe
+		case mine != nil && mine.Status == models.ContactAccepted:
+			return ErrContactExists
+		case theirs != nil && theirs.Status == models.ContactPending:
+			accepted = true
+			return acceptContact(tx, targetID, userID)
+		}
+		return saveContact(tx, userID, targetID, models.ContactPending, message)
+	})
+	return accepted, err
+}
+
+// AcceptContact 通过对方的好友申请
+func AcceptContact(userID, requesterID int) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		_, theirs, err := lockContactPair(tx, userID, requesterID)
+		if err != nil {
+			return err
+		}
+		if theirs == nil || theirs.Status != models.ContactPending {
+			return ErrContactRequestNotFound
+		}
+		return acceptContact(tx, requesterID, userID)
+	})
+}
+
+// DeleteContactRequest 拒绝对方的申请或撤回自己发出的申请
+func DeleteContactRequest(userID, otherID int) error {
+	result := config.DB.Where("status = ? AND ((user_id = ? AND contact_id = ?) OR (user_id = ? AND contact_id = ?))",
+		models.ContactPending, otherID, userID, userID, otherID).Delete(&models.ChatContact{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrContactRequestNotFound
+	}
+	return nil
+}
+
+// RemoveContact 删除联系人，双方同时解除（同部门同事仍视为联系人）
+func RemoveContact(userID, contactID int) error {
+	result := config.DB.Where("status = ? AND ((user_id = ? AND contact_id = ?) OR (user_id = ? AND contact_id = ?))",
+		models.ContactAccepted, userID, contactID, contactID, userID).Delete(&models.ChatContact{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrContactNotFound
+	}
+	return nil
+}
+
+// BlockUser 屏蔽对方：解除联系人关系、清除对方的申请
+func BlockUser(userID, targetID int) error {
+	if userID == targetID {
+		return ErrContactSelf
+	}
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := checkAccountsExist(tx, []int{targetID}); err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ? AND contact_id = ? AND status <> ?", targetID, userID, models.ContactBlocked).
+			Delete(&models.ChatContact{}).Error; err != nil {
+			return err
+		}
+		return saveContact(tx, userID, targetID, models.ContactBlocked, "")
+	})
+}
+
+// UnblockUser 取消屏蔽，不恢复之前的联系人关系
+func UnblockUser(userID, targetID int) error {
+	result := config.DB.Where("user_id = ? AND contact_id = ? AND status = ?", userID, targetID, models.ContactBlocked).
+		Delete(&models.ChatContact{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotBlocked
+	}
+	return nil
+}
+
+// ListContacts 联系人列表：好友申请添加的在前，其后是未单独添加的同部门同事（不含已屏蔽的）
+func ListContacts(userID int) ([]models.ContactDTO, error) {
+	result, err := contactList(userID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, 0, len(result))
+	for _, ct := range result {
+		ids = append(ids, ct.UserID)
+	}
+	usernames, err := dao.GetUsernames(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range result {
+		result[i].Username = usernames[result[i].UserID]
+	}
+	return result, nil
+}
+
+func contactList(userID int) ([]models.ContactDTO, error) {
+	contacts, err := dao.ListChatContacts(userID, models.ContactAccepted)
+	if err != nil {
+		return nil, err
+	}
+	colleagues, err := dao.ListDepartmentColleagues(userID)
+	if err != nil {
+		return nil, err
+	}
+	blocked, err := blockedSet(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	listed := make(map[int]bool, len(contacts))
+	result := make([]models.ContactDTO, 0, len(contacts)+len(colleagues))
+	for _, ct := range contacts {
+		since := ct.UpdatedAt
+		listed[ct.ContactID] = true
+		result = append(result, models.ContactDTO{UserID: ct.ContactID, Source: models.ContactSourceContact, Since: &since})
+	}
+	for _, id := range colleagues {
+		if listed[id] || blocked[id] {
+			continue
+		}
+		listed[id] = true
+		result = append(result, models.ContactDTO{UserID: id, Source: models.ContactSourceDepartment})
+	}
+	return result, nil
+}
+
+// ListContactRequests 收到的（outgoing 为 true 时为发出的）待处理好友申请
+func ListContactRequests(userID int, outgoing bool) ([]models.ContactRequestDTO, error) {
+	var rows []models.ChatContact
+	var err error
+	if outgoing {
+		rows, err = dao.ListChatContacts(userID, models.ContactPending)
+	} else {
+		rows, err = dao.ListIncomingContactRequests(userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(rows))
+	for _, r := range rows {
+		ids = append(ids, contactPeer(r, userID))
+	}
+	usernames, err := dao.GetUsernames(ids)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]models.ContactRequestDTO, 0, len(rows))
+	for _, r := range rows {
+		peer := contactPeer(r, userID)
+		result = append(result, models.ContactRequestDTO{
+			UserID:    peer,
+			Username:  usernames[peer],
+			Message:   r.Message,
+			CreatedAt: r.CreatedAt,
+		})
+	}
+	return result, nil
+}
+
+// ListBlockedUsers 屏蔽的用户
+func ListBlockedUsers(userID int) ([]models.ContactDTO, error) {
+	rows, err := dao.ListChatContacts(userID, models.ContactBlocked)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, 0, len(rows))
+	for _, r := range rows {
+		ids = append(ids, r.ContactID)
+	}
+	usernames, err := dao.GetUsernames(ids)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]models.ContactDTO, 0, len(rows))
+	for _, r := range rows {
+		since := r.UpdatedAt
+		result = append(result, models.ContactDTO{UserID: r.ContactID, Username: usernames[r.ContactID], Since: &since})
+	}
+	return result, nil
+}
+
+// ContactIDs 全部联系人的账号ID（含同部门同事，不含已屏蔽的）
+func ContactIDs(userID int) ([]int, error) {
+	contacts, err := contactList(userID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, 0, len(contacts))
+	for _, ct := range contacts {
+		ids = append(ids, ct.UserID)
+	}
+	return ids, nil
+}
+
+// BlockedBy ids 中屏蔽了 userID 的账号
+func BlockedBy(userID int, ids []int) (map[int]bool, error) {
+	blockers, err := dao.ListBlockers(userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[int]bool, len(blockers))
+	for _, id := range blockers {
+		set[id] = true
+	}
+	return set, nil
+}
+
+// IsContact 是否互为联系人（好友申请已通过或同部门）
+func IsContact(a, b int) (bool, error) {
+	ok, err := dao.HasChatContactStatus(a, b, models.ContactAccepted)
+	if err != nil || ok {
+		return ok, err
+	}
+	return dao.SameDepartment(a, b)
+}
+
+// CheckDirectMessage 单聊发送前校验：接收方须是存在的其他账号；被对方屏蔽不能发送；
+// 非联系人在对方回复前最多发送 chat.non_contact_limit 条，通过校验即占用一个名额
+func CheckDirectMessage(senderID, recipientID int) error {
+	if recipientID <= 0 {
+		return ErrChatRecipientInvalid
+	}
+	if recipientID == senderID {
+		return ErrChatSelf
+	}
+	if err := checkAccountsExist(config.DB, []int{recipientID}); err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return ErrChatRecipientInvalid
+		}
+		return err
+	}
+
+	blocked, err := dao.HasChatContactStatus(recipientID, senderID, models.ContactBlocked)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrContactBlocked
+	}
+
+	limit := config.Cfg.Chat.NonContactLimit
+	if limit < 0 {
+		return nil
+	}
+	contact, err := IsContact(senderID, recipientID)
+	if err != nil || contact {
+		return err
+	}
+
+	conversationID := models.DirectConversationID(senderID, recipientID)
+	replied, err := dao.CountChatMessagesFrom(conversationID, recipientID)
+	if err != nil || replied > 0 {
+		return err
+	}
+	sent, err := dao.CountChatMessagesFrom(conversationID, senderID)
+	if err != nil {
+		return err
+	}
+	reserved, err := reserveNonContactScript.Run(config.Ctx, config.Rdb,
+		[]string{nonContactQuotaKey(conversationID, senderID)},
+		sent, limit, int(nonContactQuotaTTL.Seconds())).Int()
+	if err != nil {
+		return err
+	}
+	if reserved == 0 {
+		return ErrNonContactLimit
+	}
+	return nil
+}
+
+// checkGroupInvite 拉人入群前校验：屏蔽了邀请人的用户不能被拉入群；限制非联系人消息时（chat.non_contact_limit >= 0）
+// 只能邀请联系人，以免借群聊绕过屏蔽和条数限制
+func checkGroupInvite(inviterID int, ids []int) error {
+	blockers, err := dao.ListBlockers(inviterID, ids)
+	if err != nil {
+		return err
+	}
+	if len(blockers) > 0 {
+		return ErrContactBlocked
+	}
+	if config.Cfg.Chat.NonContactLimit < 0 {
+		return nil
+	}
+	contacts, err := ContactIDs(inviterID)
+	if err != nil {
+		return err
+	}
+	isContact := make(map[int]bool, len(contacts))
+	for _, id := range contacts {
+		isContact[id] = true
+	}
+	for _, id := range ids {
+		if !isContact[id] {
+			return ErrGroupMemberNotContact
+		}
+	}
+	return nil
+}
+
+// lockContactPair 锁定双方之间两个方向的记录，不存在的为 nil
+func lockContactPair(tx *gorm.DB, userID, otherID int) (mine, theirs *models.ChatContact, err error) {
+	var rows []models.ChatContact
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("(user_id = ? AND contact_id = ?) OR (user_id = ? AND contact_id = ?)", userID, otherID, otherID, userID).
+		Find(&rows).Error; err != nil {
+		return nil, nil, err
+	}
+	for i := range rows {
+		if rows[i].UserID == userID {
+			mine = &rows[i]
+		} else {
+			theirs = &rows[i]
+		}
+	}
+	return mine, theirs, nil
+}
+
+// acceptContact 通过 requesterID 的申请，双方各写一条 accepted 记录
+func acceptContact(tx *gorm.DB, requesterID, userID int) error {
+	if err := saveContact(tx, requesterID, userID, models.ContactAccepted, ""); err != nil {
+		return err
+	}
+	return saveContact(tx, userID, requesterID, models.ContactAccepted, "")
+}
+
+// saveContact 写入或覆盖 userID -> contactID 的记录
+func saveContact(tx *gorm.DB, userID, contactID int, status, message string) error {
+	return tx.Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"status", "message", "updated_at"}),
+	}).Create(&models.ChatContact{UserID: userID, ContactID: contactID, Status: status, Message: message}).Error
+}
+
+func blockedSet(userID int) (map[int]bool, error) {
+	rows, err := dao.ListChatContacts(userID, models.ContactBlocked)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[int]bool, len(rows))
+	for _, r := range rows {
+		set[r.ContactID] = true
+	}
+	return set, nil
+}
+
+// contactPeer 记录中对方的账号ID
+func contactPeer(c models.ChatContact, userID int) int {
+	if c.UserID == userID {
+		return c.ContactID
+	}
+	return c.UserID
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/testutil"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// setupContacts 创建两个互不是联系人的员工，返回其账号ID
+func setupContacts(t *testing.T, limit int) (int, int) {
+	testutil.Setup(t)
+	config.Cfg.Chat.NonContactLimit = limit
+	alice := createTestEmployee(t, "alice", "alice@example.com")
+	bob := createTestEmployee(t, "bob", "bob@example.com")
+	return int(alice.GetAccountID()), int(bob.GetAccountID())
+}
+
+func sendDirect(t *testing.T, from, to int) {
+	t.Helper()
+	msg := models.ChatMessage{
+		ConversationID: models.DirectConversationID(from, to), SendID: from, RecipientID: to,
+		Content: "hi", MsgKey: "k", Status: models.MsgStatusSent,
+	}
+	if err := config.DB.Create(&msg).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckDirectMessageLimitsNonContacts(t *testing.T) {
+	alice, bob := setupContacts(t, 3)
+	sendDirect(t, alice, bob) // 已经发出的消息计入名额
+
+	for i := 0; i < 2; i++ {
+		if err := CheckDirectMessage(alice, bob); err != nil {
+			t.Fatalf("第 %d 条应允许发送: %v", i+2, err)
+		}
+	}
+	if err := CheckDirectMessage(alice, bob); !errors.Is(err, ErrNonContactLimit) {
+		t.Fatalf("超过上限应返回 ErrNonContactLimit，得到 %v", err)
+	}
+}
+
+func TestCheckDirectMessageRejectsInvalidRecipient(t *testing.T) {
+	alice, bob := setupContacts(t, 3)
+	for _, to := range []int{0, -1, bob + 100} {
+		if err := CheckDirectMessage(alice, to); !errors.Is(err, ErrChatRecipientInvalid) {
+			t.Fatalf("发给 %d 应返回 ErrChatRecipientInvalid，得到 %v", to, err)
+		}
+	}
+	if err := CheckDirectMessage(alice, alice); !errors.Is(err, ErrChatSelf) {
+		t.Fatalf("发给自己应返回 ErrChatSelf，得到 %v", err)
+	}
+	// 被拒绝的消息不占用名额
+	for i := 0; i < 3; i++ {
+		if err := CheckDirectMessage(alice, bob); err != nil {
+			t.Fatalf("第 %d 条应允许发送: %v", i+1, err)
+		}
+	}
+}
+
+func TestCheckDirectMessageLimitHoldsUnderConcurrency(t *testing.T) {
+	alice, bob := setupContacts(t, 3)
+
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := CheckDirectMessage(alice, bob)
+			switch {
+			case err == nil:
+				allowed.Add(1)
+			case !errors.Is(err, ErrNonContactLimit):
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := allowed.Load(); n != 3 {
+		t.Fatalf("并发发送时应只允许 3 条，允许了 %d 条", n)
+	}
+}
+
+func TestCheckDirectMessageUnlocksAfterReply(t *testing.T) {
+	alice, bob := setupContacts(t, 0)
+	if err := CheckDirectMessage(alice, bob); !errors.Is(err, ErrNonContactLimit) {
+		t.Fatalf("上限为 0 时只能给联系人发消息，得到 %v", err)
+	}
+
+	sendDirect(t, bob, alice)
+	for i := 0; i < 5; i++ {
+		if err := CheckDirectMessage(alice, bob); err != nil {
+			t.Fatalf("对方回复后不再限制: %v", err)
+		}
+	}
+}
+
+func TestCheckDirectMessageRejectsBlockedSender(t *testing.T) {
+	alice, bob := setupContacts(t, -1)
+	if err := BlockUser(bob, alice); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckDirectMessage(alice, bob); !errors.Is(err, ErrContactBlocked) {
+		t.Fatalf("被屏蔽后应返回 ErrContactBlocked，得到 %v", err)
+	}
+	// 屏蔽是单向的
+	if err := CheckDirectMessage(bob, alice); err != nil {
+		t.Fatalf("屏蔽方仍可发送: %v", err)
+	}
+	if err := UnblockUser(bob, alice); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckDirectMessage(alice, bob); err != nil {
+		t.Fatalf("取消屏蔽后应允许发送: %v", err)
+	}
+}
+
+func TestCheckDirectMessageTreatsColleaguesAsContacts(t *testing.T) {
+	alice, bob := setupContacts(t, 0)
+	dep := models.Department{Depart: "研发部"}
+	if err := config.DB.Create(&dep).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := config.DB.Model(&models.Employee{}).Where("account_id IN ?", []int{alice, bob}).
+		Update("dep_id", dep.DepID).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CheckDirectMessage(alice, bob); err != nil {
+		t.Fatalf("同部门同事视为联系人: %v", err)
+	}
+	ids, err := ContactIDs(alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != bob {
+		t.Fatalf("同部门同事应出现在联系人中，得到 %v", ids)
+	}
+}
+
+func TestGroupInviteRespectsBlockingAndContacts(t *testing.T) {
+	alice, bob := setupContacts(t, 3)
+	carol := int(createTestEmployee(t, "carol", "carol@example.com").GetAccountID())
+
+	// 非联系人不能被拉入群，以免绕过条数限制
+	if _, err := CreateGroup(alice, models.CreateGroupRequest{GroupName: "g", MemberIDs: []int{bob}}); !errors.Is(err, ErrGroupMemberNotContact) {
+		t.Fatalf("拉非联系人入群应返回 ErrGroupMemberNotContact，得到 %v", err)
+	}
+	if _, err := AddContact(alice, carol, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := AcceptContact(carol, alice); err != nil {
+		t.Fatal(err)
+	}
+	group, err := CreateGroup(alice, models.CreateGroupRequest{GroupName: "g", MemberIDs: []int{carol}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AddGroupMembers(group.ID, alice, []int{bob}); !errors.Is(err, ErrGroupMemberNotContact) {
+		t.Fatalf("拉非联系人入群应返回 ErrGroupMemberNotContact，得到 %v", err)
+	}
+
+	// 屏蔽了邀请人的用户不能被拉入群，不限制非联系人时也一样
+	config.Cfg.Chat.NonContactLimit = -1
+	if err := BlockUser(bob, alice); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AddGroupMembers(group.ID, alice, []int{bob}); !errors.Is(err, ErrContactBlocked) {
+		t.Fatalf("拉屏蔽了自己的用户入群应返回 ErrContactBlocked，得到 %v", err)
+	}
+	if err := UnblockUser(bob, alice); err != nil {
+		t.Fatal(err)
+	}
+	if added, err := AddGroupMembers(group.ID, alice, []int{bob}); err != nil || added != 1 {
+		t.Fatalf("不限制非联系人时应可拉入群，得到 %d, %v", added, err)
+	}
+}
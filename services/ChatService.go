@@ -0,0 +1,329 @@
+package services
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/dao"
+	"EmployeeManagementDemo/models"
+	"errors"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+const (
+	defaultChatPageSize = 20
+	maxChatPageSize     = 100
+)
+
+var (
+	ErrConversationInvalid = errors.New("会话不存在")
+	ErrSearchQueryInvalid  = errors.New("搜索关键词至少两个字符")
+	ErrChatMessageNotFound = errors.New("消息不存在")
+	ErrChatMessageRecalled = errors.New("消息已撤回")
+	ErrChatEditExpired     = errors.New("已超过可编辑时间")
+	ErrChatRecallExpired   = errors.New("已超过可撤回时间")
+)
+
+// ListConversations 当前用户的会话列表：最后一条消息和未读数
+func ListConversations(userID, limit int) ([]models.ConversationDTO, error) {
+	summaries, err := dao.ListConversationSummaries(userID, chatPageSize(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	lastIDs := make([]uint, 0, len(summaries))
+	for _, s := range summaries {
+		lastIDs = append(lastIDs, s.LastID)
+	}
+	msgs, err := dao.GetChatMessagesByIDs(lastIDs)
+	if err != nil {
+		return nil, err
+	}
+	lastMsgs := make(map[uint]*models.ChatMessage, len(msgs))
+	for i := range msgs {
+		lastMsgs[msgs[i].ID] = &msgs[i]
+	}
+
+	lastList := make([]models.ChatMessage, 0, len(summaries))
+	convs := make([]models.ConversationDTO, 0, len(summaries))
+	var peerIDs []int
+	var groupIDs []string
+	for _, s := range summaries {
+		peers, groupID, ok := models.ParseConversationID(s.ConversationID)
+		last := lastMsgs[s.LastID]
+		if !ok || last == nil {
+			continue
+		}
+		conv := models.ConversationDTO{
+			ID:          s.ConversationID,
+			LastMessage: last.ToDTO(),
+			UnreadCount: s.Unread,
+		}
+		if groupID != "" {
+			conv.Type = models.ConversationTypeGroup
+			conv.GroupID = groupID
+			groupIDs = append(groupIDs, groupID)
+		} else {
+			conv.Type = models.ConversationTypeUser
+			conv.PeerID = peers[0]
+			if conv.PeerID == userID {
+				conv.PeerID = peers[1]
+			}
+			peerIDs = append(peerIDs, conv.PeerID)
+		}
+		convs = append(convs, conv)
+		lastList = append(lastList, *last)
+	}
+
+	lastDTOs := make([]models.ChatMessageDTO, len(convs))
+	for i := range convs {
+		lastDTOs[i] = convs[i].LastMessage
+	}
+	if err := fillChatAttachments(lastList, lastDTOs); err != nil {
+		return nil, err
+	}
+
+	usernames, err := dao.GetUsernames(peerIDs)
+	if err != nil {
+		return nil, err
+	}
+	groupNames, err := dao.GetGroupNames(groupIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range convs {
+		convs[i].LastMessage = lastDTOs[i]
+		if convs[i].Type == models.ConversationTypeGroup {
+			convs[i].Name = groupNames[convs[i].GroupID]
+		} else {
+			convs[i].Name = usernames[convs[i].PeerID]
+		}
+	}
+	return convs, nil
+}
+
+// ListConversationMessages 会话历史，按消息ID倒序分页
+func ListConversationMessages(userID int, conversationID string, before uint, limit int) (*models.ChatMessagePageDTO, error) {
+	if err := checkConversation(userID, conversationID); err != nil {
+		return nil, err
+	}
+	limit = chatPageSize(limit)
+	msgs, err := dao.ListConversationMessages(userID, conversationID, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	return chatMessagePage(msgs, limit)
+}
+
+// ConversationHistoryBefore 会话中 before 之前的消息（WebSocket 拉取历史记录），与 REST 接口一样不含自己删除的
+func ConversationHistoryBefore(userID int, conversationID string, before time.Time, limit int) ([]models.ChatMessageDTO, error) {
+	if err := checkConversation(userID, conversationID); err != nil {
+		return nil, err
+	}
+	limit = chatPageSize(limit)
+	msgs, err := dao.ListConversationMessagesBefore(userID, conversationID, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	page, err := chatMessagePage(msgs, limit)
+	if err != nil {
+		return nil, err
+	}
+	return page.Messages, nil
+}
+
+// SearchChatMessages 在当前用户可见的消息中全文检索，conversationID 为空时检索全部会话
+func SearchChatMessages(userID int, keyword, conversationID string, before uint, limit int) (*models.ChatMessagePageDTO, error) {
+	// 按短语检索：去掉双引号避免破坏 BOOLEAN MODE 语法；ngram 分词下少于两个字符无法命中
+	keyword = strings.TrimSpace(strings.ReplaceAll(keyword, `"`, " "))
+	if len([]rune(keyword)) < 2 {
+		return nil, ErrSearchQueryInvalid
+	}
+	if conversationID != "" {
+		if err := checkConversation(userID, conversationID); err != nil {
+			return nil, err
+		}
+	}
+	limit = chatPageSize(limit)
+	msgs, err := dao.SearchChatMessages(userID, `"`+keyword+`"`, conversationID, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	return chatMessagePage(msgs, limit)
+}
+
+// PendingChatMessages 离线期间收到、尚未送达的消息
+func PendingChatMessages(userID int) ([]models.ChatMessage, error) {
+	return dao.GetUndeliveredMessages(userID)
+}
+
+// MarkChatMessagesDelivered 消息推送到接收方连接后标记为已送达
+func MarkChatMessagesDelivered(ids []uint, at time.Time) error {
+	return dao.MarkChatMessagesDelivered(ids, at)
+}
+
+// MarkMessagesRead 接收方确认已读，返回本次由未读变为已读的消息（用于给发送方回执）
+func MarkMessagesRead(userID int, ids []uint) ([]models.ChatMessage, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return dao.MarkChatMessagesRead(userID, func(db *gorm.DB) *gorm.DB {
+		return db.Where("id IN ?", ids)
+	}, time.Now())
+}
+
+// MarkConversationRead 把会话中发给自己的消息全部标记为已读
+func MarkConversationRead(userID int, conversationID string) ([]models.ChatMessage, error) {
+	if err := checkConversation(userID, conversationID); err != nil {
+		return nil, err
+	}
+	return dao.MarkChatMessagesRead(userID, func(db *gorm.DB) *gorm.DB {
+		return db.Where("conversation_id = ?", conversationID)
+	}, time.Now())
+}
+
+// ListMessageReceipts 自己发出的一条消息在各接收方处的送达、已读状态（群消息每个成员一条）
+func ListMessageReceipts(userID int, msgKey string) ([]models.MessageReceiptDTO, error) {
+	receipts, err := dao.ListMessageReceipts(userID, msgKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(receipts) == 0 {
+		return nil, ErrChatMessageNotFound
+	}
+	return receipts, nil
+}
+
+// EditChatMessage 发送方在时限内编辑自己的消息，返回全部副本（用于推送给在线的接收方）
+func EditChatMessage(userID int, msgKey, content string) ([]models.ChatMessage, error) {
+	msgs, err := ownChatMessages(userID, msgKey, config.Cfg.Chat.EditWindow, ErrChatEditExpired)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if err := dao.UpdateChatMessagesByKey(msgKey, map[string]interface{}{"content": content, "edited_at": now}); err != nil {
+		return nil, err
+	}
+	for i := range msgs {
+		msgs[i].Content = content
+		msgs[i].EditedAt = &now
+	}
+	return msgs, nil
+}
+
+// RecallChatMessage 发送方在时限内撤回自己的消息，内容和附件引用随之清空
+func RecallChatMessage(userID int, msgKey string) ([]models.ChatMessage, error) {
+	msgs, err := ownChatMessages(userID, msgKey, config.Cfg.Chat.RecallWindow, ErrChatRecallExpired)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if err := dao.UpdateChatMessagesByKey(msgKey, map[string]interface{}{"content": "", "attachment_id": nil, "recalled_at": now}); err != nil {
+		return nil, err
+	}
+	for i := range msgs {
+		msgs[i].Content = ""
+		msgs[i].AttachmentID = nil
+		msgs[i].RecalledAt = &now
+	}
+	return msgs, nil
+}
+
+// DeleteChatMessageForMe 仅对自己删除消息，其他人仍可见
+func DeleteChatMessageForMe(userID int, msgKey string) error {
+	affected, err := dao.HideChatMessage(userID, msgKey)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrChatMessageNotFound
+	}
+	return nil
+}
+
+// RemoveChatMessage 管理员删除违规消息（全部副本），返回删除前的副本（用于推送）
+func RemoveChatMessage(msgKey string) ([]models.ChatMessage, error) {
+	msgs, err := dao.GetChatMessagesByKey(msgKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(msgs) == 0 {
+		return nil, ErrChatMessageNotFound
+	}
+	if err := dao.DeleteChatMessagesByKey(msgKey); err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+// ownChatMessages 查询自己发出、未撤回且仍在时限内的消息的全部副本
+func ownChatMessages(userID int, msgKey string, window time.Duration, expired error) ([]models.ChatMessage, error) {
+	msgs, err := dao.GetChatMessagesByKey(msgKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(msgs) == 0 || msgs[0].SendID != userID {
+		return nil, ErrChatMessageNotFound
+	}
+	if msgs[0].RecalledAt != nil {
+		return nil, ErrChatMessageRecalled
+	}
+	if window <= 0 || time.Since(msgs[0].CreatedAt) > window {
+		return nil, expired
+	}
+	return msgs, nil
+}
+
+// MigrateChatMessages 为会话ID、消息状态上线前的历史消息补写会话ID、状态和消息标识
+func MigrateChatMessages() error {
+	if err := config.DB.Exec("UPDATE chat_messages SET msg_key = CONCAT('m', id) WHERE msg_key = ''").Error; err != nil {
+		return err
+	}
+	if err := config.DB.Exec("UPDATE chat_messages SET status = 'read', delivered_at = created_at, read_at = created_at " +
+		"WHERE `read` = 1 AND read_at IS NULL").Error; err != nil {
+		return err
+	}
+	if err := config.DB.Exec("UPDATE chat_messages SET conversation_id = CONCAT('g:', group_id) " +
+		"WHERE conversation_id = '' AND group_id <> ''").Error; err != nil {
+		return err
+	}
+	return config.DB.Exec("UPDATE chat_messages SET conversation_id = " +
+		"CONCAT('u:', LEAST(send_id, recipient_id), ':', GREATEST(send_id, recipient_id)) " +
+		"WHERE conversation_id = '' AND group_id = ''").Error
+}
+
+// checkConversation 校验会话ID格式，单聊须为参与者之一（群聊的可见范围由消息副本限定，退群后仍可查看历史）
+func checkConversation(userID int, conversationID string) error {
+	peers, groupID, ok := models.ParseConversationID(conversationID)
+	if !ok {
+		return ErrConversationInvalid
+	}
+	if groupID == "" && peers[0] != userID && peers[1] != userID {
+		return ErrConversationInvalid
+	}
+	return nil
+}
+
+func chatPageSize(limit int) int {
+	if limit <= 0 {
+		return defaultChatPageSize
+	}
+	if limit > maxChatPageSize {
+		return maxChatPageSize
+	}
+	return limit
+}
+
+func chatMessagePage(msgs []models.ChatMessage, limit int) (*models.ChatMessagePageDTO, error) {
+	page := &models.ChatMessagePageDTO{Messages: make([]models.ChatMessageDTO, 0, len(msgs))}
+	for i := range msgs {
+		page.Messages = append(page.Messages, msgs[i].ToDTO())
+	}
+	if err := fillChatAttachments(msgs, page.Messages); err != nil {
+		return nil, err
+	}
+	if len(msgs) == limit {
+		page.NextBefore = msgs[len(msgs)-1].ID
+	}
+	return page, nil
+}
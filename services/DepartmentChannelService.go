@@ -0,0 +1,199 @@
+package services
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/models"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log"
+)
+
+// 部门群：每个部门一个，随部门创建、改名、删除，成员即该部门的全部员工（账号），不能手动调整。
+// 部门群没有群主和管理员，也不受普通群的人数上限限制
+
+var ErrGroupManaged = errors.New("部门群由系统维护，不能手动调整")
+
+// CreateDepartmentChannel 为新部门创建部门群
+func CreateDepartmentChannel(tx *gorm.DB, dep *models.Department) error {
+	_, err := createDepartmentChannel(tx, dep)
+	return err
+}
+
+// RenameDepartmentChannel 部门改名时同步群名
+func RenameDepartmentChannel(tx *gorm.DB, depID uint, name string) error {
+	group, err := departmentChannel(tx, depID)
+	if err != nil || group == nil {
+		return err
+	}
+	return tx.Model(group).Update("group_name", name).Error
+}
+
+// DeleteDepartmentChannel 部门删除时解散部门群，历史消息保留
+func DeleteDepartmentChannel(tx *gorm.DB, depID uint) error {
+	var group models.Group
+	if err := tx.Where("dep_id = ?", depID).First(&group).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if err := tx.Where("group_id = ?", group.ID).Delete(&models.UsersGroup{}).Error; err != nil {
+		return err
+	}
+	// 释放 dep_id 唯一索引
+	if err := tx.Model(&group).Update("dep_id", nil).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&group).Error
+}
+
+// MoveDepartmentChannelMember 员工调动时调整部门群成员，部门ID为 0 表示无（新入职或离职）
+func MoveDepartmentChannelMember(tx *gorm.DB, accountID, fromDepID, toDepID uint) error {
+	if accountID == 0 || fromDepID == toDepID {
+		return nil
+	}
+	if fromDepID != 0 {
+		group, err := departmentChannel(tx, fromDepID)
+		if err != nil {
+			return err
+		}
+		if group != nil {
+			if err := tx.Where("group_id = ? AND user_id = ?", group.ID, accountID).Delete(&models.UsersGroup{}).Error; err != nil {
+				return err
+			}
+			if err := syncGroupNum(tx, group); err != nil {
+				return err
+			}
+		}
+	}
+	if toDepID != 0 {
+		group, err := departmentChannel(tx, toDepID)
+		if err != nil {
+			return err
+		}
+		if group != nil {
+			member := models.UsersGroup{GroupId: group.ID, UserId: int(accountID)}
+			if err := tx.Where(member).Attrs(models.UsersGroup{Role: models.GroupRoleMember}).
+				FirstOrCreate(&member).Error; err != nil {
+				return err
+			}
+			if err := syncGroupNum(tx, group); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SyncDepartmentChannels 启动时按部门和员工对齐全部部门群（补建缺失的群、修正群名和成员）
+func SyncDepartmentChannels() error {
+	var deps []models.Department
+	if err := config.DB.Find(&deps).Error; err != nil {
+		return err
+	}
+	for _, dep := range deps {
+		if err := config.DB.Transaction(func(tx *gorm.DB) error {
+			return syncDepartmentChannel(tx, dep)
+		}); err != nil {
+			return fmt.Errorf("部门 %d 群同步失败: %w", dep.DepID, err)
+		}
+	}
+	return nil
+}
+
+func syncDepartmentChannel(tx *gorm.DB, dep models.Department) error {
+	group, err := departmentChannel(tx, dep.DepID)
+	if err != nil {
+		return err
+	}
+	if group.GroupName != dep.Depart {
+		if err := tx.Model(group).Update("group_name", dep.Depart).Error; err != nil {
+			return err
+		}
+	}
+
+	var want []int
+	if err := tx.Model(&models.Employee{}).Where("dep_id = ? AND account_id IS NOT NULL", dep.DepID).
+		Pluck("account_id", &want).Error; err != nil {
+		return err
+	}
+	var have []int
+	if err := tx.Model(&models.UsersGroup{}).Where("group_id = ?", group.ID).Pluck("user_id", &have).Error; err != nil {
+		return err
+	}
+
+	wantSet := make(map[int]bool, len(want))
+	for _, id := range want {
+		wantSet[id] = true
+	}
+	haveSet := make(map[int]bool, len(have))
+	var stale []int
+	for _, id := range have {
+		haveSet[id] = true
+		if !wantSet[id] {
+			stale = append(stale, id)
+		}
+	}
+	var missing []models.UsersGroup
+	for _, id := range want {
+		if !haveSet[id] {
+			missing = append(missing, models.UsersGroup{GroupId: group.ID, UserId: id, Role: models.GroupRoleMember})
+		}
+	}
+	if len(stale) == 0 && len(missing) == 0 && group.GroupNum == len(want) {
+		return nil
+	}
+
+	if len(stale) > 0 {
+		if err := tx.Where("group_id = ? AND user_id IN ?", group.ID, stale).Delete(&models.UsersGroup{}).Error; err != nil {
+			return err
+		}
+	}
+	if len(missing) > 0 {
+		if err := tx.Create(&missing).Error; err != nil {
+			return err
+		}
+	}
+	log.Printf("部门群 %s 已同步：新增 %d 人，移除 %d 人", dep.Depart, len(missing), len(stale))
+	return syncGroupNum(tx, group)
+}
+
+// departmentChannel 锁定并返回部门群，缺失时补建（兼容功能上线前已有的部门）；部门不存在时返回 nil
+func departmentChannel(tx *gorm.DB, depID uint) (*models.Group, error) {
+	var group models.Group
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("dep_id = ?", depID).First(&group).Error
+	if err == nil {
+		return &group, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var dep models.Department
+	if err := tx.First(&dep, depID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return createDepartmentChannel(tx, &dep)
+}
+
+func createDepartmentChannel(tx *gorm.DB, dep *models.Department) (*models.Group, error) {
+	id, err := newGroupID(tx)
+	if err != nil {
+		return nil, err
+	}
+	depID := dep.DepID
+	group := &models.Group{
+		ID:        id,
+		GroupName: dep.Depart,
+		DepID:     &depID,
+	}
+	if err := tx.Create(group).Error; err != nil {
+		return nil, err
+	}
+	return group, nil
+}
@@ -2,9 +2,14 @@ package services
 
 import (
 	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/dao"
 	"EmployeeManagementDemo/models"
+	"errors"
+	"gorm.io/gorm"
 )
 
+var ErrDepartmentNotFound = errors.New("部门不存在")
+
 func GetDepartmentAvgSalaries() ([]models.DepartmentAvgSalaryDTO, error) {
 	var results []models.DepartmentAvgSalaryDTO
 
@@ -56,3 +61,74 @@ func GetDepartmentHeadcounts() ([]models.DepartmentHeadcountDTO, error) {
 
 	return results, nil
 }
+
+// SetDepartmentManager 任命（empID 为 0 时撤销）部门负责人，并同步 manager 角色：
+// 新负责人自动获得该角色，原负责人不再负责任何部门时收回
+func SetDepartmentManager(depID, empID uint) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		var dep models.Department
+		if err := tx.First(&dep, depID).Error; err != nil {
+			return ErrDepartmentNotFound
+		}
+		managerRole, err := dao.GetRoleByName(models.RoleManager)
+		if err != nil {
+			return ErrRoleNotFound
+		}
+
+		oldManager := dep.ManagerID
+		var newManager *uint
+		if empID != 0 {
+			var emp models.Employee
+			if err := tx.First(&emp, empID).Error; err != nil {
+				return ErrUserNotFound
+			}
+			newManager = &empID
+		}
+
+		if err := tx.Model(&dep).Update("manager_id", newManager).Error; err != nil {
+			return err
+		}
+
+		if newManager != nil {
+			ur := models.UserRole{UserType: models.UserTypeEmployee, UserID: *newManager, RoleID: managerRole.ID}
+			if err := tx.Where(ur).FirstOrCreate(&ur).Error; err != nil {
+				return err
+			}
+		}
+		if oldManager != nil && (newManager == nil || *oldManager != *newManager) {
+			return releaseManagerRole(tx, *oldManager, managerRole.ID)
+		}
+		return nil
+	})
+}
+
+// ReleaseManagerRole 员工不再负责任何部门时收回 manager 角色（删除部门后调用）
+func ReleaseManagerRole(empID uint) error {
+	managerRole, err := dao.GetRoleByName(models.RoleManager)
+	if err != nil {
+		return ErrRoleNotFound
+	}
+	return releaseManagerRole(config.DB, empID, managerRole.ID)
+}
+
+// RevokeEmployeeManagement 员工删除或调离部门时撤销其负责的全部部门，并收回 manager 角色
+func RevokeEmployeeManagement(tx *gorm.DB, empID uint) error {
+	if err := tx.Model(&models.Department{}).Where("manager_id = ?", empID).
+		Update("manager_id", nil).Error; err != nil {
+		return err
+	}
+	managerRole, err := dao.GetRoleByName(models.RoleManager)
+	if err != nil {
+		return ErrRoleNotFound
+	}
+	return releaseManagerRole(tx, empID, managerRole.ID)
+}
+
+func releaseManagerRole(tx *gorm.DB, empID, roleID uint) error {
+	count, err := dao.CountManagedDepartments(tx, empID)
+	if err != nil || count > 0 {
+		return err
+	}
+	return tx.Where("user_type = ? AND user_id = ? AND role_id = ?", models.UserTypeEmployee, empID, roleID).
+		Delete(&models.UserRole{}).Error
+}
@@ -1,9 +1,9 @@
 package services
 
 import (
-	"EmployeeManagementDemo/dao"
+	"EmployeeManagementDemo/config"
 	"EmployeeManagementDemo/models"
-	"errors"
+	"gorm.io/gorm"
 )
 
 // services/LoginAuth.go
@@ -15,13 +15,24 @@ func HashPassword(password string) (string, error) {
 }
 
 // services/employee.go
-func CreateEmployee(emp *models.Employee) error {
-	// 检查用户名唯一性
-	if exists, _ := dao.CheckUsernameExists(emp.Username); exists {
-		return errors.New("用户名已存在")
-	}
-
+// CreateEmployee 创建员工及其登录账号（用户名全局唯一，重名返回 ErrUsernameTaken）
+func CreateEmployee(emp *models.Employee, hashedPassword string) error {
 	// 默认部门分配（示例）
 	//emp.DepID = 1 // 默认部门ID
-	return dao.CreateEmployee(emp)
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		return CreateEmployeeWithTx(tx, emp, hashedPassword)
+	})
+}
+
+// CreateEmployeeWithTx 在已有事务中创建员工及账号（批量导入使用），并加入所在部门的部门群
+func CreateEmployeeWithTx(tx *gorm.DB, emp *models.Employee, hashedPassword string) error {
+	acc, err := CreateAccount(tx, emp.Username, hashedPassword)
+	if err != nil {
+		return err
+	}
+	emp.AccountID = &acc.ID
+	if err := tx.Create(emp).Error; err != nil {
+		return err
+	}
+	return MoveDepartmentChannelMember(tx, acc.ID, 0, emp.DepID)
 }
@@ -0,0 +1,68 @@
+// services/ExternalIdentityService.go
+package services
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/models"
+	"errors"
+	"gorm.io/gorm"
+	"time"
+)
+
+// 外部身份（OIDC、LDAP）与本地账号的绑定，单点登录和目录认证共用
+
+// findExternalAccount 查找已绑定的账号，找到时顺便更新最近登录时间和邮箱
+func findExternalAccount(provider, subject, email string) (uint, bool, error) {
+	var ext models.ExternalIdentity
+	err := config.DB.Where("provider = ? AND subject = ?", provider, subject).First(&ext).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	config.DB.Model(&ext).Updates(map[string]interface{}{"last_login_at": time.Now(), "email": email})
+	return ext.AccountID, true, nil
+}
+
+// linkExternalIdentity 记录外部身份与账号的绑定，action 为审计日志中的操作名
+func linkExternalIdentity(provider, subject string, accountID uint, email, action string) error {
+	ext := models.ExternalIdentity{
+		Provider:    provider,
+		Subject:     subject,
+		AccountID:   accountID,
+		Email:       email,
+		LastLoginAt: time.Now(),
+	}
+	if err := config.DB.Create(&ext).Error; err != nil {
+		return err
+	}
+
+	LogOperation(accountID, models.ActorUser, action, subject)
+	return nil
+}
+
+// provisionEmployee 自动创建员工（无本地密码，只能通过外部身份或找回密码登录）。
+// 用户名以 base 为准，重名时追加 _<suffix>
+func provisionEmployee(base, email string, depID uint, suffix, action string) (uint, error) {
+	var emp models.Employee
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		name, err := availableUsername(tx, truncateRunes(base, 20), suffix)
+		if err != nil {
+			return err
+		}
+		emp = models.Employee{
+			Username: name,
+			Email:    email,
+			DepID:    depID,
+			Status:   "在职",
+		}
+		return CreateEmployeeWithTx(tx, &emp, "")
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	LogOperation(emp.GetAccountID(), models.ActorUser, action, emp.Username)
+	return emp.GetAccountID(), nil
+}
@@ -0,0 +1,367 @@
+package services
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/dao"
+	"EmployeeManagementDemo/models"
+	"crypto/rand"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"math/big"
+	"strconv"
+)
+
+// maxGroupMembers 单个群的人数上限（含群主）
+const maxGroupMembers = 500
+
+var (
+	ErrGroupNotFound         = errors.New("群聊不存在")
+	ErrNotGroupMember        = errors.New("不是该群成员")
+	ErrGroupMemberNotFound   = errors.New("该用户不在群内")
+	ErrGroupForbidden        = errors.New("无权执行该群操作")
+	ErrGroupOwnerLeave       = errors.New("群主需先转让群主才能退群")
+	ErrGroupFull             = errors.New("群人数已达上限")
+	ErrGroupMemberNotContact = errors.New("只能邀请联系人入群")
+)
+
+// CreateGroup 创建群聊，创建者为群主；member_ids 中不存在的账号、屏蔽了群主的账号或非联系人会使整个请求失败
+func CreateGroup(ownerID int, req models.CreateGroupRequest) (*models.Group, error) {
+	memberIDs := uniqueIDs(req.MemberIDs, ownerID)
+	if len(memberIDs)+1 > maxGroupMembers {
+		return nil, ErrGroupFull
+	}
+
+	group := &models.Group{
+		GroupName:    req.GroupName,
+		GroupContent: req.GroupContent,
+		GroupIcon:    req.GroupIcon,
+		GroupOwnerId: ownerID,
+	}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := checkAccountsExist(tx, memberIDs); err != nil {
+			return err
+		}
+		if err := checkGroupInvite(ownerID, memberIDs); err != nil {
+			return err
+		}
+		id, err := newGroupID(tx)
+		if err != nil {
+			return err
+		}
+		group.ID = id
+		group.GroupNum = len(memberIDs) + 1
+		if err := tx.Create(group).Error; err != nil {
+			return err
+		}
+
+		members := []models.UsersGroup{{GroupId: id, UserId: ownerID, Role: models.GroupRoleOwner}}
+		for _, uid := range memberIDs {
+			members = append(members, models.UsersGroup{GroupId: id, UserId: uid, Role: models.GroupRoleMember})
+		}
+		return tx.Create(&members).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// ListMyGroups 当前账号所在的群聊
+func ListMyGroups(userID int) ([]models.GroupDTO, error) {
+	return dao.ListGroupsByMember(userID)
+}
+
+// ListGroupMembers 群成员列表，仅群成员可查看
+func ListGroupMembers(groupID string, userID int) ([]models.GroupMemberDTO, error) {
+	if _, err := GetGroupMemberRole(groupID, userID); err != nil {
+		return nil, err
+	}
+	return dao.ListGroupMembers(groupID)
+}
+
+// GetGroupMemberRole 查询账号在群内的角色，群不存在时返回 ErrGroupNotFound，不在群内时返回 ErrNotGroupMember
+func GetGroupMemberRole(groupID string, userID int) (string, error) {
+	var group models.Group
+	if err := config.DB.Select("id").Where("id = ?", groupID).First(&group).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrGroupNotFound
+		}
+		return "", err
+	}
+	member, err := dao.GetGroupMember(groupID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrNotGroupMember
+		}
+		return "", err
+	}
+	return member.Role, nil
+}
+
+// GetGroupMemberIDs 群内全部成员的账号ID（群聊消息按此广播）
+func GetGroupMemberIDs(groupID string) ([]int, error) {
+	return dao.GetGroupMemberIDs(groupID)
+}
+
+// UpdateGroup 修改群名、群签名（群主、管理员）
+func UpdateGroup(groupID string, operatorID int, req models.UpdateGroupRequest) error {
+	updates := map[string]interface{}{}
+	if req.GroupName != nil {
+		updates["group_name"] = *req.GroupName
+	}
+	if req.GroupContent != nil {
+		updates["group_content"] = *req.GroupContent
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	return withGroup(groupID, operatorID, func(tx *gorm.DB, group *models.Group, operator *models.UsersGroup) error {
+		if group.DepID != nil && req.GroupName != nil {
+			return ErrGroupManaged // 部门群名随部门名
+		}
+		if !canManageGroup(operator.Role) {
+			return ErrGroupForbidden
+		}
+		return tx.Model(group).Updates(updates).Error
+	})
+}
+
+// SetGroupIcon 设置群头像（群主、管理员）
+func SetGroupIcon(groupID string, operatorID int, icon string) error {
+	return withGroup(groupID, operatorID, func(tx *gorm.DB, group *models.Group, operator *models.UsersGroup) error {
+		if !canManageGroup(operator.Role) {
+			return ErrGroupForbidden
+		}
+		return tx.Model(group).Update("group_icon", icon).Error
+	})
+}
+
+// AddGroupMembers 添加成员（群主、管理员），已在群内的账号忽略，返回实际新增人数；与创建群聊一样校验屏蔽和联系人
+func AddGroupMembers(groupID string, operatorID int, userIDs []int) (int, error) {
+	added := 0
+	err := withGroup(groupID, operatorID, func(tx *gorm.DB, group *models.Group, operator *models.UsersGroup) error {
+		if group.DepID != nil {
+			return ErrGroupManaged
+		}
+		if !canManageGroup(operator.Role) {
+			return ErrGroupForbidden
+		}
+		ids := uniqueIDs(userIDs, 0)
+		if err := checkAccountsExist(tx, ids); err != nil {
+			return err
+		}
+
+		var existing []int
+		if err := tx.Model(&models.UsersGroup{}).Where("group_id = ? AND user_id IN ?", groupID, ids).
+			Pluck("user_id", &existing).Error; err != nil {
+			return err
+		}
+		inGroup := make(map[int]bool, len(existing))
+		for _, uid := range existing {
+			inGroup[uid] = true
+		}
+		var newIDs []int
+		var members []models.UsersGroup
+		for _, uid := range ids {
+			if !inGroup[uid] {
+				newIDs = append(newIDs, uid)
+				members = append(members, models.UsersGroup{GroupId: groupID, UserId: uid, Role: models.GroupRoleMember})
+			}
+		}
+		if len(members) == 0 {
+			return nil
+		}
+		if err := checkGroupInvite(operatorID, newIDs); err != nil {
+			return err
+		}
+		if group.GroupNum+len(members) > maxGroupMembers {
+			return ErrGroupFull
+		}
+		if err := tx.Create(&members).Error; err != nil {
+			return err
+		}
+		added = len(members)
+		return syncGroupNum(tx, group)
+	})
+	return added, err
+}
+
+// RemoveGroupMember 移除成员：移除自己即退群（群主须先转让）；
+// 群主可移除任何人，管理员只能移除普通成员
+func RemoveGroupMember(groupID string, operatorID, targetID int) error {
+	return withGroup(groupID, operatorID, func(tx *gorm.DB, group *models.Group, operator *models.UsersGroup) error {
+		if group.DepID != nil {
+			return ErrGroupManaged
+		}
+		target := operator
+		if targetID != operatorID {
+			var err error
+			if target, err = lockGroupMember(tx, groupID, targetID); err != nil {
+				return err
+			}
+			if !outranks(operator.Role, target.Role) {
+				return ErrGroupForbidden
+			}
+		} else if operator.Role == models.GroupRoleOwner {
+			return ErrGroupOwnerLeave
+		}
+
+		if err := tx.Where("group_id = ? AND user_id = ?", groupID, target.UserId).Delete(&models.UsersGroup{}).Error; err != nil {
+			return err
+		}
+		return syncGroupNum(tx, group)
+	})
+}
+
+// SetGroupMemberRole 任免管理员（仅群主）
+func SetGroupMemberRole(groupID string, operatorID, targetID int, role string) error {
+	return withGroup(groupID, operatorID, func(tx *gorm.DB, group *models.Group, operator *models.UsersGroup) error {
+		if operator.Role != models.GroupRoleOwner || targetID == operatorID {
+			return ErrGroupForbidden
+		}
+		if _, err := lockGroupMember(tx, groupID, targetID); err != nil {
+			return err
+		}
+		return tx.Model(&models.UsersGroup{}).Where("group_id = ? AND user_id = ?", groupID, targetID).
+			Update("role", role).Error
+	})
+}
+
+// TransferGroupOwner 转让群主（仅群主），原群主降为管理员
+func TransferGroupOwner(groupID string, operatorID, newOwnerID int) error {
+	return withGroup(groupID, operatorID, func(tx *gorm.DB, group *models.Group, operator *models.UsersGroup) error {
+		if operator.Role != models.GroupRoleOwner || newOwnerID == operatorID {
+			return ErrGroupForbidden
+		}
+		if _, err := lockGroupMember(tx, groupID, newOwnerID); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.UsersGroup{}).Where("group_id = ? AND user_id = ?", groupID, operatorID).
+			Update("role", models.GroupRoleAdmin).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.UsersGroup{}).Where("group_id = ? AND user_id = ?", groupID, newOwnerID).
+			Update("role", models.GroupRoleOwner).Error; err != nil {
+			return err
+		}
+		return tx.Model(group).Update("group_owner_id", newOwnerID).Error
+	})
+}
+
+// DissolveGroup 解散群聊（仅群主），成员关系一并删除，历史消息保留
+func DissolveGroup(groupID string, operatorID int) error {
+	return withGroup(groupID, operatorID, func(tx *gorm.DB, group *models.Group, operator *models.UsersGroup) error {
+		if operator.Role != models.GroupRoleOwner {
+			return ErrGroupForbidden
+		}
+		if err := tx.Where("group_id = ?", groupID).Delete(&models.UsersGroup{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(group).Error
+	})
+}
+
+// withGroup 在事务中锁定群并查出操作者的成员记录，同一个群的成员变更串行执行，保证 GroupNum 与成员表一致
+func withGroup(groupID string, operatorID int, fn func(tx *gorm.DB, group *models.Group, operator *models.UsersGroup) error) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		var group models.Group
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", groupID).First(&group).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrGroupNotFound
+			}
+			return err
+		}
+		operator, err := lockGroupMember(tx, groupID, operatorID)
+		if err != nil {
+			if errors.Is(err, ErrGroupMemberNotFound) {
+				return ErrNotGroupMember
+			}
+			return err
+		}
+		return fn(tx, &group, operator)
+	})
+}
+
+func lockGroupMember(tx *gorm.DB, groupID string, userID int) (*models.UsersGroup, error) {
+	var member models.UsersGroup
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("group_id = ? AND user_id = ?", groupID, userID).First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrGroupMemberNotFound
+	}
+	return &member, err
+}
+
+// syncGroupNum 按成员表重新统计群人数
+func syncGroupNum(tx *gorm.DB, group *models.Group) error {
+	var count int64
+	if err := tx.Model(&models.UsersGroup{}).Where("group_id = ?", group.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	group.GroupNum = int(count)
+	return tx.Model(group).Update("group_num", count).Error
+}
+
+func canManageGroup(role string) bool {
+	return role == models.GroupRoleOwner || role == models.GroupRoleAdmin
+}
+
+// outranks 群主可管理管理员和成员，管理员只能管理普通成员
+func outranks(operatorRole, targetRole string) bool {
+	switch operatorRole {
+	case models.GroupRoleOwner:
+		return targetRole != models.GroupRoleOwner
+	case models.GroupRoleAdmin:
+		return targetRole == models.GroupRoleMember
+	}
+	return false
+}
+
+// checkAccountsExist 校验账号均存在
+func checkAccountsExist(tx *gorm.DB, ids []int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	var count int64
+	if err := tx.Model(&models.Account{}).Where("id IN ?", ids).Count(&count).Error; err != nil {
+		return err
+	}
+	if int(count) != len(ids) {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// uniqueIDs 去重并剔除 exclude
+func uniqueIDs(ids []int, exclude int) []int {
+	seen := make(map[int]bool, len(ids))
+	out := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if id == exclude || seen[id] {
+			continue
+		}
+		seen[id] = true
+		out = append(out, id)
+	}
+	return out
+}
+
+// newGroupID 生成 9 位数字群号（群聊消息以数字群号寻址），与已有群号（含已解散的）冲突时重试
+func newGroupID(tx *gorm.DB) (string, error) {
+	for i := 0; i < 5; i++ {
+		n, err := rand.Int(rand.Reader, big.NewInt(900000000))
+		if err != nil {
+			return "", err
+		}
+		id := strconv.FormatInt(n.Int64()+100000000, 10)
+		var count int64
+		if err := tx.Unscoped().Model(&models.Group{}).Where("id = ?", id).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return id, nil
+		}
+	}
+	return "", errors.New("群号生成失败，请重试")
+}
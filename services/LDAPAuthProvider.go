@@ -0,0 +1,192 @@
+// services/LDAPAuthProvider.go
+package services
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/dao"
+	"EmployeeManagementDemo/models"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/go-ldap/ldap/v3"
+	"gorm.io/gorm"
+	"net"
+	"net/url"
+	"strings"
+)
+
+const ldapIdentityProvider = "ldap"
+
+// ErrLDAPLocalAccountExists 目录用户与尚未绑定的本地账号同名。不自动关联，
+// 否则目录中的同名用户就能接管本地账号（包括管理员）
+var ErrLDAPLocalAccountExists = errors.New("目录用户与未绑定目录身份的本地账号同名，拒绝自动关联")
+
+// ldapAuthProvider 目录服务绑定认证。目录用户按用户 DN 识别已绑定的账号，首次登录时可自动创建员工；
+// 组成员关系在每次登录时同步为角色
+type ldapAuthProvider struct {
+	cfg config.LDAPConfig
+}
+
+func newLDAPAuthProvider(cfg config.LDAPConfig) (*ldapAuthProvider, error) {
+	if cfg.URL == "" || cfg.BaseDN == "" {
+		return nil, errors.New("ldap 认证需要配置 url 和 base_dn")
+	}
+	if !strings.Contains(cfg.UserFilter, "%s") {
+		return nil, errors.New("ldap.user_filter 必须包含 %s")
+	}
+	return &ldapAuthProvider{cfg: cfg}, nil
+}
+
+func (p *ldapAuthProvider) Name() string { return "ldap" }
+
+func (p *ldapAuthProvider) Authenticate(username, password string) (uint, error) {
+	// 空密码会变成匿名绑定并"成功"，必须拒绝
+	if password == "" {
+		return 0, ErrInvalidCredentials
+	}
+
+	conn, err := p.dial()
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	entry, err := p.findUser(conn, username)
+	if err != nil {
+		return 0, err
+	}
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return 0, ErrInvalidCredentials
+		}
+		return 0, err
+	}
+
+	accountID, err := p.resolveAccount(username, entry)
+	if err != nil {
+		return 0, err
+	}
+	if err := p.syncRoles(accountID, entry.GetAttributeValues(p.cfg.GroupAttr)); err != nil {
+		return 0, err
+	}
+	return accountID, nil
+}
+
+func (p *ldapAuthProvider) dial() (*ldap.Conn, error) {
+	u, err := url.Parse(p.cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{ServerName: u.Hostname(), InsecureSkipVerify: p.cfg.InsecureSkipVerify}
+
+	conn, err := ldap.DialURL(p.cfg.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: p.cfg.Timeout}),
+		ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(p.cfg.Timeout)
+
+	if p.cfg.StartTLS && u.Scheme == "ldap" {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// findUser 用服务账号按用户名查找唯一的目录条目，找不到或不唯一时视为用户名错误
+func (p *ldapAuthProvider) findUser(conn *ldap.Conn, username string) (*ldap.Entry, error) {
+	if p.cfg.BindDN != "" {
+		if err := conn.Bind(p.cfg.BindDN, p.cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("ldap 服务账号绑定失败: %w", err)
+		}
+	}
+
+	req := ldap.NewSearchRequest(
+		p.cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 0, false,
+		fmt.Sprintf(p.cfg.UserFilter, ldap.EscapeFilter(username)),
+		[]string{p.cfg.EmailAttr, p.cfg.GroupAttr},
+		nil,
+	)
+	result, err := conn.Search(req)
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, err
+	}
+	if result == nil || len(result.Entries) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+	return result.Entries[0], nil
+}
+
+// resolveAccount 按用户 DN 查找已绑定的账号；首次登录且开启了自动创建时新建员工。
+// 已有同名本地账号时拒绝登录，不自动关联
+func (p *ldapAuthProvider) resolveAccount(username string, entry *ldap.Entry) (uint, error) {
+	email := entry.GetAttributeValue(p.cfg.EmailAttr)
+	accountID, found, err := findExternalAccount(ldapIdentityProvider, entry.DN, email)
+	if err != nil || found {
+		return accountID, err
+	}
+
+	_, err = dao.GetAccountByUsername(username)
+	switch {
+	case err == nil:
+		return 0, fmt.Errorf("%w: %s", ErrLDAPLocalAccountExists, username)
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return 0, err
+	case !p.cfg.JITProvisioning:
+		return 0, ErrInvalidCredentials
+	}
+	accountID, err = provisionEmployee(username, email, p.cfg.DefaultDepartmentID, "ldap", "ldap_provision")
+	if err != nil {
+		return 0, err
+	}
+
+	if err := linkExternalIdentity(ldapIdentityProvider, entry.DN, accountID, email, "ldap_link"); err != nil {
+		return 0, err
+	}
+	return accountID, nil
+}
+
+// syncRoles 按组成员关系同步映射中出现的角色，其余角色不受影响
+func (p *ldapAuthProvider) syncRoles(accountID uint, groups []string) error {
+	if len(p.cfg.GroupRoles) == 0 {
+		return nil
+	}
+
+	memberOf := make(map[string]bool, len(groups))
+	for _, g := range groups {
+		memberOf[strings.ToLower(g)] = true
+	}
+	want := map[string]bool{} // 角色名 -> 是否应当拥有
+	for _, gr := range p.cfg.GroupRoles {
+		want[gr.Role] = want[gr.Role] || memberOf[strings.ToLower(gr.Group)]
+	}
+
+	var profiles []models.BaseUser
+	if admin, err := dao.GetAdminByAccountID(accountID); err == nil {
+		profiles = append(profiles, admin)
+	}
+	if emp, err := dao.GetEmployeeByAccountID(accountID); err == nil {
+		profiles = append(profiles, emp)
+	}
+
+	for name, granted := range want {
+		role, err := dao.GetRoleByName(name)
+		if err != nil {
+			return fmt.Errorf("ldap 组映射的角色 %s 不存在", name)
+		}
+		for _, user := range profiles {
+			if granted {
+				err = dao.AssignRole(user.GetRole(), user.GetID(), role.ID)
+			} else {
+				_, err = dao.UnassignRole(user.GetRole(), user.GetID(), role.ID)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/dao"
+	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/testutil"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jimlambrt/gldap"
+	"github.com/jimlambrt/gldap/testdirectory"
+)
+
+const (
+	testLDAPPeople = "ou=people,dc=example,dc=org"
+	testLDAPHR     = "cn=hr,ou=groups,dc=example,dc=org"
+)
+
+// setupLDAP 启动进程内的目录服务，目录用户的密码均为 password，dana 属于 hr 组
+func setupLDAP(t *testing.T) (*ldapAuthProvider, *testdirectory.Directory) {
+	testutil.Setup(t)
+
+	users := testdirectory.NewUsers(t, []string{"svc", "alice", "lina"})
+	users = append(users, testdirectory.NewUsers(t, []string{"dana"}, testdirectory.WithMembersOf(t, testLDAPHR))...)
+	td := testdirectory.Start(t,
+		testdirectory.WithNoTLS(t),
+		testdirectory.WithDefaults(t, &testdirectory.Defaults{Users: users}),
+	)
+
+	p, err := newLDAPAuthProvider(config.LDAPConfig{
+		URL:          fmt.Sprintf("ldap://%s:%d", td.Host(), td.Port()),
+		Timeout:      5 * time.Second,
+		BindDN:       "cn=svc," + testLDAPPeople,
+		BindPassword: "password",
+		BaseDN:       testLDAPPeople,
+		UserFilter:   "(cn=%s)",
+		EmailAttr:    "email",
+		GroupAttr:    "memberOf",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, td
+}
+
+func ldapIdentities(t *testing.T, dn string) []models.ExternalIdentity {
+	t.Helper()
+	var ids []models.ExternalIdentity
+	if err := config.DB.Where("provider = ? AND subject = ?", ldapIdentityProvider, dn).Find(&ids).Error; err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+func TestLDAPLoginProvisionsAndLinks(t *testing.T) {
+	p, _ := setupLDAP(t)
+	p.cfg.JITProvisioning = true
+
+	accountID, err := p.Authenticate("lina", "password")
+	if err != nil {
+		t.Fatalf("目录登录失败: %v", err)
+	}
+	emp, err := dao.GetEmployeeByAccountID(accountID)
+	if err != nil {
+		t.Fatalf("首次登录应自动创建员工: %v", err)
+	}
+	if emp.Username != "lina" || emp.Email != "lina@example.com" {
+		t.Fatalf("自动创建的员工为 %s/%s", emp.Username, emp.Email)
+	}
+	ids := ldapIdentities(t, "cn=lina,"+testLDAPPeople)
+	if len(ids) != 1 || ids[0].AccountID != accountID {
+		t.Fatalf("首次登录应绑定目录身份，得到 %+v", ids)
+	}
+
+	again, err := p.Authenticate("lina", "password")
+	if err != nil || again != accountID {
+		t.Fatalf("再次登录得到账号 %d（%v），期望 %d", again, err, accountID)
+	}
+}
+
+func TestLDAPLoginRejectsWrongPassword(t *testing.T) {
+	p, _ := setupLDAP(t)
+	p.cfg.JITProvisioning = true
+
+	if _, err := p.Authenticate("lina", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("密码错误应返回 ErrInvalidCredentials，得到 %v", err)
+	}
+	if _, err := p.Authenticate("lina", ""); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("空密码应返回 ErrInvalidCredentials，得到 %v", err)
+	}
+	if ids := ldapIdentities(t, "cn=lina,"+testLDAPPeople); len(ids) != 0 {
+		t.Fatalf("登录失败时不应绑定目录身份，得到 %+v", ids)
+	}
+}
+
+func TestLDAPLoginRefusesSameNamedLocalAccount(t *testing.T) {
+	p, _ := setupLDAP(t)
+	p.cfg.JITProvisioning = true
+	local := createTestEmployee(t, "alice", "alice@local.example.com")
+
+	_, err := p.Authenticate("alice", "password")
+	if !errors.Is(err, ErrLDAPLocalAccountExists) {
+		t.Fatalf("同名本地账号不应被目录用户接管，得到 %v", err)
+	}
+	if ids := ldapIdentities(t, "cn=alice,"+testLDAPPeople); len(ids) != 0 {
+		t.Fatalf("拒绝登录时不应绑定目录身份，得到 %+v", ids)
+	}
+
+	// 管理员显式绑定后可以登录到该账号
+	if err := linkExternalIdentity(ldapIdentityProvider, "cn=alice,"+testLDAPPeople, local.GetAccountID(), "", "ldap_link"); err != nil {
+		t.Fatal(err)
+	}
+	accountID, err := p.Authenticate("alice", "password")
+	if err != nil || accountID != local.GetAccountID() {
+		t.Fatalf("显式绑定后登录得到账号 %d（%v），期望 %d", accountID, err, local.GetAccountID())
+	}
+}
+
+func TestLDAPLoginSyncsGroupRoles(t *testing.T) {
+	p, td := setupLDAP(t)
+	p.cfg.JITProvisioning = true
+	p.cfg.GroupRoles = []config.LDAPGroupRole{{Group: testLDAPHR, Role: "hr"}}
+	role := models.Role{Name: "hr"}
+	if err := config.DB.Create(&role).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	accountID, err := p.Authenticate("dana", "password")
+	if err != nil {
+		t.Fatalf("目录登录失败: %v", err)
+	}
+	emp, err := dao.GetEmployeeByAccountID(accountID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hasHR := func() bool {
+		var count int64
+		config.DB.Model(&models.UserRole{}).
+			Where("user_type = ? AND user_id = ? AND role_id = ?", models.UserTypeEmployee, emp.EmpID, role.ID).
+			Count(&count)
+		return count > 0
+	}
+	if !hasHR() {
+		t.Fatal("hr 组成员登录后应获得 hr 角色")
+	}
+
+	// 离开 hr 组后下次登录收回角色
+	td.SetUsers(append(testdirectory.NewUsers(t, []string{"svc"}),
+		gldap.NewEntry("cn=dana,"+testLDAPPeople, map[string][]string{"password": {"password"}}))...)
+	if _, err := p.Authenticate("dana", "password"); err != nil {
+		t.Fatalf("目录登录失败: %v", err)
+	}
+	if hasHR() {
+		t.Fatal("离开 hr 组后应收回 hr 角色")
+	}
+}
@@ -7,19 +7,22 @@ import (
 	"errors"
 )
 
-// 认证逻辑
-func AuthenticateUser(username, password string) (models.BaseUser, error) {
-	// 先尝试查找管理员
-	admin, err := dao.GetAdminByUsername(username)
-	if err == nil && admin.CheckPassword(password) {
-		return admin, nil
+// 认证逻辑：按配置的认证方式校验用户名密码，再加载对应档案（userType 为空时优先管理员）
+func AuthenticateUser(username, password, userType string) (models.BaseUser, error) {
+	accountID, err := authenticateAccount(username, password)
+	if err != nil {
+		return nil, err
 	}
+	return GetAccountProfile(accountID, userType)
+}
 
-	// 再尝试查找员工
-	emp, err := dao.GetEmployeeByUsername(username)
-	if err == nil && emp.CheckPassword(password) {
-		return emp, nil
+// GetUserByRole 根据令牌中的角色和ID重新加载用户（刷新令牌时确认账号仍然存在）
+func GetUserByRole(role string, id uint) (models.BaseUser, error) {
+	switch role {
+	case "admin":
+		return dao.GetAdminByID(id)
+	case "employee":
+		return dao.GetEmployeeByID(id)
 	}
-
-	return nil, errors.New("invalid credentials")
+	return nil, errors.New("unknown role")
 }
@@ -0,0 +1,151 @@
+// services/LoginGuard.go
+package services
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/models"
+	"errors"
+	"log"
+	"strings"
+	"time"
+)
+
+// 登录防爆破：按用户名和 IP 分别计数，达到阈值后临时锁定，
+// 每次锁定时长在上一次基础上翻倍（指数退避），直到上限
+const (
+	loginFailPrefix  = "login_fail:"
+	loginLockPrefix  = "login_lock:"
+	loginLevelPrefix = "login_lock_level:"
+)
+
+var ErrLoginLocked = errors.New("登录失败次数过多，请稍后再试")
+
+// LoginLockedError 处于锁定期，Remaining 为剩余锁定时长；errors.Is(err, ErrLoginLocked) 成立
+type LoginLockedError struct {
+	Remaining time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return ErrLoginLocked.Error()
+}
+
+func (e *LoginLockedError) Unwrap() error {
+	return ErrLoginLocked
+}
+
+// CheckLoginAllowed 用户名或 IP 处于锁定期时返回 *LoginLockedError；Redis 出错时返回该错误，由调用方拒绝登录
+func CheckLoginAllowed(username, ip string) error {
+	var remaining time.Duration
+	for _, subject := range loginSubjects(username, ip) {
+		ttl, err := config.Rdb.PTTL(config.Ctx, loginLockPrefix+subject).Result()
+		if err != nil {
+			return err
+		}
+		if ttl > remaining {
+			remaining = ttl
+		}
+	}
+	if remaining > 0 {
+		return &LoginLockedError{Remaining: remaining}
+	}
+	return nil
+}
+
+// RecordLoginFailure 记录一次失败，达到阈值时锁定并返回 *LoginLockedError
+func RecordLoginFailure(username, ip string) error {
+	cfg := config.Cfg.LoginProtection
+	limits := map[string]int{
+		"user:" + normalizeUsername(username): cfg.MaxUserFailures,
+		"ip:" + ip:                            cfg.MaxIPFailures,
+	}
+
+	var lockedFor time.Duration
+	for subject, limit := range limits {
+		failKey := loginFailPrefix + subject
+		count, err := config.Rdb.Incr(config.Ctx, failKey).Result()
+		if err != nil {
+			return err
+		}
+		if count == 1 {
+			config.Rdb.Expire(config.Ctx, failKey, cfg.FailureWindow)
+		}
+		if limit <= 0 || count < int64(limit) {
+			continue
+		}
+
+		duration, err := lockLoginSubject(subject)
+		if err != nil {
+			return err
+		}
+		if duration > lockedFor {
+			lockedFor = duration
+		}
+	}
+	if lockedFor > 0 {
+		return &LoginLockedError{Remaining: lockedFor}
+	}
+	return nil
+}
+
+// ResetLoginFailures 登录成功后清空该用户名的失败计数
+func ResetLoginFailures(username string) {
+	if err := config.Rdb.Del(config.Ctx, loginFailPrefix+"user:"+normalizeUsername(username)).Err(); err != nil {
+		log.Printf("登录失败计数清除失败 (%s): %v", username, err)
+	}
+}
+
+// UnlockLogin 管理员解除锁定（同时清空失败计数和退避等级）
+func UnlockLogin(username, ip string) error {
+	var keys []string
+	for _, subject := range loginSubjects(username, ip) {
+		keys = append(keys, loginLockPrefix+subject, loginFailPrefix+subject, loginLevelPrefix+subject)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return config.Rdb.Del(config.Ctx, keys...).Err()
+}
+
+func lockLoginSubject(subject string) (time.Duration, error) {
+	cfg := config.Cfg.LoginProtection
+
+	levelKey := loginLevelPrefix + subject
+	level, err := config.Rdb.Incr(config.Ctx, levelKey).Result()
+	if err != nil {
+		return 0, err
+	}
+	config.Rdb.Expire(config.Ctx, levelKey, cfg.LevelResetAfter)
+
+	duration := cfg.BaseLockout
+	for i := int64(1); i < level && duration < cfg.MaxLockout; i++ {
+		duration *= 2
+	}
+	if duration > cfg.MaxLockout {
+		duration = cfg.MaxLockout
+	}
+
+	pipe := config.Rdb.TxPipeline()
+	pipe.Set(config.Ctx, loginLockPrefix+subject, level, duration)
+	pipe.Del(config.Ctx, loginFailPrefix+subject) // 解锁后重新计数
+	if _, err := pipe.Exec(config.Ctx); err != nil {
+		return 0, err
+	}
+
+	LogOperation(0, models.ActorAnonymous, "login_locked", subject+" "+duration.String())
+	return duration, nil
+}
+
+func loginSubjects(username, ip string) []string {
+	var subjects []string
+	if username != "" {
+		subjects = append(subjects, "user:"+normalizeUsername(username))
+	}
+	if ip != "" {
+		subjects = append(subjects, "ip:"+ip)
+	}
+	return subjects
+}
+
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
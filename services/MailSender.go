@@ -0,0 +1,97 @@
+// services/MailSender.go
+package services
+
+import (
+	"EmployeeManagementDemo/config"
+	"fmt"
+	"log"
+	"mime"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MailSender 邮件发送接口，按配置选择实现
+type MailSender interface {
+	Send(to, subject, body string) error
+}
+
+// Mailer 全局邮件发送器，由 InitMailSender 初始化
+var Mailer MailSender
+
+// InitMailSender 根据 mail.driver 初始化邮件发送器
+func InitMailSender() {
+	cfg := config.Cfg.Mail
+	switch cfg.Driver {
+	case "smtp":
+		Mailer = &SMTPMailSender{
+			Host:     cfg.SMTP.Host,
+			Port:     cfg.SMTP.Port,
+			Username: cfg.SMTP.Username,
+			Password: cfg.SMTP.Password,
+			From:     cfg.From,
+		}
+	default:
+		Mailer = &LogMailSender{Path: cfg.LogFile, From: cfg.From}
+	}
+}
+
+// SMTPMailSender 通过 SMTP 发送（587 端口会自动 STARTTLS）
+type SMTPMailSender struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+func (s *SMTPMailSender) Send(to, subject, body string) error {
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+	addr := fmt.Sprintf("%s:%d", s.Host, s.Port)
+	return smtp.SendMail(addr, auth, s.From, []string{to}, buildMessage(s.From, to, subject, body))
+}
+
+// LogMailSender 把邮件写入文件或标准日志，供开发和测试环境使用
+type LogMailSender struct {
+	Path string
+	From string
+	mu   sync.Mutex
+}
+
+func (s *LogMailSender) Send(to, subject, body string) error {
+	msg := buildMessage(s.From, to, subject, body)
+	if s.Path == "" {
+		log.Printf("邮件（未实际发送）:\n%s", msg)
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(s.Path), os.ModePerm); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintf(f, "----- %s -----\n%s\n", time.Now().Format(time.RFC3339), msg)
+	return err
+}
+
+func buildMessage(from, to, subject, body string) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + to + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("UTF-8", subject) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(body)
+	return []byte(b.String())
+}
@@ -0,0 +1,197 @@
+// services/PasswordPolicyService.go
+package services
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/models"
+	"bufio"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrPasswordPolicy 新密码不符合密码策略，具体原因见 PasswordPolicyError
+var ErrPasswordPolicy = errors.New("密码不符合安全要求")
+
+// PasswordPolicyError 列出新密码未满足的全部要求，便于前端一次性提示
+type PasswordPolicyError struct {
+	Reasons []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return strings.Join(e.Reasons, "；")
+}
+
+func (e *PasswordPolicyError) Unwrap() error {
+	return ErrPasswordPolicy
+}
+
+// 内置常见弱密码（小写），可通过 password_policy.banned_file 追加
+var commonPasswords = []string{
+	"12345678", "123456789", "1234567890", "11111111", "00000000", "88888888", "66666666",
+	"password", "password1", "password123", "passw0rd", "p@ssw0rd", "qwerty123", "qwertyuiop",
+	"iloveyou", "abc12345", "abcd1234", "a1234567", "a12345678", "admin123", "admin888",
+	"qq123456", "woaini1314", "1qaz2wsx", "zaq12wsx", "asdf1234", "1q2w3e4r", "123qwe123",
+}
+
+var bannedPasswords map[string]bool
+
+// InitPasswordPolicy 加载禁用密码列表
+func InitPasswordPolicy() error {
+	banned := make(map[string]bool, len(commonPasswords))
+	for _, p := range commonPasswords {
+		banned[p] = true
+	}
+
+	if path := config.Cfg.PasswordPolicy.BannedFile; path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				banned[strings.ToLower(line)] = true
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+
+	bannedPasswords = banned
+	return nil
+}
+
+// ValidatePassword 按密码策略检查新密码，username 用于检查密码中是否包含用户名
+func ValidatePassword(password, username string) error {
+	policy := config.Cfg.PasswordPolicy
+	var reasons []string
+
+	length := utf8.RuneCountInString(password)
+	if length < policy.MinLength {
+		reasons = append(reasons, fmt.Sprintf("密码长度至少 %d 位", policy.MinLength))
+	}
+	if (policy.MaxLength > 0 && length > policy.MaxLength) || len(password) > 72 {
+		reasons = append(reasons, fmt.Sprintf("密码长度不能超过 %d 位", policy.MaxLength))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsLetter(r) && !unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if policy.RequireUpper && !upper {
+		reasons = append(reasons, "密码需包含大写字母")
+	}
+	if policy.RequireLower && !lower {
+		reasons = append(reasons, "密码需包含小写字母")
+	}
+	if policy.RequireDigit && !digit {
+		reasons = append(reasons, "密码需包含数字")
+	}
+	if policy.RequireSymbol && !symbol {
+		reasons = append(reasons, "密码需包含特殊字符")
+	}
+
+	lowered := strings.ToLower(password)
+	if bannedPasswords[lowered] {
+		reasons = append(reasons, "密码过于常见")
+	}
+	if policy.DisallowUsername && username != "" && strings.Contains(lowered, strings.ToLower(username)) {
+		reasons = append(reasons, "密码不能包含用户名")
+	}
+
+	if len(reasons) > 0 {
+		return &PasswordPolicyError{Reasons: reasons}
+	}
+	return nil
+}
+
+// ChangeAccountPassword 校验密码策略和历史密码后更新账号密码（修改密码、重置密码使用）
+func ChangeAccountPassword(tx *gorm.DB, accountID uint, newPassword string) error {
+	var acc models.Account
+	if err := tx.First(&acc, accountID).Error; err != nil {
+		return err
+	}
+	if err := ValidatePassword(newPassword, acc.Username); err != nil {
+		return err
+	}
+	if err := checkPasswordReuse(tx, &acc, newPassword); err != nil {
+		return err
+	}
+
+	hashed, err := HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	return SetAccountPassword(tx, accountID, hashed)
+}
+
+// checkPasswordReuse 新密码不能与当前密码及最近 history_size 次的密码相同
+func checkPasswordReuse(tx *gorm.DB, acc *models.Account, newPassword string) error {
+	if acc.CheckPassword(newPassword) {
+		return &PasswordPolicyError{Reasons: []string{"新密码不能与当前密码相同"}}
+	}
+
+	size := config.Cfg.PasswordPolicy.HistorySize
+	if size <= 0 {
+		return nil
+	}
+	var history []models.PasswordHistory
+	if err := tx.Where("account_id = ?", acc.ID).Order("id DESC").Limit(size).Find(&history).Error; err != nil {
+		return err
+	}
+	for _, h := range history {
+		if bcrypt.CompareHashAndPassword([]byte(h.Password), []byte(newPassword)) == nil {
+			return &PasswordPolicyError{Reasons: []string{fmt.Sprintf("不能使用最近 %d 次用过的密码", size)}}
+		}
+	}
+	return nil
+}
+
+// recordPasswordHistory 记录新密码哈希，只保留最近 history_size 条
+func recordPasswordHistory(tx *gorm.DB, accountID uint, hashedPassword string) error {
+	size := config.Cfg.PasswordPolicy.HistorySize
+	if size <= 0 || hashedPassword == "" {
+		return nil
+	}
+	if err := tx.Create(&models.PasswordHistory{AccountID: accountID, Password: hashedPassword}).Error; err != nil {
+		return err
+	}
+
+	var keep []uint
+	if err := tx.Model(&models.PasswordHistory{}).Where("account_id = ?", accountID).
+		Order("id DESC").Limit(size).Pluck("id", &keep).Error; err != nil {
+		return err
+	}
+	return tx.Where("account_id = ? AND id NOT IN ?", accountID, keep).Delete(&models.PasswordHistory{}).Error
+}
+
+// PasswordChangeRequired 开启强制定期更换时，密码超过有效期需要修改
+func PasswordChangeRequired(accountID uint) bool {
+	maxAge := config.Cfg.PasswordPolicy.MaxAge
+	if maxAge <= 0 {
+		return false
+	}
+	var acc models.Account
+	if err := config.DB.Select("id", "password_changed_at").First(&acc, accountID).Error; err != nil {
+		return false
+	}
+	return acc.PasswordChangedAt != nil && time.Since(*acc.PasswordChangedAt) > maxAge
+}
@@ -0,0 +1,142 @@
+// services/PasswordResetService.go
+package services
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/utils"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const passwordResetThrottlePrefix = "pwd_reset_throttle:"
+
+var ErrResetTokenInvalid = errors.New("重置链接无效或已过期")
+
+// RequestPasswordReset 给该邮箱下的账号（管理员、员工）发送重置邮件。
+// 邮箱不存在时同样返回成功，避免被用来探测账号
+func RequestPasswordReset(email string) error {
+	email = strings.TrimSpace(email)
+
+	// 同一邮箱 1 分钟内只发一次
+	ok, err := config.Rdb.SetNX(config.Ctx, passwordResetThrottlePrefix+strings.ToLower(email), 1, time.Minute).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+
+	var admins []models.Admin
+	if err := config.DB.Where("admin_email = ?", email).Find(&admins).Error; err != nil {
+		return err
+	}
+	var emps []models.Employee
+	if err := config.DB.Where("email = ?", email).Find(&emps).Error; err != nil {
+		return err
+	}
+
+	// 同一账号关联的多个档案只发一封邮件
+	var users []models.BaseUser
+	seen := map[uint]bool{}
+	add := func(user models.BaseUser) {
+		if user.GetAccountID() == 0 || seen[user.GetAccountID()] {
+			return
+		}
+		seen[user.GetAccountID()] = true
+		users = append(users, user)
+	}
+	for i := range admins {
+		add(&admins[i])
+	}
+	for i := range emps {
+		add(&emps[i])
+	}
+
+	for _, user := range users {
+		token, err := createResetToken(user)
+		if err != nil {
+			return err
+		}
+		if err := Mailer.Send(email, "重置密码", buildResetMail(user, token)); err != nil {
+			// 邮件失败只记录日志，不向调用方暴露账号是否存在
+			log.Printf("重置密码邮件发送失败 (%s:%d): %v", user.GetRole(), user.GetID(), err)
+		}
+	}
+	return nil
+}
+
+// ResetPassword 校验一次性令牌并设置账号新密码，成功后该账号所有已登录会话失效
+func ResetPassword(token, newPassword string) error {
+	var reset models.PasswordResetToken
+	if err := config.DB.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?",
+		utils.HashToken(token), time.Now()).First(&reset).Error; err != nil {
+		return ErrResetTokenInvalid
+	}
+
+	user, err := GetUserByRole(reset.UserType, reset.UserID)
+	if err != nil || user.GetAccountID() == 0 {
+		return ErrResetTokenInvalid
+	}
+	accountID := user.GetAccountID()
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// 条件更新保证令牌只能被使用一次
+		result := tx.Model(&models.PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL", reset.ID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrResetTokenInvalid
+		}
+
+		// 新密码不符合策略时整个事务回滚，令牌仍可再次使用
+		if err := ChangeAccountPassword(tx, accountID, newPassword); err != nil {
+			return err
+		}
+
+		// 同一用户其余未使用的令牌一并作废
+		return tx.Model(&models.PasswordResetToken{}).
+			Where("user_type = ? AND user_id = ? AND used_at IS NULL", reset.UserType, reset.UserID).
+			Update("used_at", time.Now()).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := InvalidateUserSessions(accountID); err != nil {
+		return err
+	}
+	LogOperation(accountID, models.ActorUser, "reset_password", "")
+	return nil
+}
+
+func createResetToken(user models.BaseUser) (string, error) {
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	reset := models.PasswordResetToken{
+		UserType:  user.GetRole(),
+		UserID:    user.GetID(),
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(config.Cfg.PasswordReset.TokenTTL),
+	}
+	if err := config.DB.Create(&reset).Error; err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func buildResetMail(user models.BaseUser, token string) string {
+	link := config.Cfg.PasswordReset.ResetURL + "?token=" + url.QueryEscape(token)
+	return fmt.Sprintf("%s，您好：\n\n我们收到了重置账号密码的请求，请在 %d 分钟内点击以下链接设置新密码：\n\n%s\n\n如果不是您本人操作，请忽略本邮件。\n",
+		user.GetUsername(), int(config.Cfg.PasswordReset.TokenTTL.Minutes()), link)
+}
@@ -0,0 +1,272 @@
+// services/RoleService.go
+package services
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/dao"
+	"EmployeeManagementDemo/models"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+)
+
+var (
+	ErrRoleNotFound      = errors.New("角色不存在")
+	ErrRoleBuiltIn       = errors.New("内置角色不可修改或删除")
+	ErrRoleNameExists    = errors.New("角色名称已存在")
+	ErrUnknownPermission = errors.New("存在未知的权限编码")
+	ErrUserNotFound      = errors.New("用户不存在")
+)
+
+// SeedRBAC 启动时同步权限目录和内置角色
+func SeedRBAC() error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		// 1. 同步权限目录
+		for _, p := range models.PermissionCatalog {
+			perm := models.Permission{Code: p.Code}
+			if err := tx.Where(perm).Assign(models.Permission{Description: p.Description}).
+				FirstOrCreate(&perm).Error; err != nil {
+				return fmt.Errorf("同步权限 %s 失败: %w", p.Code, err)
+			}
+		}
+		var allPerms []models.Permission
+		if err := tx.Find(&allPerms).Error; err != nil {
+			return err
+		}
+
+		// 2. super_admin 始终拥有全部权限
+		superAdmin := models.Role{Name: models.RoleSuperAdmin}
+		if err := tx.Where(superAdmin).Attrs(models.Role{Description: "超级管理员，拥有全部权限", DataScope: models.DataScopeAll, BuiltIn: true}).
+			FirstOrCreate(&superAdmin).Error; err != nil {
+			return err
+		}
+		if err := dao.ReplaceRolePermissions(tx, &superAdmin, allPerms); err != nil {
+			return err
+		}
+
+		// 3. 其余内置角色只在首次创建时写入初始权限，之后允许管理员调整
+		for name, def := range models.BuiltInRoles {
+			var role models.Role
+			err := tx.Where("name = ?", name).First(&role).Error
+			if err == nil {
+				continue
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			role = models.Role{Name: name, Description: def.Description, DataScope: def.DataScope, BuiltIn: true}
+			if err := tx.Create(&role).Error; err != nil {
+				return err
+			}
+			var perms []models.Permission
+			if err := tx.Where("code IN ?", def.Permissions).Find(&perms).Error; err != nil {
+				return err
+			}
+			if err := dao.ReplaceRolePermissions(tx, &role, perms); err != nil {
+				return err
+			}
+		}
+
+		// 4. 迁移：首次启用 RBAC 时，已有管理员全部授予 super_admin，保持原有权限不变
+		var assigned int64
+		if err := tx.Model(&models.UserRole{}).Count(&assigned).Error; err != nil {
+			return err
+		}
+		if assigned == 0 {
+			var adminIDs []uint
+			if err := tx.Model(&models.Admin{}).Pluck("admin_id", &adminIDs).Error; err != nil {
+				return err
+			}
+			for _, id := range adminIDs {
+				ur := models.UserRole{UserType: models.UserTypeAdmin, UserID: id, RoleID: superAdmin.ID}
+				if err := tx.Create(&ur).Error; err != nil {
+					return err
+				}
+			}
+			log.Printf("RBAC 初始化：%d 个管理员已授予 %s", len(adminIDs), models.RoleSuperAdmin)
+		}
+		return nil
+	})
+}
+
+// GetUserPermissions 获取用户拥有的权限集合
+func GetUserPermissions(userType string, userID uint) (map[string]bool, error) {
+	codes, err := dao.GetUserPermissionCodes(userType, userID)
+	if err != nil {
+		return nil, err
+	}
+	granted := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		granted[code] = true
+	}
+	return granted, nil
+}
+
+// GetDataScope 计算用户的数据范围：任一角色为 all 即不限部门，
+// 否则只能访问自己担任负责人的部门
+func GetDataScope(userType string, userID uint) (*models.DataScope, error) {
+	// API 密钥由权限范围限制能调用的接口，不再按部门限制数据
+	if userType == models.ActorAPIKey {
+		return &models.DataScope{All: true}, nil
+	}
+	scopes, err := dao.GetUserDataScopes(userType, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, scope := range scopes {
+		if scope == models.DataScopeAll {
+			return &models.DataScope{All: true}, nil
+		}
+	}
+
+	scope := &models.DataScope{}
+	if userType == models.UserTypeEmployee && len(scopes) > 0 {
+		if scope.DepIDs, err = dao.GetManagedDepartmentIDs(userID); err != nil {
+			return nil, err
+		}
+	}
+	return scope, nil
+}
+
+func ListRoles() ([]models.Role, error) {
+	return dao.ListRoles()
+}
+
+func ListPermissions() ([]models.Permission, error) {
+	return dao.ListPermissions()
+}
+
+func CreateRole(req models.CreateRoleRequest) (*models.Role, error) {
+	if _, err := dao.GetRoleByName(req.Name); err == nil {
+		return nil, ErrRoleNameExists
+	}
+	perms, err := resolvePermissions(req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role := models.Role{Name: req.Name, Description: req.Description, DataScope: req.DataScope}
+	if role.DataScope == "" {
+		role.DataScope = models.DataScopeAll
+	}
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&role).Error; err != nil {
+			return err
+		}
+		return dao.ReplaceRolePermissions(tx, &role, perms)
+	})
+	if err != nil {
+		return nil, err
+	}
+	role.Permissions = perms
+	return &role, nil
+}
+
+func UpdateRole(roleID uint, req models.UpdateRoleRequest) error {
+	role, err := getRole(roleID)
+	if err != nil {
+		return err
+	}
+	updates := map[string]interface{}{"description": req.Description}
+	if req.DataScope != "" {
+		if role.Name == models.RoleSuperAdmin {
+			return ErrRoleBuiltIn
+		}
+		updates["data_scope"] = req.DataScope
+	}
+	return config.DB.Model(role).Updates(updates).Error
+}
+
+func DeleteRole(roleID uint) error {
+	role, err := getRole(roleID)
+	if err != nil {
+		return err
+	}
+	if role.BuiltIn {
+		return ErrRoleBuiltIn
+	}
+	return dao.DeleteRole(role)
+}
+
+// SetRolePermissions 整体替换角色权限（super_admin 的权限由系统维护）
+func SetRolePermissions(roleID uint, codes []string) error {
+	role, err := getRole(roleID)
+	if err != nil {
+		return err
+	}
+	if role.Name == models.RoleSuperAdmin {
+		return ErrRoleBuiltIn
+	}
+	perms, err := resolvePermissions(codes)
+	if err != nil {
+		return err
+	}
+	return dao.ReplaceRolePermissions(config.DB, role, perms)
+}
+
+func ListRoleMembers(roleID uint) ([]models.UserRole, error) {
+	if _, err := getRole(roleID); err != nil {
+		return nil, err
+	}
+	return dao.ListRoleMembers(roleID)
+}
+
+func AssignRole(roleID uint, userType string, userID uint) error {
+	if _, err := getRole(roleID); err != nil {
+		return err
+	}
+	if _, err := GetUserByRole(userType, userID); err != nil {
+		return ErrUserNotFound
+	}
+	return dao.AssignRole(userType, userID, roleID)
+}
+
+func UnassignRole(roleID uint, userType string, userID uint) error {
+	removed, err := dao.UnassignRole(userType, userID, roleID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// AssignRoleByName 按角色名分配（注册等内部流程使用）
+func AssignRoleByName(name, userType string, userID uint) error {
+	role, err := dao.GetRoleByName(name)
+	if err != nil {
+		return ErrRoleNotFound
+	}
+	return dao.AssignRole(userType, userID, role.ID)
+}
+
+func getRole(roleID uint) (*models.Role, error) {
+	role, err := dao.GetRoleByID(roleID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+	return role, nil
+}
+
+// 把权限编码解析为权限记录，存在未知编码时报错
+func resolvePermissions(codes []string) ([]models.Permission, error) {
+	perms, err := dao.GetPermissionsByCodes(codes)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(perms))
+	for _, p := range perms {
+		known[p.Code] = true
+	}
+	for _, code := range codes {
+		if !known[code] {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownPermission, code)
+		}
+	}
+	return perms, nil
+}
@@ -0,0 +1,220 @@
+// services/SSOService.go
+package services
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/utils"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+	"strings"
+	"sync"
+)
+
+// OIDC 单点登录：授权码 + PKCE。state、nonce 和 code_verifier 保存在 Redis，一次性使用
+const ssoStatePrefix = "sso_state:"
+
+var (
+	ErrSSODisabled       = errors.New("未启用单点登录")
+	ErrSSOStateInvalid   = errors.New("登录状态无效或已过期，请重新发起登录")
+	ErrSSOUserNotFound   = errors.New("未找到与该企业账号对应的用户，请联系管理员")
+	ErrSSOEmailAmbiguous = errors.New("该邮箱对应多个用户，无法自动关联，请联系管理员")
+)
+
+var (
+	ssoMu       sync.Mutex
+	ssoProvider *oidc.Provider
+)
+
+// ssoClaims ID Token 中用到的声明
+type ssoClaims struct {
+	Email             string      `json:"email"`
+	EmailVerified     interface{} `json:"email_verified"` // 部分 IdP 以字符串 "true" 返回
+	Name              string      `json:"name"`
+	PreferredUsername string      `json:"preferred_username"`
+}
+
+func (c ssoClaims) emailVerified() bool {
+	return c.EmailVerified == true || c.EmailVerified == "true"
+}
+
+// ssoClient 首次使用时通过发现文档初始化 IdP，失败不缓存，便于 IdP 恢复后重试
+func ssoClient(ctx context.Context) (*oidc.Provider, *oauth2.Config, error) {
+	cfg := config.Cfg.SSO
+	if !cfg.Enabled {
+		return nil, nil, ErrSSODisabled
+	}
+
+	ssoMu.Lock()
+	defer ssoMu.Unlock()
+	if ssoProvider == nil {
+		provider, err := oidc.NewProvider(ctx, cfg.Issuer)
+		if err != nil {
+			return nil, nil, fmt.Errorf("OIDC 发现失败: %w", err)
+		}
+		ssoProvider = provider
+	}
+
+	return ssoProvider, &oauth2.Config{
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		Endpoint:     ssoProvider.Endpoint(),
+		RedirectURL:  cfg.RedirectURL,
+		Scopes:       cfg.Scopes,
+	}, nil
+}
+
+// BeginSSOLogin 生成 state/nonce/PKCE 校验码，返回 IdP 授权地址
+func BeginSSOLogin(ctx context.Context) (string, error) {
+	_, oc, err := ssoClient(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	state, err := utils.RandomToken(16)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := utils.RandomToken(16)
+	if err != nil {
+		return "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	key := ssoStatePrefix + state
+	pipe := config.Rdb.TxPipeline()
+	pipe.HSet(config.Ctx, key, map[string]interface{}{"verifier": verifier, "nonce": nonce})
+	pipe.Expire(config.Ctx, key, config.Cfg.SSO.StateTTL)
+	if _, err := pipe.Exec(config.Ctx); err != nil {
+		return "", err
+	}
+
+	return oc.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// CompleteSSOLogin 用授权码换取并校验 ID Token，返回对应的本地用户（优先管理员档案）
+func CompleteSSOLogin(ctx context.Context, state, code string) (models.BaseUser, error) {
+	if !config.Cfg.SSO.Enabled {
+		return nil, ErrSSODisabled
+	}
+
+	// state 一次性使用：取出即删除
+	key := ssoStatePrefix + state
+	pipe := config.Rdb.TxPipeline()
+	get := pipe.HGetAll(config.Ctx, key)
+	pipe.Del(config.Ctx, key)
+	if _, err := pipe.Exec(config.Ctx); err != nil {
+		return nil, err
+	}
+	saved := get.Val()
+	if len(saved) == 0 {
+		return nil, ErrSSOStateInvalid
+	}
+
+	provider, oc, err := ssoClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	token, err := oc.Exchange(ctx, code, oauth2.VerifierOption(saved["verifier"]))
+	if err != nil {
+		return nil, fmt.Errorf("授权码换取令牌失败: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("IdP 未返回 id_token")
+	}
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: oc.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("id_token 校验失败: %w", err)
+	}
+	if idToken.Nonce != saved["nonce"] {
+		return nil, ErrSSOStateInvalid
+	}
+
+	var claims ssoClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+
+	accountID, err := resolveSSOAccount(idToken.Issuer, idToken.Subject, claims)
+	if err != nil {
+		return nil, err
+	}
+	user, err := GetAccountProfile(accountID, "")
+	if errors.Is(err, ErrInvalidCredentials) {
+		return nil, ErrSSOUserNotFound
+	}
+	return user, err
+}
+
+// resolveSSOAccount 按已绑定的外部身份查找账号；首次登录时按邮箱匹配已有用户，
+// 仍找不到且开启了自动创建时新建员工，最后记录绑定关系
+func resolveSSOAccount(provider, subject string, claims ssoClaims) (uint, error) {
+	accountID, found, err := findExternalAccount(provider, subject, claims.Email)
+	if err != nil || found {
+		return accountID, err
+	}
+
+	accountID, err = matchAccountByEmail(claims)
+	if err != nil {
+		return 0, err
+	}
+	if accountID == 0 {
+		if !config.Cfg.SSO.JITProvisioning {
+			return 0, ErrSSOUserNotFound
+		}
+		base := claims.PreferredUsername
+		if base == "" {
+			base, _, _ = strings.Cut(claims.Email, "@")
+		}
+		if base == "" {
+			base = "sso_user"
+		}
+		accountID, err = provisionEmployee(base, claims.Email, config.Cfg.SSO.DefaultDepartmentID, "sso", "sso_provision")
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := linkExternalIdentity(provider, subject, accountID, claims.Email, "sso_link"); err != nil {
+		return 0, err
+	}
+	return accountID, nil
+}
+
+// matchAccountByEmail 按邮箱匹配管理员/员工，邮箱对应多个账号时拒绝自动关联
+func matchAccountByEmail(claims ssoClaims) (uint, error) {
+	if claims.Email == "" || (config.Cfg.SSO.RequireVerifiedEmail && !claims.emailVerified()) {
+		return 0, nil
+	}
+
+	var ids []uint
+	if err := config.DB.Model(&models.Admin{}).
+		Where("admin_email = ? AND account_id IS NOT NULL", claims.Email).
+		Pluck("account_id", &ids).Error; err != nil {
+		return 0, err
+	}
+	var empIDs []uint
+	if err := config.DB.Model(&models.Employee{}).
+		Where("email = ? AND account_id IS NOT NULL", claims.Email).
+		Pluck("account_id", &empIDs).Error; err != nil {
+		return 0, err
+	}
+
+	unique := map[uint]bool{}
+	for _, id := range append(ids, empIDs...) {
+		unique[id] = true
+	}
+	switch len(unique) {
+	case 0:
+		return 0, nil
+	case 1:
+		for id := range unique {
+			return id, nil
+		}
+	}
+	return 0, ErrSSOEmailAmbiguous
+}
@@ -0,0 +1,299 @@
+package services
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/testutil"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"gorm.io/gorm"
+)
+
+// mockIdP 最小的 OIDC 提供方：发现文档、JWKS 和令牌端点，授权步骤由测试直接调用 authorize 完成
+type mockIdP struct {
+	t      *testing.T
+	srv    *httptest.Server
+	signer jose.Signer
+
+	mu    sync.Mutex
+	codes map[string]mockGrant
+}
+
+type mockGrant struct {
+	challenge string
+	idToken   string
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: jose.RS256,
+		Key:       jose.JSONWebKey{Key: key, KeyID: "test", Algorithm: string(jose.RS256)},
+	}, (&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idp := &mockIdP{t: t, signer: signer, codes: map[string]mockGrant{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{
+			"issuer":                                idp.srv.URL,
+			"authorization_endpoint":                idp.srv.URL + "/authorize",
+			"token_endpoint":                        idp.srv.URL + "/token",
+			"jwks_uri":                              idp.srv.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &key.PublicKey, KeyID: "test", Algorithm: string(jose.RS256), Use: "sig"},
+		}})
+	})
+	mux.HandleFunc("/token", idp.token)
+	idp.srv = httptest.NewServer(mux)
+	t.Cleanup(idp.srv.Close)
+	return idp
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// token 校验授权码和 PKCE 校验码，返回授权时签发的 id_token
+func (idp *mockIdP) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	idp.mu.Lock()
+	grant, ok := idp.codes[r.PostForm.Get("code")]
+	delete(idp.codes, r.PostForm.Get("code"))
+	idp.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != grant.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		writeJSON(w, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     grant.idToken,
+	})
+}
+
+// authorize 模拟用户在 IdP 登录：按授权地址中的参数签发 id_token，返回回调收到的 state 和 code。
+// claims 中的字段覆盖默认声明，可用来伪造错误的 nonce
+func (idp *mockIdP) authorize(authURL string, claims map[string]interface{}) (string, string) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		idp.t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("client_id") != config.Cfg.SSO.ClientID || q.Get("code_challenge_method") != "S256" {
+		idp.t.Fatalf("授权参数不正确: %s", authURL)
+	}
+
+	now := time.Now()
+	all := map[string]interface{}{
+		"iss":   idp.srv.URL,
+		"aud":   config.Cfg.SSO.ClientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"nonce": q.Get("nonce"),
+	}
+	for k, v := range claims {
+		all[k] = v
+	}
+	payload, _ := json.Marshal(all)
+	signed, err := idp.signer.Sign(payload)
+	if err != nil {
+		idp.t.Fatal(err)
+	}
+	raw, err := signed.CompactSerialize()
+	if err != nil {
+		idp.t.Fatal(err)
+	}
+
+	code, _ := randomCode()
+	idp.mu.Lock()
+	idp.codes[code] = mockGrant{challenge: q.Get("code_challenge"), idToken: raw}
+	idp.mu.Unlock()
+	return q.Get("state"), code
+}
+
+func randomCode() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b), err
+}
+
+func setupSSO(t *testing.T) *mockIdP {
+	testutil.Setup(t)
+	idp := newMockIdP(t)
+	config.Cfg.SSO = config.SSOConfig{
+		Enabled:              true,
+		Issuer:               idp.srv.URL,
+		ClientID:             "ems",
+		ClientSecret:         "ems-secret",
+		RedirectURL:          "http://localhost/sso/callback",
+		Scopes:               []string{"openid", "email", "profile"},
+		StateTTL:             10 * time.Minute,
+		RequireVerifiedEmail: true,
+	}
+	resetSSOProvider := func() {
+		ssoMu.Lock()
+		ssoProvider = nil
+		ssoMu.Unlock()
+	}
+	resetSSOProvider()
+	t.Cleanup(resetSSOProvider)
+	return idp
+}
+
+func createTestEmployee(t *testing.T, username, email string) *models.Employee {
+	t.Helper()
+	emp := &models.Employee{Username: username, Email: email, Phone: "13800000000", Status: "在职"}
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return CreateEmployeeWithTx(tx, emp, "")
+	}); err != nil {
+		t.Fatalf("创建员工失败: %v", err)
+	}
+	return emp
+}
+
+func ssoLogin(t *testing.T, idp *mockIdP, claims map[string]interface{}) (models.BaseUser, error) {
+	t.Helper()
+	ctx := context.Background()
+	authURL, err := BeginSSOLogin(ctx)
+	if err != nil {
+		t.Fatalf("发起单点登录失败: %v", err)
+	}
+	state, code := idp.authorize(authURL, claims)
+	return CompleteSSOLogin(ctx, state, code)
+}
+
+func TestSSOLoginLinksExistingEmployeeOnFirstLogin(t *testing.T) {
+	idp := setupSSO(t)
+	emp := createTestEmployee(t, "alice", "alice@example.com")
+
+	user, err := ssoLogin(t, idp, map[string]interface{}{
+		"sub": "alice-sub", "email": "alice@example.com", "email_verified": true,
+	})
+	if err != nil {
+		t.Fatalf("单点登录失败: %v", err)
+	}
+	if user.GetAccountID() != emp.GetAccountID() {
+		t.Fatalf("登录到了账号 %d，期望 %d", user.GetAccountID(), emp.GetAccountID())
+	}
+
+	var ext models.ExternalIdentity
+	if err := config.DB.Where("provider = ? AND subject = ?", idp.srv.URL, "alice-sub").First(&ext).Error; err != nil {
+		t.Fatalf("首次登录没有记录外部身份: %v", err)
+	}
+	if ext.AccountID != emp.GetAccountID() {
+		t.Fatalf("外部身份绑定到账号 %d，期望 %d", ext.AccountID, emp.GetAccountID())
+	}
+
+	// 绑定后按 subject 识别，不再依赖邮箱
+	user, err = ssoLogin(t, idp, map[string]interface{}{"sub": "alice-sub", "email": "alice@new.example.com"})
+	if err != nil {
+		t.Fatalf("再次登录失败: %v", err)
+	}
+	if user.GetAccountID() != emp.GetAccountID() {
+		t.Fatalf("再次登录到了账号 %d，期望 %d", user.GetAccountID(), emp.GetAccountID())
+	}
+}
+
+func TestSSOLoginRequiresVerifiedEmailToLink(t *testing.T) {
+	idp := setupSSO(t)
+	createTestEmployee(t, "bob", "bob@example.com")
+
+	_, err := ssoLogin(t, idp, map[string]interface{}{"sub": "bob-sub", "email": "bob@example.com"})
+	if !errors.Is(err, ErrSSOUserNotFound) {
+		t.Fatalf("未验证的邮箱不应关联已有用户，得到 %v", err)
+	}
+}
+
+func TestSSOLoginProvisionsEmployee(t *testing.T) {
+	idp := setupSSO(t)
+	config.Cfg.SSO.JITProvisioning = true
+	createTestEmployee(t, "carol", "carol@old.example.com")
+
+	user, err := ssoLogin(t, idp, map[string]interface{}{
+		"sub": "carol-sub", "email": "carol@example.com", "email_verified": true, "preferred_username": "carol",
+	})
+	if err != nil {
+		t.Fatalf("单点登录失败: %v", err)
+	}
+	if user.GetUsername() != "carol_sso" {
+		t.Fatalf("自动创建的用户名为 %q，期望重名时改为 carol_sso", user.GetUsername())
+	}
+	var count int64
+	config.DB.Model(&models.ExternalIdentity{}).Where("account_id = ?", user.GetAccountID()).Count(&count)
+	if count != 1 {
+		t.Fatalf("自动创建的用户应绑定外部身份，得到 %d 条", count)
+	}
+}
+
+func TestSSOLoginRejectsNonceMismatch(t *testing.T) {
+	idp := setupSSO(t)
+	createTestEmployee(t, "dave", "dave@example.com")
+
+	_, err := ssoLogin(t, idp, map[string]interface{}{
+		"sub": "dave-sub", "email": "dave@example.com", "email_verified": true, "nonce": "forged",
+	})
+	if !errors.Is(err, ErrSSOStateInvalid) {
+		t.Fatalf("nonce 不一致应拒绝登录，得到 %v", err)
+	}
+	var count int64
+	config.DB.Model(&models.ExternalIdentity{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("拒绝登录时不应记录外部身份，得到 %d 条", count)
+	}
+}
+
+func TestSSOLoginRejectsBadState(t *testing.T) {
+	idp := setupSSO(t)
+	createTestEmployee(t, "erin", "erin@example.com")
+	ctx := context.Background()
+
+	authURL, err := BeginSSOLogin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, code := idp.authorize(authURL, map[string]interface{}{
+		"sub": "erin-sub", "email": "erin@example.com", "email_verified": true,
+	})
+
+	if _, err := CompleteSSOLogin(ctx, "unknown-state", code); !errors.Is(err, ErrSSOStateInvalid) {
+		t.Fatalf("未知的 state 应拒绝登录，得到 %v", err)
+	}
+	if _, err := CompleteSSOLogin(ctx, state, code); err != nil {
+		t.Fatalf("单点登录失败: %v", err)
+	}
+	// state 一次性使用
+	if _, err := CompleteSSOLogin(ctx, state, code); !errors.Is(err, ErrSSOStateInvalid) {
+		t.Fatalf("重复使用的 state 应拒绝登录，得到 %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+// services/SessionService.go
+package services
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// 账号的会话索引：user_sessions:<主体ID> -> 会话ID集合。
+// 会话过期后索引中可能残留ID，列出时顺便清理
+const userSessionsPrefix = "user_sessions:"
+
+// 访问令牌每次请求都会刷新会话活跃时间，间隔小于该值时不重复写入
+const sessionTouchInterval = time.Minute
+
+// SessionRevokedChannel 会话吊销通知（Redis 发布订阅），WebSocket 据此断开被踢出或注销的连接
+const SessionRevokedChannel = "session_revoked"
+
+// SessionRevokedEvent 会话吊销通知，SessionID 为空表示账号的全部会话
+type SessionRevokedEvent struct {
+	PrincipalID uint   `json:"principal_id"`
+	SessionID   string `json:"session_id,omitempty"`
+}
+
+var ErrSessionNotFound = errors.New("会话不存在或已失效")
+
+func userSessionsKey(principalID uint) string {
+	return fmt.Sprintf("%s%d", userSessionsPrefix, principalID)
+}
+
+// TouchSession 检查会话是否仍然有效，并更新最近活跃时间
+func TouchSession(sessionID string) (bool, error) {
+	key := refreshFamilyPrefix + sessionID
+	lastSeen, err := config.Rdb.HGet(config.Ctx, key, "last_seen").Int64()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if now := time.Now(); now.Sub(time.Unix(lastSeen, 0)) >= sessionTouchInterval {
+		config.Rdb.HSet(config.Ctx, key, "last_seen", now.Unix())
+	}
+	return true, nil
+}
+
+// ListUserSessions 列出账号当前所有有效会话，最近活跃的在前
+func ListUserSessions(principalID uint) ([]models.SessionDTO, error) {
+	indexKey := userSessionsKey(principalID)
+	ids, err := config.Rdb.SMembers(config.Ctx, indexKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]models.SessionDTO, 0, len(ids))
+	for _, id := range ids {
+		family, err := config.Rdb.HGetAll(config.Ctx, refreshFamilyPrefix+id).Result()
+		if err != nil {
+			return nil, err
+		}
+		if len(family) == 0 {
+			config.Rdb.SRem(config.Ctx, indexKey, id)
+			continue
+		}
+		createdAt, _ := strconv.ParseInt(family["created_at"], 10, 64)
+		lastSeen, _ := strconv.ParseInt(family["last_seen"], 10, 64)
+		sessions = append(sessions, models.SessionDTO{
+			ID:        id,
+			IP:        family["ip"],
+			UserAgent: family["user_agent"],
+			CreatedAt: time.Unix(createdAt, 0),
+			LastSeen:  time.Unix(lastSeen, 0),
+		})
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].LastSeen.After(sessions[j].LastSeen) })
+	return sessions, nil
+}
+
+// RevokeSession 吊销单个会话（注销时调用），该会话的访问令牌和刷新令牌立即失效
+func RevokeSession(sessionID string) error {
+	if sessionID == "" {
+		return nil
+	}
+	key := refreshFamilyPrefix + sessionID
+	owner, err := config.Rdb.HGet(config.Ctx, key, "principal_id").Uint64()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	if err := config.Rdb.Del(config.Ctx, key).Err(); err != nil {
+		return err
+	}
+	if owner != 0 {
+		config.Rdb.SRem(config.Ctx, userSessionsKey(uint(owner)), sessionID)
+		publishSessionRevoked(uint(owner), sessionID)
+	}
+	return nil
+}
+
+// RevokeUserSession 吊销指定账号的某个会话，会话不属于该账号时视为不存在
+func RevokeUserSession(principalID uint, sessionID string) error {
+	owner, err := config.Rdb.HGet(config.Ctx, refreshFamilyPrefix+sessionID, "principal_id").Uint64()
+	if err == redis.Nil || (err == nil && uint(owner) != principalID) {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return RevokeSession(sessionID)
+}
+
+// RevokeUserSessions 吊销账号的全部会话（exceptID 非空时保留该会话），返回吊销数量
+func RevokeUserSessions(principalID uint, exceptID string) (int, error) {
+	indexKey := userSessionsKey(principalID)
+	ids, err := config.Rdb.SMembers(config.Ctx, indexKey).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	revoked := 0
+	for _, id := range ids {
+		if id == exceptID {
+			continue
+		}
+		n, err := config.Rdb.Del(config.Ctx, refreshFamilyPrefix+id).Result()
+		if err != nil {
+			return revoked, err
+		}
+		config.Rdb.SRem(config.Ctx, indexKey, id)
+		if n > 0 {
+			publishSessionRevoked(principalID, id)
+		}
+		revoked += int(n)
+	}
+	return revoked, nil
+}
+
+// publishSessionRevoked 通知各实例断开对应的 WebSocket 连接，发送失败只记录日志
+func publishSessionRevoked(principalID uint, sessionID string) {
+	data, _ := json.Marshal(SessionRevokedEvent{PrincipalID: principalID, SessionID: sessionID})
+	if err := config.Rdb.Publish(config.Ctx, SessionRevokedChannel, data).Err(); err != nil {
+		log.Printf("会话吊销通知发送失败: %v", err)
+	}
+}
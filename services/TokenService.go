@@ -0,0 +1,249 @@
+// services/TokenService.go
+package services
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/utils"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 刷新令牌族：一次登录对应一个族（即一个会话，族ID就是访问令牌的 jti），族内每次刷新都会
+// 轮换出新的刷新令牌，Redis 中只保存当前有效令牌的摘要。旧令牌被再次使用即视为泄露，整个族立即吊销。
+const refreshFamilyPrefix = "refresh_family:"
+
+// SessionMeta 登录/刷新时记录的客户端信息
+type SessionMeta struct {
+	IP        string
+	UserAgent string
+}
+
+var (
+	ErrRefreshTokenInvalid = errors.New("刷新令牌无效或已过期")
+	ErrRefreshTokenReused  = errors.New("刷新令牌被重复使用，会话已吊销")
+
+	ErrAccessTokenInvalid = errors.New("令牌无效")
+	ErrAccessTokenRevoked = errors.New("令牌已失效")
+	ErrUserKicked         = errors.New("用户已被踢出")
+	ErrSessionRevoked     = errors.New("会话已失效")
+)
+
+// 比较并轮换当前令牌，返回 1 成功，0 族不存在，-1 检测到重放（族已删除）
+var rotateRefreshScript = redis.NewScript(`
+local cur = redis.call('HGET', KEYS[1], 'current')
+if not cur then
+	return 0
+end
+if cur ~= ARGV[1] then
+	redis.call('DEL', KEYS[1])
+	return -1
+end
+redis.call('HSET', KEYS[1], 'current', ARGV[2])
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+return 1
+`)
+
+// IssueTokenPair 登录成功后创建新的令牌族（会话），并签发访问令牌和刷新令牌
+func IssueTokenPair(user models.BaseUser, meta SessionMeta) (*models.TokenDTO, error) {
+	if user.GetAccountID() == 0 {
+		return nil, errors.New("用户未关联账号")
+	}
+	familyID, err := utils.RandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := newRefreshToken(familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	key := refreshFamilyPrefix + familyID
+	indexKey := userSessionsKey(user.GetAccountID())
+	now := time.Now().Unix()
+	pipe := config.Rdb.TxPipeline()
+	pipe.HSet(config.Ctx, key, map[string]interface{}{
+		"principal_id": user.GetAccountID(),
+		"user_id":      user.GetID(),
+		"role":         user.GetRole(),
+		"current":      utils.HashToken(refreshToken),
+		"created_at":   now,
+		"last_seen":    now,
+		"ip":           meta.IP,
+		"user_agent":   truncateUserAgent(meta.UserAgent),
+	})
+	pipe.Expire(config.Ctx, key, config.Cfg.JWT.RefreshTTL)
+	pipe.SAdd(config.Ctx, indexKey, familyID)
+	pipe.Expire(config.Ctx, indexKey, config.Cfg.JWT.SessionMaxAge)
+	if _, err := pipe.Exec(config.Ctx); err != nil {
+		return nil, fmt.Errorf("保存令牌族失败: %w", err)
+	}
+
+	return buildTokenDTO(user, familyID, refreshToken)
+}
+
+// RefreshTokenPair 用刷新令牌换取新的令牌对，旧刷新令牌随即失效
+func RefreshTokenPair(refreshToken string, meta SessionMeta) (*models.TokenDTO, error) {
+	familyID, _, ok := strings.Cut(refreshToken, ".")
+	if !ok || familyID == "" {
+		return nil, ErrRefreshTokenInvalid
+	}
+	key := refreshFamilyPrefix + familyID
+
+	family, err := config.Rdb.HGetAll(config.Ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(family) == 0 {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	userID, _ := strconv.ParseUint(family["user_id"], 10, 64)
+	createdAt, _ := strconv.ParseInt(family["created_at"], 10, 64)
+
+	// 会话超过最长存活时间，必须重新登录
+	remaining := time.Until(time.Unix(createdAt, 0).Add(config.Cfg.JWT.SessionMaxAge))
+	if remaining <= 0 {
+		config.Rdb.Del(config.Ctx, key)
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	user, err := GetUserByRole(family["role"], uint(userID))
+	if err != nil || user.GetAccountID() == 0 {
+		config.Rdb.Del(config.Ctx, key)
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	// 账号在会话建立后被踢出，同样吊销
+	if kickTime, err := config.Rdb.Get(config.Ctx, userInvalidKey(user.GetAccountID())).Int64(); err == nil && createdAt < kickTime {
+		config.Rdb.Del(config.Ctx, key)
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	newToken, err := newRefreshToken(familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 滑动续期：每次刷新顺延空闲有效期，但不超过会话最长存活时间
+	ttl := config.Cfg.JWT.RefreshTTL
+	if remaining < ttl {
+		ttl = remaining
+	}
+	res, err := rotateRefreshScript.Run(config.Ctx, config.Rdb, []string{key},
+		utils.HashToken(refreshToken), utils.HashToken(newToken), ttl.Milliseconds()).Int()
+	if err != nil {
+		return nil, err
+	}
+	switch res {
+	case 0:
+		return nil, ErrRefreshTokenInvalid
+	case -1:
+		publishSessionRevoked(user.GetAccountID(), familyID)
+		LogOperation(user.GetAccountID(), models.ActorUser, "refresh_token_reuse", familyID)
+		return nil, ErrRefreshTokenReused
+	}
+
+	// 记录最近活跃时间和客户端信息
+	config.Rdb.HSet(config.Ctx, key, map[string]interface{}{
+		"last_seen":  time.Now().Unix(),
+		"ip":         meta.IP,
+		"user_agent": truncateUserAgent(meta.UserAgent),
+	})
+
+	return buildTokenDTO(user, familyID, newToken)
+}
+
+// InvalidateUserSessions 让账号此前签发的所有令牌失效（踢人、重置密码共用）
+func InvalidateUserSessions(principalID uint) error {
+	if err := config.Rdb.Set(config.Ctx, userInvalidKey(principalID), time.Now().Unix(), 0).Err(); err != nil {
+		return err
+	}
+	publishSessionRevoked(principalID, "")
+	return nil
+}
+
+// UserInvalidatedAt 账号最近一次被踢出的时间戳，没有记录时返回 0
+func UserInvalidatedAt(principalID uint) (int64, error) {
+	ts, err := config.Rdb.Get(config.Ctx, userInvalidKey(principalID)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return ts, err
+}
+
+// CheckAccessToken 访问令牌验签通过后的状态检查：是否已注销、账号是否被踢出、所属会话是否仍然有效。
+// HTTP 中间件和 WebSocket 握手共用
+func CheckAccessToken(tokenString string, claims *utils.Claims) error {
+	// 检查 Token 黑名单
+	exists, err := config.Rdb.Exists(config.Ctx, "jwt_blacklist:"+tokenString).Result()
+	if err != nil {
+		return err
+	}
+	if exists == 1 {
+		return ErrAccessTokenRevoked
+	}
+
+	// 检查账号是否被踢出（按主体ID，管理员和员工不再互相影响）
+	if claims.PrincipalID == 0 {
+		return ErrAccessTokenInvalid
+	}
+	kickTime, err := UserInvalidatedAt(claims.PrincipalID)
+	if err != nil {
+		return err
+	}
+	if claims.IssuedAt.Unix() < kickTime {
+		return ErrUserKicked
+	}
+
+	// 会话必须仍然存在（会话可被用户本人或管理员单独吊销）
+	if claims.ID == "" {
+		return ErrAccessTokenInvalid
+	}
+	alive, err := TouchSession(claims.ID)
+	if err != nil {
+		return err
+	}
+	if !alive {
+		return ErrSessionRevoked
+	}
+	return nil
+}
+
+func userInvalidKey(principalID uint) string {
+	return fmt.Sprintf("user_invalid:%d", principalID)
+}
+
+func newRefreshToken(familyID string) (string, error) {
+	secret, err := utils.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	return familyID + "." + secret, nil
+}
+
+func truncateUserAgent(ua string) string {
+	if len(ua) > 255 {
+		return ua[:255]
+	}
+	return ua
+}
+
+// buildTokenDTO 签发访问令牌。密码已过期时令牌带上标记，修改密码后刷新令牌即可解除
+func buildTokenDTO(user models.BaseUser, familyID, refreshToken string) (*models.TokenDTO, error) {
+	mustChange := PasswordChangeRequired(user.GetAccountID())
+	accessToken, err := utils.GenerateJWT(user, familyID, mustChange)
+	if err != nil {
+		return nil, err
+	}
+	return &models.TokenDTO{
+		Token:              accessToken,
+		RefreshToken:       refreshToken,
+		ExpiresIn:          int64(config.Cfg.JWT.AccessTTL.Seconds()),
+		MustChangePassword: mustChange,
+	}, nil
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/testutil"
+	"EmployeeManagementDemo/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setupTokens(t *testing.T) {
+	testutil.Setup(t)
+	config.Cfg.JWT = config.JWTConfig{
+		AccessTTL:     15 * time.Minute,
+		RefreshTTL:    24 * time.Hour,
+		SessionMaxAge: 720 * time.Hour,
+		Secret:        strings.Repeat("k", 32),
+	}
+	if err := utils.InitJWTKeys(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTokensMarkExpiredPassword(t *testing.T) {
+	setupTokens(t)
+	config.Cfg.PasswordPolicy.MaxAge = 24 * time.Hour
+	emp := createTestEmployee(t, "alice", "alice@example.com")
+	setChangedAt := func(at time.Time) {
+		if err := config.DB.Model(&models.Account{}).Where("id = ?", emp.GetAccountID()).
+			Update("password_changed_at", at).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	setChangedAt(time.Now().Add(-48 * time.Hour))
+	tokens, err := IssueTokenPair(emp, SessionMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := utils.ParseJWT(tokens.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tokens.MustChangePassword || !claims.MustChangePassword {
+		t.Fatal("密码过期时签发的令牌应带上必须修改密码的标记")
+	}
+
+	// 修改密码后刷新令牌即解除
+	setChangedAt(time.Now())
+	tokens, err = RefreshTokenPair(tokens.RefreshToken, SessionMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err = utils.ParseJWT(tokens.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokens.MustChangePassword || claims.MustChangePassword {
+		t.Fatal("修改密码后刷新的令牌不应再带标记")
+	}
+}
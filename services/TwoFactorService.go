@@ -0,0 +1,268 @@
+// services/TwoFactorService.go
+package services
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/utils"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	mfaChallengePrefix     = "mfa_challenge:"
+	maxChallengeAttempts   = 5
+	recoveryCodeCount      = 10
+	recoveryCodeHalfLength = 5
+)
+
+var (
+	ErrTwoFactorNotEnrolled   = errors.New("尚未开启两步验证")
+	ErrTwoFactorAlreadyOn     = errors.New("两步验证已开启")
+	ErrTwoFactorSetupMissing  = errors.New("请先获取两步验证密钥")
+	ErrTwoFactorCodeInvalid   = errors.New("验证码错误")
+	ErrTwoFactorRequired      = errors.New("当前角色必须开启两步验证")
+	ErrLoginChallengeInvalid  = errors.New("登录挑战已失效，请重新登录")
+	ErrLoginChallengeNoEnroll = errors.New("当前登录无需绑定两步验证")
+)
+
+// LoginChallenge 密码校验通过、等待两步验证的登录
+type LoginChallenge struct {
+	Key                string
+	UserID             uint
+	Role               string
+	EnrollmentRequired bool
+}
+
+// TwoFactorRequired 角色是否被配置为强制两步验证
+func TwoFactorRequired(role string) bool {
+	for _, r := range config.Cfg.TwoFactor.RequiredRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTwoFactorEnabled 用户是否已开启两步验证
+func IsTwoFactorEnabled(userType string, userID uint) (bool, error) {
+	var count int64
+	err := config.DB.Model(&models.TwoFactorAuth{}).
+		Where("user_type = ? AND user_id = ? AND enabled = ?", userType, userID, true).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// BeginTwoFactorEnrollment 生成（或重新生成）待确认的密钥
+func BeginTwoFactorEnrollment(user models.BaseUser) (*models.TwoFactorSetupDTO, error) {
+	var tfa models.TwoFactorAuth
+	err := config.DB.Where("user_type = ? AND user_id = ?", user.GetRole(), user.GetID()).First(&tfa).Error
+	if err == nil && tfa.Enabled {
+		return nil, ErrTwoFactorAlreadyOn
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	tfa.UserType = user.GetRole()
+	tfa.UserID = user.GetID()
+	tfa.Secret = secret
+	tfa.LastUsedStep = 0
+	if err := config.DB.Save(&tfa).Error; err != nil {
+		return nil, err
+	}
+
+	return &models.TwoFactorSetupDTO{
+		Secret:     secret,
+		OtpauthURI: utils.TOTPURI(config.Cfg.TwoFactor.Issuer, user.GetUsername(), secret),
+	}, nil
+}
+
+// ConfirmTwoFactorEnrollment 校验首个验证码后正式开启，并返回恢复码（仅此一次明文返回）
+func ConfirmTwoFactorEnrollment(userType string, userID uint, code string) ([]string, error) {
+	var tfa models.TwoFactorAuth
+	if err := config.DB.Where("user_type = ? AND user_id = ?", userType, userID).First(&tfa).Error; err != nil {
+		return nil, ErrTwoFactorSetupMissing
+	}
+	if tfa.Enabled {
+		return nil, ErrTwoFactorAlreadyOn
+	}
+	step, ok := utils.VerifyTOTP(tfa.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrTwoFactorCodeInvalid
+	}
+
+	var codes []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(&tfa).Updates(map[string]interface{}{
+			"enabled":        true,
+			"enabled_at":     &now,
+			"last_used_step": step,
+		}).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = replaceRecoveryCodes(tx, userType, userID)
+		return err
+	})
+	return codes, err
+}
+
+// DisableTwoFactor 关闭两步验证（需提供有效验证码或恢复码）
+func DisableTwoFactor(userType string, userID uint, code string) error {
+	if TwoFactorRequired(userType) {
+		return ErrTwoFactorRequired
+	}
+	if err := VerifyTwoFactorCode(userType, userID, code); err != nil {
+		return err
+	}
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_type = ? AND user_id = ?", userType, userID).Delete(&models.TwoFactorAuth{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_type = ? AND user_id = ?", userType, userID).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，旧恢复码全部作废
+func RegenerateRecoveryCodes(userType string, userID uint, code string) ([]string, error) {
+	if err := VerifyTwoFactorCode(userType, userID, code); err != nil {
+		return nil, err
+	}
+	var codes []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, userType, userID)
+		return err
+	})
+	return codes, err
+}
+
+// VerifyTwoFactorCode 校验 TOTP 验证码，utils.TOTPDigits 位数字以外的输入按恢复码处理
+func VerifyTwoFactorCode(userType string, userID uint, code string) error {
+	var tfa models.TwoFactorAuth
+	if err := config.DB.Where("user_type = ? AND user_id = ? AND enabled = ?", userType, userID, true).First(&tfa).Error; err != nil {
+		return ErrTwoFactorNotEnrolled
+	}
+
+	code = strings.TrimSpace(code)
+	if _, err := strconv.Atoi(code); err == nil && len(code) == utils.TOTPDigits {
+		step, ok := utils.VerifyTOTP(tfa.Secret, code, time.Now())
+		if !ok {
+			return ErrTwoFactorCodeInvalid
+		}
+		// 条件更新保证同一时间步的验证码只能用一次
+		result := config.DB.Model(&models.TwoFactorAuth{}).
+			Where("id = ? AND last_used_step < ?", tfa.ID, step).
+			Update("last_used_step", step)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTwoFactorCodeInvalid
+		}
+		return nil
+	}
+
+	result := config.DB.Model(&models.RecoveryCode{}).
+		Where("user_type = ? AND user_id = ? AND code_hash = ? AND used_at IS NULL",
+			userType, userID, utils.HashToken(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTwoFactorCodeInvalid
+	}
+	return nil
+}
+
+// CreateLoginChallenge 密码校验通过后创建短期挑战令牌
+func CreateLoginChallenge(user models.BaseUser, enrollmentRequired bool) (string, error) {
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	key := mfaChallengePrefix + utils.HashToken(token)
+	pipe := config.Rdb.TxPipeline()
+	pipe.HSet(config.Ctx, key, map[string]interface{}{
+		"user_id":  user.GetID(),
+		"role":     user.GetRole(),
+		"enroll":   enrollmentRequired,
+		"attempts": 0,
+	})
+	pipe.Expire(config.Ctx, key, config.Cfg.TwoFactor.ChallengeTTL)
+	if _, err := pipe.Exec(config.Ctx); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// GetLoginChallenge 读取挑战，并计一次尝试；超过次数直接作废
+func GetLoginChallenge(token string) (*LoginChallenge, error) {
+	key := mfaChallengePrefix + utils.HashToken(token)
+	data, err := config.Rdb.HGetAll(config.Ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	if data["role"] == "" {
+		return nil, ErrLoginChallengeInvalid
+	}
+	attempts, err := config.Rdb.HIncrBy(config.Ctx, key, "attempts", 1).Result()
+	if err != nil {
+		return nil, err
+	}
+	if attempts > maxChallengeAttempts {
+		config.Rdb.Del(config.Ctx, key)
+		return nil, ErrLoginChallengeInvalid
+	}
+
+	userID, _ := strconv.ParseUint(data["user_id"], 10, 64)
+	enroll, _ := strconv.ParseBool(data["enroll"])
+	return &LoginChallenge{
+		Key:                key,
+		UserID:             uint(userID),
+		Role:               data["role"],
+		EnrollmentRequired: enroll,
+	}, nil
+}
+
+// CompleteLoginChallenge 挑战通过后立即删除，防止重复使用
+func CompleteLoginChallenge(ch *LoginChallenge) {
+	config.Rdb.Del(config.Ctx, ch.Key)
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userType string, userID uint) ([]string, error) {
+	if err := tx.Where("user_type = ? AND user_id = ?", userType, userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw, err := utils.RandomToken(recoveryCodeHalfLength)
+		if err != nil {
+			return nil, err
+		}
+		code := fmt.Sprintf("%s-%s", raw[:recoveryCodeHalfLength], raw[recoveryCodeHalfLength:])
+		rc := models.RecoveryCode{UserType: userType, UserID: userID, CodeHash: utils.HashToken(raw)}
+		if err := tx.Create(&rc).Error; err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ReplaceAll(code, "-", "")
+	code = strings.ReplaceAll(code, " ", "")
+	return strings.ToLower(code)
+}
@@ -48,8 +48,13 @@ func StartLogConsumer() {
 			}
 
 			// 写入数据库
+			actorType, ok := logData["actor_type"].(string)
+			if !ok {
+				actorType = models.ActorUser
+			}
 			logEntry := models.OperationLog{
 				UserID:    uint(logData["user_id"].(float64)), // 注意类型断言
+				ActorType: actorType,
 				Action:    logData["action"].(string),
 				TargetID:  logData["target_id"].(string),
 				CreatedAt: time.Now(),
@@ -7,8 +7,26 @@ import (
 	"log"
 )
 
+// LogOperation 记录一条操作日志。actorID 的含义由 actorType 决定（见 models.ActorUser 等），
+// 用户一律记账号ID，以免管理员与员工的档案ID相互混淆
+func LogOperation(actorID uint, actorType, action, targetID string) {
+	if r := []rune(targetID); len(r) > 100 {
+		targetID = string(r[:100])
+	}
+	SendLogToRabbitMQ(map[string]interface{}{
+		"user_id":    actorID,
+		"actor_type": actorType,
+		"action":     action,
+		"target_id":  targetID,
+	})
+}
+
 // 通用消息发送函数
 func SendLogToRabbitMQ(data map[string]interface{}) {
+	if config.RabbitMQChannel == nil {
+		log.Printf("日志发送失败: RabbitMQ 未初始化, %v", data)
+		return
+	}
 	body, _ := json.Marshal(data)
 	err := config.RabbitMQChannel.Publish(
 		"",               // 使用默认交换机
@@ -28,3 +28,16 @@ func GetCurrentUserRole(c *gin.Context) (string, error) {
 	}
 	return role, nil
 }
+
+// GetCurrentPrincipalID 当前请求的账号ID（跨管理员/员工唯一）
+func GetCurrentPrincipalID(c *gin.Context) (uint, error) {
+	rawID, ok := c.Get("principalID")
+	if !ok {
+		return 0, errors.New("principalID not found")
+	}
+	principalID, ok := rawID.(uint)
+	if !ok {
+		return 0, errors.New("invalid principalID type")
+	}
+	return principalID, nil
+}
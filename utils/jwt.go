@@ -1,38 +1,67 @@
 package utils
 
 import (
+	"EmployeeManagementDemo/config"
 	"EmployeeManagementDemo/models"
+	"errors"
+	"fmt"
 	"github.com/golang-jwt/jwt/v4"
 	"time"
 )
 
-var JwtSecret = []byte("your-secret-key")
-
 type Claims struct {
-	UserID               uint   `json:"user_id"`
+	PrincipalID          uint   `json:"pid"`     // 账号ID，跨管理员/员工唯一
+	UserID               uint   `json:"user_id"` // 档案ID（admin_id 或 emp_id），配合 Role 使用
 	Role                 string `json:"role"`
-	jwt.RegisteredClaims        // 替换 StandardClaims
+	MustChangePassword   bool   `json:"mcp,omitempty"` // 密码已过期，只能修改密码或注销
+	jwt.RegisteredClaims        // 替换 StandardClaims；ID（jti）为所属会话ID
 }
 
-func GenerateJWT(user models.BaseUser) (string, error) {
+// GenerateJWT 签发短期访问令牌，jti 为所属会话（刷新令牌族）ID；
+// mustChangePassword 为 true 时令牌只能用于修改密码和注销
+func GenerateJWT(user models.BaseUser, sessionID string, mustChangePassword bool) (string, error) {
 	claims := Claims{
-		UserID: user.GetID(),
-		Role:   user.GetRole(),
+		PrincipalID:        user.GetAccountID(),
+		UserID:             user.GetID(),
+		Role:               user.GetRole(),
+		MustChangePassword: mustChangePassword,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        sessionID,
 			IssuedAt:  jwt.NewNumericDate(time.Now()), // 新增：设置签发时间
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(config.Cfg.JWT.AccessTTL)),
 			Issuer:    "EmployeeManagement",
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(JwtSecret)
+	key := activeJWTKey
+	if key == nil || key.signKey == nil {
+		return "", errors.New("未配置可用的 JWT 签名密钥")
+	}
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.kid
+	return token.SignedString(key.signKey)
 }
 
 func ParseJWT(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return JwtSecret, nil
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			kid = defaultJWTKeyID // 兼容引入 kid 之前签发的令牌
+		}
+		key, ok := jwtKeys[kid]
+		if !ok {
+			return nil, fmt.Errorf("未知的签名密钥: %q", kid)
+		}
+		// 算法必须与密钥声明一致，防止用公钥当 HMAC 密钥伪造令牌
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("签名算法不匹配: %s", token.Method.Alg())
+		}
+		return key.verifyKey, nil
 	})
+	if token == nil {
+		return nil, err
+	}
 
 	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
 		return claims, nil
@@ -0,0 +1,196 @@
+package utils
+
+import (
+	"EmployeeManagementDemo/config"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"log"
+	"math/big"
+	"os"
+	"sort"
+)
+
+const defaultJWTKeyID = "default"
+
+// jwtKey 密钥环中的一个密钥，signKey 为空表示只用于校验
+type jwtKey struct {
+	kid       string
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+var (
+	jwtKeys      = map[string]*jwtKey{}
+	activeJWTKey *jwtKey
+)
+
+// JWK 对外公开的公钥（RFC 7517），HS256 共享密钥不会出现在这里
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`   // RSA 模数
+	E   string `json:"e,omitempty"`   // RSA 指数
+	Crv string `json:"crv,omitempty"` // OKP 曲线
+	X   string `json:"x,omitempty"`   // Ed25519 公钥
+}
+
+// InitJWTKeys 根据配置加载签名密钥环，需在 config.LoadConfig 之后调用
+func InitJWTKeys() error {
+	cfg := config.Cfg.JWT
+	keys := cfg.Keys
+	activeKID := cfg.ActiveKID
+
+	// 未配置密钥环时退回到单个 HS256 密钥
+	if len(keys) == 0 {
+		keys = []config.JWTKeyConfig{{KID: defaultJWTKeyID, Alg: "HS256", Secret: cfg.Secret}}
+		activeKID = defaultJWTKeyID
+	}
+	if activeKID == "" {
+		activeKID = keys[0].KID
+	}
+
+	loaded := make(map[string]*jwtKey, len(keys))
+	for _, kc := range keys {
+		if kc.KID == "" {
+			return fmt.Errorf("JWT 密钥缺少 kid")
+		}
+		if _, dup := loaded[kc.KID]; dup {
+			return fmt.Errorf("JWT 密钥 kid 重复: %s", kc.KID)
+		}
+		key, err := loadJWTKey(kc)
+		if err != nil {
+			return fmt.Errorf("加载 JWT 密钥 %s 失败: %w", kc.KID, err)
+		}
+		loaded[kc.KID] = key
+	}
+
+	active, ok := loaded[activeKID]
+	if !ok {
+		return fmt.Errorf("active_kid %s 不在密钥列表中", activeKID)
+	}
+	if active.signKey == nil {
+		return fmt.Errorf("active_kid %s 没有私钥，无法签发令牌", activeKID)
+	}
+
+	jwtKeys = loaded
+	activeJWTKey = active
+	return nil
+}
+
+func loadJWTKey(kc config.JWTKeyConfig) (*jwtKey, error) {
+	key := &jwtKey{kid: kc.KID}
+
+	switch kc.Alg {
+	case "HS256":
+		if kc.Secret == "" {
+			return nil, fmt.Errorf("HS256 密钥为空")
+		}
+		if len(kc.Secret) < 32 {
+			log.Printf("警告: JWT 密钥 %s 长度不足 32 字节，生产环境请更换", kc.KID)
+		}
+		key.method = jwt.SigningMethodHS256
+		key.signKey = []byte(kc.Secret)
+		key.verifyKey = key.signKey
+
+	case "RS256":
+		key.method = jwt.SigningMethodRS256
+		if kc.PrivateKeyFile != "" {
+			pem, err := os.ReadFile(kc.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			priv, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			key.signKey = priv
+			key.verifyKey = &priv.PublicKey
+		} else {
+			pem, err := readPublicKeyFile(kc)
+			if err != nil {
+				return nil, err
+			}
+			pub, err := jwt.ParseRSAPublicKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			key.verifyKey = pub
+		}
+
+	case "EdDSA":
+		key.method = jwt.SigningMethodEdDSA
+		if kc.PrivateKeyFile != "" {
+			pem, err := os.ReadFile(kc.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			priv, err := jwt.ParseEdPrivateKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			edPriv, ok := priv.(ed25519.PrivateKey)
+			if !ok {
+				return nil, fmt.Errorf("不是 Ed25519 私钥")
+			}
+			key.signKey = edPriv
+			key.verifyKey = edPriv.Public()
+		} else {
+			pem, err := readPublicKeyFile(kc)
+			if err != nil {
+				return nil, err
+			}
+			pub, err := jwt.ParseEdPublicKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			key.verifyKey = pub
+		}
+
+	default:
+		return nil, fmt.Errorf("不支持的签名算法: %q", kc.Alg)
+	}
+	return key, nil
+}
+
+func readPublicKeyFile(kc config.JWTKeyConfig) ([]byte, error) {
+	if kc.PublicKeyFile == "" {
+		return nil, fmt.Errorf("需要 private_key_file 或 public_key_file")
+	}
+	return os.ReadFile(kc.PublicKeyFile)
+}
+
+// JWKS 返回所有非对称密钥的公钥，供其他服务校验令牌
+func JWKS() []JWK {
+	enc := base64.RawURLEncoding
+	list := make([]JWK, 0, len(jwtKeys))
+	for _, key := range jwtKeys {
+		switch pub := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			list = append(list, JWK{
+				Kty: "RSA",
+				Kid: key.kid,
+				Use: "sig",
+				Alg: key.method.Alg(),
+				N:   enc.EncodeToString(pub.N.Bytes()),
+				E:   enc.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			list = append(list, JWK{
+				Kty: "OKP",
+				Kid: key.kid,
+				Use: "sig",
+				Alg: key.method.Alg(),
+				Crv: "Ed25519",
+				X:   enc.EncodeToString(pub),
+			})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Kid < list[j].Kid })
+	return list
+}
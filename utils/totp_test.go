@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 附录 B 的 SHA-1 测试向量，密钥为 ASCII "12345678901234567890"
+var rfc6238Secret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCodeMatchesRFC6238(t *testing.T) {
+	vectors := []struct {
+		unix int64
+		code string // 附录 B 中的 8 位验证码，按配置的位数取末尾
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, v := range vectors {
+		want := v.code[len(v.code)-TOTPDigits:]
+		got, err := TOTPCode(rfc6238Secret, time.Unix(v.unix, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("T=%d 的验证码为 %s，期望 %s", v.unix, got, want)
+		}
+	}
+}
+
+func TestVerifyTOTPAllowsOneStepSkew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := now.Unix() / totpPeriod
+	cases := []struct {
+		offset int64 // 验证码所在时间步相对当前时间步的偏移
+		ok     bool
+	}{
+		{-2, false},
+		{-1, true},
+		{0, true},
+		{1, true},
+		{2, false},
+	}
+	for _, tc := range cases {
+		code, err := totpCodeAt(rfc6238Secret, current+tc.offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		step, ok := VerifyTOTP(rfc6238Secret, code, now)
+		if ok != tc.ok {
+			t.Errorf("偏移 %d 个步长的验证码校验结果为 %v，期望 %v", tc.offset, ok, tc.ok)
+		}
+		if ok && step != current+tc.offset {
+			t.Errorf("偏移 %d 个步长的验证码返回时间步 %d，期望 %d", tc.offset, step, current+tc.offset)
+		}
+	}
+	if _, ok := VerifyTOTP(rfc6238Secret, strings.Repeat("1", TOTPDigits+1), now); ok {
+		t.Error("位数不对的验证码不应通过")
+	}
+}
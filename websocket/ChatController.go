@@ -3,61 +3,95 @@ package websocket
 import (
 	"EmployeeManagementDemo/config"
 	"EmployeeManagementDemo/models"
-	"context"
-	"encoding/json"
-	"fmt"
-	"github.com/gorilla/websocket"
+	"EmployeeManagementDemo/services"
+	"EmployeeManagementDemo/utils"
+	"errors"
 	"strconv"
 	"time"
 )
 
-// 聊天的后端调度逻辑
+// 聊天的后端调度逻辑（在连接的读协程中执行，只读取连接上不变的字段）
 // 单聊
-func SingleChat(c *models.Client, sendMsg *models.SendMsg) {
-	// 使用更明确的键名
-	chatKey := fmt.Sprintf("chat:%s", c.ID)
-	r1, _ := config.Rdb.Get(context.Background(), chatKey).Result()
-	//从redis中取出固定用户发给当前用户的消息
-	id := CreateId(strconv.Itoa(c.RecipientID), strconv.Itoa(c.SendID))
-	r2, _ := config.Rdb.Get(context.Background(), id).Result()
-	//根据redis的结果去做未关注聊天次数限制
-	if r2 >= "3" && r1 == "" {
-		ResponseWebSocket(c.Socket, CodeLimiteTimes, "未相互关注，限制聊天次数")
+func (h *Hub) SingleChat(c *models.Client, sendMsg *models.SendMsg) {
+	h.singleChat(c, sendMsg, nil)
+}
+
+func (h *Hub) singleChat(c *models.Client, sendMsg *models.SendMsg, att *models.ChatAttachment) {
+	//接收方须存在，被对方屏蔽不能发送，非联系人在对方回复前限制条数（同部门同事视为联系人）
+	if err := services.CheckDirectMessage(c.SendID, sendMsg.RecipientID); err != nil {
+		switch {
+		case errors.Is(err, services.ErrChatRecipientInvalid), errors.Is(err, services.ErrChatSelf):
+			ResponseWebSocket(c, CodeParamError, err.Error())
+		case errors.Is(err, services.ErrContactBlocked):
+			ResponseWebSocket(c, CodeContactBlocked, err.Error())
+		case errors.Is(err, services.ErrNonContactLimit):
+			ResponseWebSocket(c, CodeLimiteTimes, err.Error())
+		default:
+			ResponseWebSocket(c, CodeServerBusy, "服务繁忙")
+		}
 		return
-	} else {
-		//将消息写入redis
-		config.Rdb.Incr(context.Background(), c.ID)
-		//设置消息的过期时间
-		_, _ = config.Rdb.Expire(context.Background(), c.ID, time.Hour*24*30*3).Result()
-	}
-	fmt.Println(c.ID+"发送消息：", sendMsg.Content)
-	//将消息广播出去
-	models.Manager.Broadcast <- &models.Broadcast{
-		Client:  c,
-		Message: []byte(sendMsg.Content),
 	}
+	//先把消息标识回给发送方，再落库投递，之后的送达、已读回执以此对应
+	msgKey := newMsgKey()
+	sentAck(c, models.DirectConversationID(c.SendID, sendMsg.RecipientID), msgKey)
+	h.deliverMessage(&models.Broadcast{
+		Client:      c,
+		RecipientID: sendMsg.RecipientID,
+		MsgKey:      msgKey,
+		Message:     []byte(sendMsg.Content),
+		Attachment:  att,
+	})
 }
 
-// 查看未读消息
-func UnreadMessages(c *models.Client) {
-	//获取数据库中的未读消息
-	msgs, err := GetMessageUnread(c.SendID)
+// 拉取离线期间收到的消息：推送后标记为已送达并回执给发送方，已读由客户端另行确认
+func (h *Hub) UnreadMessages(c *models.Client) {
+	msgs, err := services.PendingChatMessages(c.SendID)
 	if err != nil {
-		ResponseWebSocket(c.Socket, CodeServerBusy, "服务繁忙")
+		ResponseWebSocket(c, CodeServerBusy, "服务繁忙")
+		return
 	}
-	for i, msg := range msgs {
-		replyMsg := models.ReplyMsg{
-			From:    msg.Direction,
-			Content: msg.Content,
+	atts, err := services.ChatMessageAttachments(msgs)
+	if err != nil {
+		ResponseWebSocket(c, CodeServerBusy, "服务繁忙")
+		return
+	}
+	delivered := make([]uint, 0, len(msgs))
+	for _, msg := range msgs {
+		reply := models.ReplyMsg{
+			From:           msg.Direction,
+			Code:           CodeConnectionSuccess,
+			Content:        msg.Content,
+			ID:             msg.ID,
+			MsgKey:         msg.MsgKey,
+			ConversationID: msg.ConversationID,
+			SenderID:       msg.SendID,
+		}
+		if msg.AttachmentID != nil && atts[*msg.AttachmentID] != nil {
+			reply.Attachment = atts[*msg.AttachmentID].ToDTO()
 		}
-		message, _ := json.Marshal(replyMsg)
-		_ = c.Socket.WriteMessage(websocket.TextMessage, message)
-		//发送完后将消息设为已读
-		msgs[i].Read = true
-		err := UpdateMessage(&msgs[i])
-		if err != nil {
-			ResponseWebSocket(c.Socket, CodeServerBusy, "服务繁忙")
+		if !deliver(c, replyFrame(reply)) {
+			break // 连接已断开，剩余消息保持未送达
 		}
+		delivered = append(delivered, msg.ID)
+	}
+	if len(delivered) == 0 {
+		return
+	}
+
+	now := time.Now()
+	if err := services.MarkChatMessagesDelivered(delivered, now); err != nil {
+		ResponseWebSocket(c, CodeServerBusy, "服务繁忙")
+		return
+	}
+	for _, msg := range msgs[:len(delivered)] {
+		h.notify(msg.SendID, eventFrame(models.ChatEvent{
+			Event:          models.ChatEventDelivered,
+			ConversationID: msg.ConversationID,
+			MsgKey:         msg.MsgKey,
+			MessageID:      msg.ID,
+			UserID:         c.SendID,
+			At:             now,
+		}))
 	}
 }
 
@@ -67,49 +101,105 @@ func HistoryMsg(c *models.Client, sendMsg *models.SendMsg) {
 	timeT := TimeStringToGoTime(sendMsg.Content)
 	//查找聊天记录
 	//做一个分页处理，一次查询十条数据,根据时间去限制次数
-	//别人发给当前用户的
-	direction := CreateId(strconv.Itoa(c.RecipientID), strconv.Itoa(c.SendID))
-	//当前用户发出的
-	id := CreateId(strconv.Itoa(c.SendID), strconv.Itoa(c.RecipientID))
-	msgs, err := GetHistoryMsg(direction, id, timeT, 10)
+	//双方往来的消息（REST 接口 /api/chat/conversations/:id/messages 支持游标分页）
+	msgs, err := GetHistoryMsg(c.SendID, models.DirectConversationID(c.SendID, sendMsg.RecipientID), timeT, 10)
 	if err != nil {
-		ResponseWebSocket(c.Socket, CodeServerBusy, "服务繁忙")
+		ResponseWebSocket(c, CodeServerBusy, "服务繁忙")
+		return
 	}
 	//把消息写给用户
-	for _, msg := range *msgs {
-		replyMsg := models.ReplyMsg{
-			From:    msg.Direction,
-			Content: msg.Content,
-		}
-		message, _ := json.Marshal(replyMsg)
-		_ = c.Socket.WriteMessage(websocket.TextMessage, message)
-		//发送完后将消息设为已读
-		if err != nil {
-			ResponseWebSocket(c.Socket, CodeServerBusy, "服务繁忙")
+	for _, msg := range msgs {
+		if !deliver(c, historyMsgFrame(msg)) {
+			return
 		}
 	}
 }
 
 // 群聊消息广播
-func GroupChat(c *models.Client, sendMsg *models.SendMsg) {
+func (h *Hub) GroupChat(c *models.Client, sendMsg *models.SendMsg) {
+	h.groupChat(c, sendMsg, nil)
+}
+
+// 附件消息：附件须由发送方先经 REST 接口上传，Content 作为附言
+func (h *Hub) AttachmentChat(c *models.Client, sendMsg *models.SendMsg, group bool) {
+	att, err := services.GetOwnChatAttachment(c.SendID, sendMsg.AttachmentID)
+	if err != nil {
+		ResponseWebSocket(c, CodeAttachmentInvalid, "附件不存在")
+		return
+	}
+	if group {
+		h.groupChat(c, sendMsg, att)
+	} else {
+		h.singleChat(c, sendMsg, att)
+	}
+}
+
+func (h *Hub) groupChat(c *models.Client, sendMsg *models.SendMsg, att *models.ChatAttachment) {
 	//根据消息类型判断是否为群聊消息
 	//先去数据库查询该群下的所有用户
-	users, err := GetAllGroupUser(strconv.Itoa(sendMsg.RecipientID))
+	groupID := strconv.Itoa(sendMsg.RecipientID)
+	memberIDs, err := GetAllGroupUser(groupID)
 	if err != nil {
-		ResponseWebSocket(c.Socket, CodeServerBusy, "服务繁忙")
+		ResponseWebSocket(c, CodeServerBusy, "服务繁忙")
+		return
+	}
+	//只有群成员才能发言
+	if !containsID(memberIDs, c.SendID) {
+		ResponseWebSocket(c, CodeNotGroupMember, "不是该群成员")
+		return
+	}
+	//发送人保存一份已读副本，用于会话列表和历史记录
+	msgKey := newMsgKey()
+	now := time.Now()
+	own := models.ChatMessage{
+		ConversationID: models.GroupConversationID(groupID),
+		Direction:      createId(strconv.Itoa(c.SendID), strconv.Itoa(c.SendID)),
+		SendID:         c.SendID,
+		RecipientID:    c.SendID,
+		GroupID:        groupID,
+		Content:        sendMsg.Content,
+		Read:           true,
+		MsgKey:         msgKey,
+		Status:         models.MsgStatusRead,
+		DeliveredAt:    &now,
+		ReadAt:         &now,
+	}
+	if att != nil {
+		own.AttachmentID = &att.ID
+	}
+	if err := config.DB.Create(&own).Error; err != nil {
+		ResponseWebSocket(c, CodeServerBusy, "服务繁忙")
+		return
 	}
 	//向群里面的用户广播消息
-	for _, user := range users {
-		//获取群里每个用户的连接
-		if int(user.ID) == c.SendID {
+	sentAck(c, own.ConversationID, msgKey)
+	for _, id := range memberIDs {
+		if id == c.SendID {
 			continue
 		}
-		c.ID = strconv.Itoa(c.SendID) + "->"
-		c.GroupID = strconv.Itoa(sendMsg.RecipientID)
-		c.RecipientID = int(user.ID)
-		models.Manager.Broadcast <- &models.Broadcast{
-			Client:  c,
-			Message: []byte(sendMsg.Content),
-		}
+		h.deliverMessage(&models.Broadcast{
+			Client:      c,
+			RecipientID: id,
+			GroupID:     groupID,
+			MsgKey:      msgKey,
+			Message:     []byte(sendMsg.Content),
+			Attachment:  att,
+		})
 	}
 }
+
+// newMsgKey 消息标识
+func newMsgKey() string {
+	key, _ := utils.RandomToken(8)
+	return key
+}
+
+// sentAck 告知发送方消息已受理
+func sentAck(c *models.Client, conversationID, msgKey string) {
+	deliver(c, eventFrame(models.ChatEvent{
+		Event:          models.ChatEventSent,
+		ConversationID: conversationID,
+		MsgKey:         msgKey,
+		At:             time.Now(),
+	}))
+}
@@ -0,0 +1,53 @@
+package websocket
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/testutil"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestHistoryMsgMatchesRESTVisibility(t *testing.T) {
+	testutil.Setup(t)
+	conv := models.DirectConversationID(1, 2)
+	now := time.Now()
+	msgs := []models.ChatMessage{
+		{ConversationID: conv, SendID: 2, RecipientID: 1, Content: "plain", MsgKey: "k1"},
+		{ConversationID: conv, SendID: 1, RecipientID: 2, Content: "deleted", MsgKey: "k2", DeletedBySender: true},
+		{ConversationID: conv, SendID: 2, RecipientID: 1, Content: "after edit", MsgKey: "k3", EditedAt: &now},
+		{ConversationID: conv, SendID: 2, RecipientID: 1, Content: "", MsgKey: "k4", RecalledAt: &now},
+	}
+	for i := range msgs {
+		msgs[i].Status = models.MsgStatusSent
+		if err := config.DB.Create(&msgs[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	alice := models.NewClient(1, "session", nil, sendBufferSize)
+	HistoryMsg(alice, &models.SendMsg{Type: 3, RecipientID: 2, Content: strconv.FormatInt(now.Add(time.Minute).Unix(), 10)})
+
+	got := map[string]models.ChatMessageDTO{}
+	for len(alice.Send) > 0 {
+		var dto models.ChatMessageDTO
+		if err := json.Unmarshal(<-alice.Send, &dto); err != nil {
+			t.Fatal(err)
+		}
+		got[dto.MsgKey] = dto
+	}
+	if len(got) != 3 {
+		t.Fatalf("应收到 3 条历史消息，得到 %+v", got)
+	}
+	if _, ok := got["k2"]; ok {
+		t.Fatal("自己删除的消息不应出现在历史记录中")
+	}
+	if !got["k3"].Edited || got["k3"].Content != "after edit" {
+		t.Fatalf("编辑过的消息应带编辑状态，得到 %+v", got["k3"])
+	}
+	if !got["k4"].Recalled {
+		t.Fatalf("撤回的消息应带撤回状态，得到 %+v", got["k4"])
+	}
+}
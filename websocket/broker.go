@@ -0,0 +1,228 @@
+package websocket
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+	"os"
+	"strconv"
+	"time"
+)
+
+// 多实例部署：在线状态登记在 Redis，消息经接收方所在实例的频道转发，每个实例只投递本地连接
+const (
+	presencePrefix    = "ws_presence:"   // 账号ID -> {实例ID: 连接数}
+	nodeAlivePrefix   = "ws_node_alive:" // 实例存活标记，定期续期；实例异常退出后其在线登记随之作废
+	nodeChannelPrefix = "ws_node:"       // 实例的消息频道
+	nodeUsersPrefix   = "ws_node_users:" // 实例ID -> 在该实例上有连接的账号ID，实例退出或过期后据此清理
+	nodesKey          = "ws_nodes"       // 登记过连接的实例，用于发现存活标记已过期的实例
+	nodeAliveTTL      = 30 * time.Second
+)
+
+// 在线登记的增减和连接总数的统计在一个脚本中完成，并发上线/下线时每个账号只有一次 1 和一次 0，
+// 据此推送上线和下线。连接总数只统计存活实例，返回 -1 表示没有可注销的登记
+var (
+	// KEYS: 账号的在线登记、实例的账号集合、实例集合；ARGV: 实例ID、增量、账号ID、存活标记前缀
+	updatePresenceScript = redis.NewScript(`
+if tonumber(ARGV[2]) < 0 then
+  if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 0 then return -1 end
+  if redis.call('HINCRBY', KEYS[1], ARGV[1], ARGV[2]) <= 0 then
+    redis.call('HDEL', KEYS[1], ARGV[1])
+    redis.call('SREM', KEYS[2], ARGV[3])
+  end
+else
+  redis.call('HINCRBY', KEYS[1], ARGV[1], ARGV[2])
+  redis.call('SADD', KEYS[2], ARGV[3])
+  redis.call('SADD', KEYS[3], ARGV[1])
+end
+` + countDevicesLua)
+
+	// KEYS: 账号的在线登记；ARGV: 实例ID、存活标记前缀。删除实例的整条登记
+	dropNodePresenceScript = redis.NewScript(`
+if redis.call('HDEL', KEYS[1], ARGV[1]) == 0 then return -1 end
+` + countDevicesLua)
+)
+
+const countDevicesLua = `
+local total = 0
+local fields = redis.call('HGETALL', KEYS[1])
+for i = 1, #fields, 2 do
+  if redis.call('EXISTS', ARGV[#ARGV] .. fields[i]) == 1 then
+    total = total + tonumber(fields[i + 1])
+  end
+end
+return total
+`
+
+func presenceKey(principalID int) string {
+	return fmt.Sprintf("%s%d", presencePrefix, principalID)
+}
+
+// newNodeID 生成实例ID：主机名加随机后缀，同一主机上的多个进程也不会冲突
+func newNodeID() string {
+	host, _ := os.Hostname()
+	suffix, _ := utils.RandomToken(4)
+	return host + "-" + suffix
+}
+
+// addPresence 登记本实例上的一个连接，账号的第一个设备上线时推送状态
+func (h *Hub) addPresence(principalID int) {
+	node := h.manager.NodeID
+	total, err := updatePresenceScript.Run(config.Ctx, h.rdb,
+		[]string{presenceKey(principalID), nodeUsersPrefix + node, nodesKey},
+		node, 1, principalID, nodeAlivePrefix).Int()
+	if err != nil {
+		zap.L().Error("在线状态登记失败", zap.Error(err))
+		return
+	}
+	if total == 1 {
+		now := time.Now()
+		h.rdb.Set(config.Ctx, lastSeenKey(principalID), now.Unix(), 0)
+		h.announcePresence(principalID, h.presenceStatus(principalID), now)
+	}
+}
+
+// removePresence 注销本实例上的一个连接，计数归零时删除本实例的登记；账号的最后一个设备下线时推送 offline
+func (h *Hub) removePresence(principalID int) {
+	node := h.manager.NodeID
+	total, err := updatePresenceScript.Run(config.Ctx, h.rdb,
+		[]string{presenceKey(principalID), nodeUsersPrefix + node, nodesKey},
+		node, -1, principalID, nodeAlivePrefix).Int()
+	if err != nil {
+		zap.L().Error("在线状态注销失败", zap.Error(err))
+		return
+	}
+	if total == 0 {
+		h.wentOffline(principalID)
+	}
+}
+
+// dropNodePresence 删除实例在各账号下的整条登记，因此没有任何连接的账号推送 offline
+func (h *Hub) dropNodePresence(node string, principalIDs []string) {
+	for _, raw := range principalIDs {
+		id, err := strconv.Atoi(raw)
+		if err != nil {
+			continue
+		}
+		total, err := dropNodePresenceScript.Run(config.Ctx, h.rdb, []string{presenceKey(id)}, node, nodeAlivePrefix).Int()
+		if err != nil {
+			zap.L().Error("在线状态注销失败", zap.String("node", node), zap.Error(err))
+			continue
+		}
+		if total == 0 {
+			h.wentOffline(id)
+		}
+	}
+}
+
+// leave 实例退出：注销本实例的全部在线登记并推送下线，最后删除存活标记。
+// 调度协程已退出，本地连接均已关闭，下线通知只转发给其他实例
+func (h *Hub) leave() {
+	node := h.manager.NodeID
+	users, err := h.rdb.SMembers(config.Ctx, nodeUsersPrefix+node).Result()
+	if err != nil {
+		zap.L().Error("在线状态查询失败", zap.Error(err))
+	}
+	h.dropNodePresence(node, users)
+	h.rdb.SRem(config.Ctx, nodesKey, node)
+	h.rdb.Del(config.Ctx, nodeUsersPrefix+node, nodeAlivePrefix+node)
+}
+
+// reapNodes 清理存活标记已过期的实例（进程异常退出）的在线登记并推送下线。
+// 从实例集合中移除成功的实例才处理，多个实例同时检查时每个过期实例只处理一次
+func (h *Hub) reapNodes() {
+	nodes, err := h.rdb.SMembers(config.Ctx, nodesKey).Result()
+	if err != nil {
+		zap.L().Error("实例列表查询失败", zap.Error(err))
+		return
+	}
+	for _, node := range nodes {
+		if node == h.manager.NodeID {
+			continue
+		}
+		if alive, err := h.rdb.Exists(config.Ctx, nodeAlivePrefix+node).Result(); err != nil || alive > 0 {
+			continue
+		}
+		if removed, err := h.rdb.SRem(config.Ctx, nodesKey, node).Result(); err != nil || removed == 0 {
+			continue // 其他实例已处理
+		}
+		zap.L().Warn("实例存活标记已过期，清理其在线登记", zap.String("node", node))
+		users, err := h.rdb.SMembers(config.Ctx, nodeUsersPrefix+node).Result()
+		if err != nil {
+			zap.L().Error("在线状态查询失败", zap.Error(err))
+		}
+		h.dropNodePresence(node, users)
+		h.rdb.Del(config.Ctx, nodeUsersPrefix+node)
+	}
+}
+
+// relayToNodes 把消息帧转发给接收方在线的其他实例，返回是否有实例接收
+func (h *Hub) relayToNodes(recipientID int, frame []byte) bool {
+	key := presenceKey(recipientID)
+	nodes, err := h.rdb.HGetAll(config.Ctx, key).Result()
+	if err != nil {
+		zap.L().Error("在线状态查询失败", zap.Error(err))
+		return false
+	}
+
+	data, _ := json.Marshal(models.RelayFrame{RecipientID: recipientID, Frame: frame})
+	relayed := false
+	for node := range nodes {
+		if node == h.manager.NodeID {
+			continue
+		}
+		// 实例已下线（未续期存活标记），残留登记由 reapNodes 清理并推送下线
+		if alive, _ := h.rdb.Exists(config.Ctx, nodeAlivePrefix+node).Result(); alive == 0 {
+			continue
+		}
+		if err := h.rdb.Publish(config.Ctx, nodeChannelPrefix+node, data).Err(); err != nil {
+			zap.L().Error("消息转发失败", zap.String("node", node), zap.Error(err))
+			continue
+		}
+		relayed = true
+	}
+	return relayed
+}
+
+// runNode 续期本实例的存活标记（由 Start 设置，退出时由 Start 删除）并清理已过期的实例，
+// 把本实例频道收到的消息交给调度协程
+func (h *Hub) runNode(ctx context.Context) {
+	manager := h.manager
+	aliveKey := nodeAlivePrefix + manager.NodeID
+
+	sub := h.rdb.Subscribe(ctx, nodeChannelPrefix+manager.NodeID)
+	defer sub.Close()
+	ch := sub.Channel()
+
+	ticker := time.NewTicker(nodeAliveTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.rdb.Set(config.Ctx, aliveKey, 1, nodeAliveTTL)
+			h.reapNodes()
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			relay := new(models.RelayFrame)
+			if err := json.Unmarshal([]byte(msg.Payload), relay); err != nil {
+				zap.L().Error("转发消息解析失败", zap.Error(err))
+				continue
+			}
+			select {
+			case manager.Relay <- relay:
+			case <-manager.Quit:
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package websocket
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/testutil"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// startCluster 启动共用一个 Redis 的两个实例，等到两者都订阅了各自的频道再返回，另返回停止实例 b 的函数
+func startCluster(t *testing.T) (*miniredis.Miniredis, *Hub, *Hub, context.CancelFunc) {
+	mr := testutil.Setup(t)
+	config.Cfg.Chat.NonContactLimit = -1
+	createAccounts(t, 1, 2, 3)
+
+	newClient := func() *redis.Client {
+		rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		t.Cleanup(func() { rdb.Close() })
+		return rdb
+	}
+	a, _ := startNode(t, newClient(), "node-a")
+	b, stopB := startNode(t, newClient(), "node-b")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		subs := mr.PubSubNumSub(nodeChannelPrefix+"node-a", nodeChannelPrefix+"node-b")
+		if subs[nodeChannelPrefix+"node-a"] > 0 && subs[nodeChannelPrefix+"node-b"] > 0 {
+			return mr, a, b, stopB
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("实例未订阅转发频道")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// waitFrame 等待经其他实例转发来的一帧
+func waitFrame(t *testing.T, c *models.Client) frame {
+	t.Helper()
+	select {
+	case data := <-c.Send:
+		var f frame
+		if err := json.Unmarshal(data, &f); err != nil {
+			t.Fatalf("无法解析的帧 %s: %v", data, err)
+		}
+		return f
+	case <-time.After(2 * time.Second):
+		t.Fatal("没有收到其他实例转发的消息")
+		return frame{}
+	}
+}
+
+func TestBrokerRelaysToOtherNode(t *testing.T) {
+	_, a, b, _ := startCluster(t)
+	alice := connectClient(t, a, 1)
+	bob := connectClient(t, b, 2)
+	if n := devices(t, a, 2); n != 1 {
+		t.Fatalf("其他实例上的连接应计入在线设备，得到 %d", n)
+	}
+
+	a.SingleChat(alice, &models.SendMsg{Type: 1, RecipientID: 2, Content: "hi"})
+	if f := waitFrame(t, bob); f.Content != "hi" || f.ID == 0 {
+		t.Fatalf("接收方应收到转发的消息，得到 %+v", f)
+	}
+	evs := events(drain(t, alice))
+	if len(evs) != 2 || evs[0] != models.ChatEventSent || evs[1] != models.ChatEventDelivered {
+		t.Fatalf("发送方应依次收到 sent、delivered，得到 %v", evs)
+	}
+
+	// 事件同样经接收方所在实例推送
+	a.NotifyContact(models.ChatEventContactRequest, 2, 1, "hello")
+	if f := waitFrame(t, bob); f.Event != models.ChatEventContactRequest {
+		t.Fatalf("接收方应收到转发的事件，得到 %+v", f)
+	}
+}
+
+func TestBrokerTreatsExpiredNodeAsOffline(t *testing.T) {
+	mr, a, b, _ := startCluster(t)
+	alice := connectClient(t, a, 1)
+	bob := connectClient(t, b, 2)
+
+	// 实例 b 停止续期（如进程被杀），存活标记过期后其上的连接不再计入在线
+	mr.FastForward(nodeAliveTTL + time.Second)
+	if n := devices(t, a, 2); n != 0 {
+		t.Fatalf("存活标记过期的实例上的连接不应计入在线设备，得到 %d", n)
+	}
+
+	a.SingleChat(alice, &models.SendMsg{Type: 1, RecipientID: 2, Content: "hi"})
+	frames := drain(t, alice)
+	if len(frames) != 2 || frames[1].Message != "对方不在线" {
+		t.Fatalf("接收方只在已过期的实例上时消息应视为未送达，得到 %+v", frames)
+	}
+	if frames := drain(t, bob); len(frames) != 0 {
+		t.Fatalf("不应转发给已过期的实例，得到 %+v", frames)
+	}
+	var msg models.ChatMessage
+	if err := config.DB.Where("recipient_id = ?", 2).First(&msg).Error; err != nil {
+		t.Fatal(err)
+	}
+	if msg.Status != models.MsgStatusSent {
+		t.Fatalf("消息状态为 %s，期望 sent", msg.Status)
+	}
+}
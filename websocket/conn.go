@@ -3,104 +3,185 @@ package websocket
 
 import (
 	"EmployeeManagementDemo/models"
-	"encoding/json"
+	"EmployeeManagementDemo/services"
+	"EmployeeManagementDemo/utils"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 	"net/http"
-	"strconv"
-	"sync"
+	"time"
 )
 
-// 在全局或结构体中声明
-var rwLocker sync.RWMutex
+// 浏览器无法在握手时设置 Authorization 头，令牌通过 ?token= 或子协议传递：
+// new WebSocket(url, ["access_token", token])
+const tokenSubprotocol = "access_token"
 
-func WsHandle(c *gin.Context) {
-	myid := c.Query("myid")
-	userid, err := strconv.Atoi(myid)
+// WsHandle 握手时校验访问令牌（与 CheckJWTBlacklist 相同的注销/踢出/会话检查），
+// 用户身份取自令牌中的账号ID，管理员和员工共用同一ID空间
+func (h *Hub) WsHandle(c *gin.Context) {
+	tokenString := handshakeToken(c.Request)
+	if tokenString == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "未提供访问令牌"})
+		return
+	}
+	claims, err := utils.ParseJWT(tokenString)
 	if err != nil {
-		zap.L().Error("转换失败", zap.Error(err))
-		ResponseError(c, CodeParamError)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "令牌无效: " + err.Error()})
+		return
+	}
+	if err := services.CheckAccessToken(tokenString, claims); err != nil {
+		if errors.Is(err, services.ErrAccessTokenInvalid) || errors.Is(err, services.ErrAccessTokenRevoked) ||
+			errors.Is(err, services.ErrUserKicked) || errors.Is(err, services.ErrSessionRevoked) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "服务器错误"})
+		return
 	}
+	if claims.MustChangePassword {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "密码已过期，请先修改密码", "must_change_password": true})
+		return
+	}
+
 	//将http协议升级为ws协议
-	conn, err := (&websocket.Upgrader{CheckOrigin: func(r *http.Request) bool {
-		return true
-	}}).Upgrade(c.Writer, c.Request, nil)
+	conn, err := (&websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool {
+			return true
+		},
+		Subprotocols: []string{tokenSubprotocol},
+	}).Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		http.NotFound(c.Writer, c.Request)
 		return
 	}
 	//创建一个用户客户端实例，用于记录该用户的连接信息
-	client := new(models.Client)
-	client = &models.Client{
-		ID:     myid + "->",
-		SendID: userid,
-		Socket: conn,
-		Send:   make(chan []byte),
+	client := models.NewClient(int(claims.PrincipalID), claims.ID, conn, sendBufferSize)
+	h.trackClient(client)
+	//使用管道将实例注册到用户管理上，调度协程已退出（服务关闭中）时直接断开
+	if !h.connect(client) {
+		h.untrackClient(client)
+		_ = conn.Close()
+		return
 	}
-	//使用管道将实例注册到用户管理上
-	models.Manager.Register <- client
 	//开启两个协程用于读写消息
-	go Read(client)
+	go h.Read(client)
 	go Write(client)
 }
 
-// 用于读管道中的数据
-func Read(c *models.Client) {
-	//结束把通道关闭
+// handshakeToken 依次从 ?token= 和 Sec-WebSocket-Protocol（access_token 之后的一项）读取令牌
+func handshakeToken(r *http.Request) string {
+	if token := r.URL.Query().Get("token"); token != "" {
+		return token
+	}
+	protocols := websocket.Subprotocols(r)
+	for i, p := range protocols {
+		if p == tokenSubprotocol && i+1 < len(protocols) {
+			return protocols[i+1]
+		}
+	}
+	return ""
+}
+
+// Read 读协程：处理客户端消息，任何读错误（含心跳超时）都会结束连接
+func (h *Hub) Read(c *models.Client) {
+	//结束时注销连接，写协程随之发送关闭帧并关闭连接
 	defer func() {
-		models.Manager.Unregister <- c
-		//关闭连接
+		h.untrackClient(c)
+		h.disconnect(c)
+		c.Close()
 		_ = c.Socket.Close()
 	}()
+
+	c.Socket.SetReadLimit(maxMessageSize)
+	_ = c.Socket.SetReadDeadline(time.Now().Add(pongWait))
+	c.Socket.SetPongHandler(func(string) error {
+		return c.Socket.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
 	for {
-		//先测试一下连接能不能连上
-		c.Socket.PongHandler()
 		sendMsg := new(models.SendMsg)
-		err := c.Socket.ReadJSON(sendMsg)
-		c.RecipientID = sendMsg.RecipientID
-		if err != nil {
-			zap.L().Error("数据格式不正确", zap.Error(err))
-			models.Manager.Unregister <- c
-			_ = c.Socket.Close()
+		if err := c.Socket.ReadJSON(sendMsg); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				zap.L().Info("连接断开", zap.String("id", c.ID), zap.Error(err))
+			}
 			return
 		}
+		// 收到任何消息都说明连接存活
+		_ = c.Socket.SetReadDeadline(time.Now().Add(pongWait))
 		//根据要发送的消息类型去判断怎么处理
 		//消息类型的后端调度
 		switch sendMsg.Type {
 		case 1: //私信
-			SingleChat(c, sendMsg)
+			h.SingleChat(c, sendMsg)
 		case 2: //获取未读消息
-			UnreadMessages(c)
+			h.UnreadMessages(c)
 		case 3: //拉取历史消息记录
 			HistoryMsg(c, sendMsg)
 		case 4: //群聊消息广播
-			GroupChat(c, sendMsg)
+			h.GroupChat(c, sendMsg)
+		case 5: //已读确认
+			h.ReadAck(c, sendMsg)
+		case 6: //整个会话标记已读
+			h.ConversationRead(c, sendMsg)
+		case 7: //正在输入
+			h.Typing(c, sendMsg, true)
+		case 8: //停止输入
+			h.Typing(c, sendMsg, false)
+		case 9: //附件私信
+			h.AttachmentChat(c, sendMsg, false)
+		case 10: //群聊附件消息
+			h.AttachmentChat(c, sendMsg, true)
+		case 11: //设置在线状态（content 为 online/away/busy）
+			h.SetStatus(c, sendMsg)
 		}
 	}
 }
 
-// websocket/conn.go
+// Write 写协程：连接唯一的写入方，负责发送缓冲中的帧和心跳
 func Write(c *models.Client) {
+	ticker := time.NewTicker(pingPeriod)
 	defer func() {
+		ticker.Stop()
 		_ = c.Socket.Close()
 	}()
 	for {
 		select {
-		case message, ok := <-c.Send:
-			if !ok {
-				_ = c.Socket.WriteMessage(websocket.CloseMessage, []byte{})
+		case message := <-c.Send:
+			_ = c.Socket.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.Socket.WriteMessage(websocket.TextMessage, message); err != nil {
+				c.Close()
+				return
+			}
+		case <-ticker.C:
+			_ = c.Socket.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.Socket.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.Close()
 				return
 			}
-			replyMsg := models.ReplyMsg{
-				Code:    CodeConnectionSuccess,
-				Content: string(message),
+		case <-c.Done():
+			// select 随机选择就绪的分支，关闭前先写出已投递的帧（如断开原因）
+			if flush(c) != nil {
+				return
 			}
-			msg, _ := json.Marshal(replyMsg)
+			_ = c.Socket.SetWriteDeadline(time.Now().Add(writeWait))
+			_ = c.Socket.WriteMessage(websocket.CloseMessage, []byte{})
+			return
+		}
+	}
+}
 
-			rwLocker.Lock()
-			_ = c.Socket.WriteMessage(websocket.TextMessage, msg)
-			rwLocker.Unlock() // 确保解锁
+// flush 写出发送缓冲中剩余的帧。连接关闭后不再接受投递，缓冲只会变空
+func flush(c *models.Client) error {
+	for {
+		select {
+		case message := <-c.Send:
+			_ = c.Socket.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.Socket.WriteMessage(websocket.TextMessage, message); err != nil {
+				return err
+			}
+		default:
+			return nil
 		}
 	}
 }
@@ -1,9 +1,22 @@
 package websocket
 
+import "time"
+
 const (
-	CodeLimiteTimes       = 4003 // 未关注用户聊天次数限制
+	CodeLimiteTimes       = 4003 // 非联系人聊天条数限制
 	CodeParamError        = 4000
 	CodeServerBusy        = 503 // 使用标准HTTP状态码
 	CodeConnectionSuccess = 200
 	CodeConnectionBreak   = 4004 // 连接中断（客户端主动断开或网络问题）
+	CodeNotGroupMember    = 4005 // 不是群成员，不能在群内发言
+	CodeAttachmentInvalid = 4006 // 附件不存在或不是自己上传的
+	CodeContactBlocked    = 4007 // 已被对方屏蔽
+)
+
+const (
+	writeWait      = 10 * time.Second  // 单次写入超时
+	pongWait       = 60 * time.Second  // 超过该时间没有收到任何数据（含 pong）即断开
+	pingPeriod     = pongWait * 9 / 10 // 心跳间隔，须小于 pongWait
+	maxMessageSize = 64 * 1024         // 单条消息上限（字节）
+	sendBufferSize = 256               // 每个连接的发送缓冲，写满视为慢消费者并断开
 )
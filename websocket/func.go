@@ -1,34 +1,19 @@
 package websocket
 
 import (
-	"EmployeeManagementDemo/config"
 	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/services"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
-	"github.com/gorilla/websocket"
-	"gorm.io/gorm"
+	"go.uber.org/zap"
 	"net/http"
 	"strconv"
 	"time"
 )
 
-func CreateId(id1, id2 string) string {
-	// 按数值大小排序，确保键名唯一性（如 1001:2002 和 2002:1001 生成相同键）
-	if id1 > id2 {
-		id1, id2 = id2, id1
-	}
-	return fmt.Sprintf("%s:%s", id1, id2)
-}
-
-// 修正后的响应函数
-func ResponseWebSocket(conn *websocket.Conn, code int, message string) error {
-	// 验证连接有效性
-	if conn == nil {
-		return errors.New("websocket连接未初始化")
-	}
-
+// ResponseWebSocket 向连接投递一条状态消息（经写协程发送）
+func ResponseWebSocket(c *models.Client, code int, message string) {
 	response := struct {
 		Code    int    `json:"code"`
 		Message string `json:"message"`
@@ -36,48 +21,36 @@ func ResponseWebSocket(conn *websocket.Conn, code int, message string) error {
 		Code:    code,
 		Message: message,
 	}
-
-	// 必须处理JSON序列化错误
-	data, err := json.Marshal(response)
-	if err != nil {
-		return fmt.Errorf("响应序列化失败: %w", err)
-	}
-
-	// 使用线程安全写入
-	return conn.WriteMessage(websocket.TextMessage, data)
+	data, _ := json.Marshal(response)
+	deliver(c, data)
 }
 
-func GetMessageUnread(recipientID int) ([]models.ChatMessage, error) {
-	var messages []models.ChatMessage
-
-	// 使用 GORM 查询构建器
-	result := config.DB.Where(
-		"recipient_id = ? AND `read` = ?",
-		recipientID,
-		false,
-	).Order("created_at desc").Find(&messages)
-
-	if result.Error != nil {
-		return nil, result.Error
+// conversationID 消息所属会话
+func conversationID(broadcast *models.Broadcast) string {
+	if broadcast.GroupID != "" {
+		return models.GroupConversationID(broadcast.GroupID)
 	}
-	return messages, nil
+	return models.DirectConversationID(broadcast.Client.SendID, broadcast.RecipientID)
 }
 
-// mysql/message.go
-func UpdateMessage(msg *models.ChatMessage) error {
-	// 使用Select限定仅更新Read字段
-	result := config.DB.Model(msg).
-		Select("Read").
-		Where("id = ?", msg.ID).
-		Updates(map[string]interface{}{"read": true})
+// replyFrame 聊天消息帧
+func replyFrame(reply models.ReplyMsg) []byte {
+	data, _ := json.Marshal(reply)
+	return data
+}
 
-	if result.Error != nil {
-		return fmt.Errorf("消息状态更新失败: %w", result.Error)
+// deliver 投递一帧。发送缓冲写满说明客户端消费过慢，直接断开，避免拖慢调度协程和其他连接
+func deliver(c *models.Client, frame []byte) bool {
+	if c.Enqueue(frame) {
+		return true
 	}
-	if result.RowsAffected == 0 {
-		return gorm.ErrRecordNotFound
+	select {
+	case <-c.Done():
+	default:
+		zap.L().Warn("客户端消费过慢，断开连接", zap.String("id", c.ID))
+		c.Close()
 	}
-	return nil
+	return false
 }
 
 func TimeStringToGoTime(timeStr string) time.Time {
@@ -95,28 +68,36 @@ func TimeStringToGoTime(timeStr string) time.Time {
 	return time.Now().UTC()
 }
 
-func GetHistoryMsg(direction, id string, before time.Time, limit int) (*[]models.ChatMessage, error) {
-	var messages []models.ChatMessage
-	result := config.DB.
-		Where("(direction = ? OR direction = ?) AND created_at < ?", direction, id, before).
-		Order("created_at DESC").
-		Limit(limit).
-		Find(&messages)
-
-	if result.Error != nil {
-		return nil, result.Error
+// GetHistoryMsg 会话中 before 之前、用户可见的消息（不含自己删除的），带编辑、撤回状态和附件
+func GetHistoryMsg(userID int, conversationID string, before time.Time, limit int) ([]models.ChatMessageDTO, error) {
+	msgs, err := services.ConversationHistoryBefore(userID, conversationID, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("历史消息查询失败: %w", err)
 	}
-	return &messages, nil
+	return msgs, nil
 }
 
-// websocket/func.go
-func GetAllGroupUser(groupID string) ([]models.User, error) {
-	var group models.Group
-	result := config.DB.Preload("Users").Where("id = ?", groupID).First(&group)
-	if result.Error != nil {
-		return nil, fmt.Errorf("群组查询失败: %w", result.Error)
+// historyMsgFrame 历史消息帧：消息的完整状态，from、code 与实时消息帧一致
+func historyMsgFrame(msg models.ChatMessageDTO) []byte {
+	data, _ := json.Marshal(struct {
+		From string `json:"from"`
+		Code int    `json:"code"`
+		models.ChatMessageDTO
+	}{
+		From:           createId(strconv.Itoa(msg.SenderID), strconv.Itoa(msg.RecipientID)),
+		Code:           CodeConnectionSuccess,
+		ChatMessageDTO: msg,
+	})
+	return data
+}
+
+// GetAllGroupUser 群内全部成员的账号ID，群不存在（或已解散）时为空
+func GetAllGroupUser(groupID string) ([]int, error) {
+	ids, err := services.GetGroupMemberIDs(groupID)
+	if err != nil {
+		return nil, fmt.Errorf("群组查询失败: %w", err)
 	}
-	return group.Users, nil
+	return ids, nil
 }
 
 func ResponseError(c *gin.Context, code int) {
@@ -0,0 +1,77 @@
+package websocket
+
+import (
+	"EmployeeManagementDemo/models"
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+	"sync"
+)
+
+// Hub 一个实例上的聊天调度。manager 的连接表只由 Start 所在的调度协程读写，其他协程经通道交给它；
+// 落库、在线登记和跨实例转发等阻塞操作都在调用方协程（连接的读协程、HTTP 请求）中完成，不占用调度协程
+type Hub struct {
+	manager *models.ClientManager
+	rdb     *redis.Client // 在线状态登记、跨实例转发和会话吊销通知
+
+	// 本实例上的连接按账号索引，踢人/注销时据此强制断开（与调度协程的连接表相互独立，可在任意协程读写）
+	connMu sync.Mutex
+	conns  map[uint]map[*models.Client]struct{}
+}
+
+// NewHub 创建调度实例，调用 Start 后开始工作。manager 未指定实例ID时生成一个
+func NewHub(manager *models.ClientManager, rdb *redis.Client) *Hub {
+	if manager.NodeID == "" {
+		manager.NodeID = newNodeID()
+	}
+	return &Hub{
+		manager: manager,
+		rdb:     rdb,
+		conns:   make(map[uint]map[*models.Client]struct{}),
+	}
+}
+
+// DefaultHub 本进程的调度实例，由 main 在注册路由之前创建；HTTP 接口经它推送事件和建立连接
+var DefaultHub *Hub
+
+// connect 把连接注册到调度协程并登记在线状态，调度协程已退出（服务关闭中）时返回 false
+func (h *Hub) connect(c *models.Client) bool {
+	select {
+	case h.manager.Register <- c:
+	case <-h.manager.Quit:
+		return false
+	}
+	zap.L().Debug("建立新连接", zap.String("id", c.ID), zap.Int("principal_id", c.SendID))
+	h.addPresence(c.SendID)
+	return true
+}
+
+// disconnect 从调度协程注销连接并注销在线登记；调度协程已退出时在线登记由其统一清理
+func (h *Hub) disconnect(c *models.Client) {
+	select {
+	case h.manager.Unregister <- c:
+	case <-h.manager.Quit:
+		return
+	}
+	zap.L().Debug("连接断开", zap.String("id", c.ID), zap.Int("principal_id", c.SendID))
+	h.removePresence(c.SendID)
+}
+
+// pushLocal 交给调度协程投递给账号在本实例上的全部连接，返回是否有连接接收。不能在调度协程中调用
+func (h *Hub) pushLocal(recipientID int, frame []byte) bool {
+	relay := &models.RelayFrame{RecipientID: recipientID, Frame: frame, Delivered: make(chan bool, 1)}
+	select {
+	case h.manager.Relay <- relay:
+	case <-h.manager.Quit:
+		return false
+	}
+	return <-relay.Delivered
+}
+
+// pushToUser 投递给账号在本实例和其他实例上的全部连接，返回是否有连接接收。不能在调度协程中调用
+func (h *Hub) pushToUser(recipientID int, frame []byte) bool {
+	delivered := h.pushLocal(recipientID, frame)
+	if h.relayToNodes(recipientID, frame) {
+		delivered = true
+	}
+	return delivered
+}
@@ -0,0 +1,227 @@
+package websocket
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/testutil"
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// frame 客户端收到的一帧：聊天消息、事件或状态消息
+type frame struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Content string `json:"content"`
+	ID      uint   `json:"id"`
+	MsgKey  string `json:"msg_key"`
+	Event   string `json:"event"`
+	Status  string `json:"status"`
+	UserID  int    `json:"user_id"`
+}
+
+// startHub 在测试环境中启动一个调度实例
+func startHub(t *testing.T) (*Hub, context.CancelFunc) {
+	testutil.Setup(t)
+	config.Cfg.Chat.NonContactLimit = -1
+	createAccounts(t, 1, 2, 3)
+	return startNode(t, config.Rdb, "")
+}
+
+// createAccounts 创建测试用的账号，消息只能发给存在的账号
+func createAccounts(t *testing.T, ids ...int) {
+	t.Helper()
+	for _, id := range ids {
+		if err := config.DB.Create(&models.Account{ID: uint(id), Username: "user" + strconv.Itoa(id)}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// startNode 启动一个使用 rdb 的调度实例，测试结束时停止并等待其退出
+func startNode(t *testing.T, rdb *redis.Client, nodeID string) (*Hub, context.CancelFunc) {
+	manager := models.NewClientManager()
+	manager.NodeID = nodeID
+	h := NewHub(manager, rdb)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.Start(ctx)
+	}()
+	stop := func() {
+		cancel()
+		<-done
+	}
+	t.Cleanup(stop)
+	return h, stop
+}
+
+// connectClient 建立一个没有网络连接的客户端：帧留在发送缓冲中由测试读取，建立后清空连接成功和上线通知
+func connectClient(t *testing.T, h *Hub, principalID int) *models.Client {
+	t.Helper()
+	c := models.NewClient(principalID, "session", nil, sendBufferSize)
+	if !h.connect(c) {
+		t.Fatal("调度协程未接受连接")
+	}
+	drain(t, c)
+	return c
+}
+
+// disconnectClient 与读协程退出时相同：注销后关闭
+func disconnectClient(h *Hub, c *models.Client) {
+	h.disconnect(c)
+	c.Close()
+}
+
+// drain 取出已投递给客户端的全部帧。投递在调用返回前完成，不需要等待
+func drain(t *testing.T, c *models.Client) []frame {
+	t.Helper()
+	var frames []frame
+	for {
+		select {
+		case data := <-c.Send:
+			var f frame
+			if err := json.Unmarshal(data, &f); err != nil {
+				t.Fatalf("无法解析的帧 %s: %v", data, err)
+			}
+			frames = append(frames, f)
+		default:
+			return frames
+		}
+	}
+}
+
+func devices(t *testing.T, h *Hub, principalID int) int {
+	t.Helper()
+	p, err := h.GetPresence(principalID, []int{principalID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p[0].Devices
+}
+
+func events(frames []frame) []string {
+	var evs []string
+	for _, f := range frames {
+		if f.Event != "" {
+			evs = append(evs, f.Event)
+		}
+	}
+	return evs
+}
+
+func TestHubDeliversToEveryDevice(t *testing.T) {
+	h, _ := startHub(t)
+	alice := connectClient(t, h, 1)
+	phone := connectClient(t, h, 2)
+	laptop := connectClient(t, h, 2)
+	if n := devices(t, h, 2); n != 2 {
+		t.Fatalf("接收方登记了 %d 个设备，期望 2", n)
+	}
+
+	h.SingleChat(alice, &models.SendMsg{Type: 1, RecipientID: 2, Content: "hi"})
+
+	var id uint
+	for _, c := range []*models.Client{phone, laptop} {
+		frames := drain(t, c)
+		if len(frames) != 1 || frames[0].Content != "hi" || frames[0].ID == 0 {
+			t.Fatalf("接收方的每个设备应收到一条消息，得到 %+v", frames)
+		}
+		id = frames[0].ID
+	}
+	evs := events(drain(t, alice))
+	if len(evs) != 2 || evs[0] != models.ChatEventSent || evs[1] != models.ChatEventDelivered {
+		t.Fatalf("发送方应依次收到 sent、delivered，得到 %v", evs)
+	}
+
+	var msg models.ChatMessage
+	if err := config.DB.First(&msg, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	if msg.Status != models.MsgStatusDelivered {
+		t.Fatalf("消息状态为 %s，期望 delivered", msg.Status)
+	}
+}
+
+func TestHubDisconnectAndReconnect(t *testing.T) {
+	h, _ := startHub(t)
+	alice := connectClient(t, h, 1)
+	bob := connectClient(t, h, 2)
+	disconnectClient(h, bob)
+	if n := devices(t, h, 2); n != 0 {
+		t.Fatalf("断开后仍登记了 %d 个设备", n)
+	}
+
+	h.SingleChat(alice, &models.SendMsg{Type: 1, RecipientID: 2, Content: "hi"})
+	if frames := drain(t, bob); len(frames) != 0 {
+		t.Fatalf("已断开的连接不应收到消息，得到 %+v", frames)
+	}
+	frames := drain(t, alice)
+	if len(frames) != 2 || frames[0].Event != models.ChatEventSent || frames[1].Message != "对方不在线" {
+		t.Fatalf("接收方离线时发送方应收到 sent 和离线提示，得到 %+v", frames)
+	}
+
+	// 重新连接后拉取离线消息，发送方收到送达回执
+	bob = connectClient(t, h, 2)
+	if n := devices(t, h, 2); n != 1 {
+		t.Fatalf("重新连接后登记了 %d 个设备，期望 1", n)
+	}
+	h.UnreadMessages(bob)
+	if frames := drain(t, bob); len(frames) != 1 || frames[0].Content != "hi" {
+		t.Fatalf("重新连接后应收到离线消息，得到 %+v", frames)
+	}
+	if evs := events(drain(t, alice)); len(evs) != 1 || evs[0] != models.ChatEventDelivered {
+		t.Fatalf("发送方应收到送达回执，得到 %v", evs)
+	}
+}
+
+func TestHubDropsSlowConsumer(t *testing.T) {
+	h, _ := startHub(t)
+	alice := connectClient(t, h, 1)
+	// 缓冲只够连接成功和上线通知两帧
+	slow := models.NewClient(2, "session", nil, 2)
+	if !h.connect(slow) {
+		t.Fatal("调度协程未接受连接")
+	}
+
+	h.SingleChat(alice, &models.SendMsg{Type: 1, RecipientID: 2, Content: "hi"})
+	select {
+	case <-slow.Done():
+	default:
+		t.Fatal("发送缓冲写满的连接应被断开")
+	}
+	if frames := drain(t, alice); len(frames) != 2 || frames[1].Message != "对方不在线" {
+		t.Fatalf("唯一的连接被断开时消息应视为未送达，得到 %+v", frames)
+	}
+
+	// 读协程随后注销，在线登记归零
+	disconnectClient(h, slow)
+	if n := devices(t, h, 2); n != 0 {
+		t.Fatalf("注销后仍登记了 %d 个设备", n)
+	}
+}
+
+func TestHubStopClosesConnections(t *testing.T) {
+	h, stop := startHub(t)
+	bob := connectClient(t, h, 2)
+
+	stop()
+	select {
+	case <-bob.Done():
+	default:
+		t.Fatal("调度协程退出时应关闭全部连接")
+	}
+	if n := devices(t, h, 2); n != 0 {
+		t.Fatalf("调度协程退出后仍登记了 %d 个设备", n)
+	}
+	if h.connect(models.NewClient(3, "session", nil, sendBufferSize)) {
+		t.Fatal("调度协程退出后不应再接受连接")
+	}
+	// 读协程在退出后注销不会阻塞
+	disconnectClient(h, bob)
+}
@@ -0,0 +1,186 @@
+package websocket
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/services"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+	"strconv"
+	"time"
+)
+
+// 在线状态：连接数来自各实例的在线登记（只统计存活实例），away/busy 为用户设置的状态，
+// 第一个设备上线、最后一个设备下线（含所在实例退出或存活标记过期）以及用户修改状态时推送给联系人
+const (
+	presenceStatusPrefix = "ws_status:"    // 账号ID -> 用户设置的状态，未设置即 online
+	lastSeenPrefix       = "ws_last_seen:" // 账号ID -> 最近一次在线的时间（Unix 秒）
+)
+
+func presenceStatusKey(principalID int) string {
+	return fmt.Sprintf("%s%d", presenceStatusPrefix, principalID)
+}
+
+func lastSeenKey(principalID int) string {
+	return fmt.Sprintf("%s%d", lastSeenPrefix, principalID)
+}
+
+// GetPresence 批量查询 viewerID 看到的在线状态：屏蔽了 viewerID 的用户显示为离线，且不带最近在线时间
+func (h *Hub) GetPresence(viewerID int, ids []int) ([]models.PresenceDTO, error) {
+	blockers, err := services.BlockedBy(viewerID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	pipe := h.rdb.Pipeline()
+	conns := make([]*redis.StringStringMapCmd, len(ids))
+	statuses := make([]*redis.StringCmd, len(ids))
+	seen := make([]*redis.StringCmd, len(ids))
+	for i, id := range ids {
+		conns[i] = pipe.HGetAll(config.Ctx, presenceKey(id))
+		statuses[i] = pipe.Get(config.Ctx, presenceStatusKey(id))
+		seen[i] = pipe.Get(config.Ctx, lastSeenKey(id))
+	}
+	// Exec 返回第一个出错命令的错误（读取不存在的键也算），逐条检查：只有 GET 可以是 redis.Nil
+	_, _ = pipe.Exec(config.Ctx)
+	for i := range ids {
+		if err := conns[i].Err(); err != nil {
+			return nil, err
+		}
+		for _, cmd := range []*redis.StringCmd{statuses[i], seen[i]} {
+			if err := cmd.Err(); err != nil && !errors.Is(err, redis.Nil) {
+				return nil, err
+			}
+		}
+	}
+
+	var nodes []string
+	for _, cmd := range conns {
+		for node := range cmd.Val() {
+			nodes = append(nodes, node)
+		}
+	}
+	alive, err := h.aliveNodes(nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	result := make([]models.PresenceDTO, 0, len(ids))
+	for i, id := range ids {
+		p := models.PresenceDTO{UserID: id, Status: models.PresenceOffline}
+		if blockers[id] {
+			result = append(result, p)
+			continue
+		}
+		for node, n := range conns[i].Val() {
+			if alive[node] {
+				count, _ := strconv.Atoi(n)
+				p.Devices += count
+			}
+		}
+		if p.Devices > 0 {
+			p.Status = models.PresenceOnline
+			if s := statuses[i].Val(); s != "" {
+				p.Status = s
+			}
+			p.LastSeen = &now
+		} else if sec, err := seen[i].Int64(); err == nil {
+			t := time.Unix(sec, 0)
+			p.LastSeen = &t
+		}
+		result = append(result, p)
+	}
+	return result, nil
+}
+
+// SetPresenceStatus 设置 online/away/busy，在线时推送给联系人和自己的其他设备
+func (h *Hub) SetPresenceStatus(principalID int, status string) error {
+	var err error
+	if status == models.PresenceOnline {
+		err = h.rdb.Del(config.Ctx, presenceStatusKey(principalID)).Err()
+	} else {
+		err = h.rdb.Set(config.Ctx, presenceStatusKey(principalID), status, 0).Err()
+	}
+	if err != nil {
+		return err
+	}
+	if p, err := h.GetPresence(principalID, []int{principalID}); err == nil && p[0].Devices > 0 {
+		h.announcePresence(principalID, status, time.Now())
+	}
+	return nil
+}
+
+// SetStatus 通过连接设置在线状态（如客户端检测到空闲时设为 away）
+func (h *Hub) SetStatus(c *models.Client, sendMsg *models.SendMsg) {
+	switch sendMsg.Content {
+	case models.PresenceOnline, models.PresenceAway, models.PresenceBusy:
+	default:
+		ResponseWebSocket(c, CodeParamError, "在线状态无效")
+		return
+	}
+	if err := h.SetPresenceStatus(c.SendID, sendMsg.Content); err != nil {
+		ResponseWebSocket(c, CodeServerBusy, "服务繁忙")
+	}
+}
+
+// presenceStatus 用户设置的状态，未设置或查询失败时为 online
+func (h *Hub) presenceStatus(principalID int) string {
+	status, err := h.rdb.Get(config.Ctx, presenceStatusKey(principalID)).Result()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			zap.L().Error("在线状态查询失败", zap.Error(err))
+		}
+		return models.PresenceOnline
+	}
+	return status
+}
+
+// wentOffline 账号的最后一个设备下线：记录最近在线时间并推送 offline
+func (h *Hub) wentOffline(principalID int) {
+	now := time.Now()
+	h.rdb.Set(config.Ctx, lastSeenKey(principalID), now.Unix(), 0)
+	h.announcePresence(principalID, models.PresenceOffline, now)
+}
+
+// announcePresence 把状态变化推送给联系人（含同部门同事），在调用方协程中执行
+func (h *Hub) announcePresence(principalID int, status string, at time.Time) {
+	watchers, err := services.ContactIDs(principalID)
+	if err != nil {
+		zap.L().Error("联系人查询失败", zap.Error(err))
+		return
+	}
+	frame := eventFrame(models.ChatEvent{
+		Event:  models.ChatEventPresence,
+		UserID: principalID,
+		Status: status,
+		At:     at,
+	})
+	for _, id := range append(watchers, principalID) {
+		h.notify(id, frame)
+	}
+}
+
+// aliveNodes 查询哪些实例仍在续期存活标记
+func (h *Hub) aliveNodes(nodes []string) (map[string]bool, error) {
+	alive := make(map[string]bool, len(nodes))
+	if len(nodes) == 0 {
+		return alive, nil
+	}
+	pipe := h.rdb.Pipeline()
+	cmds := make(map[string]*redis.IntCmd, len(nodes))
+	for _, node := range nodes {
+		if _, ok := cmds[node]; !ok {
+			cmds[node] = pipe.Exists(config.Ctx, nodeAlivePrefix+node)
+		}
+	}
+	if _, err := pipe.Exec(config.Ctx); err != nil {
+		return nil, err
+	}
+	for node, cmd := range cmds {
+		alive[node] = cmd.Val() > 0
+	}
+	return alive, nil
+}
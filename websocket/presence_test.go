@@ -0,0 +1,144 @@
+package websocket
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/models"
+	"sync"
+	"testing"
+	"time"
+)
+
+// addContacts 让两人互为联系人
+func addContacts(t *testing.T, a, b int) {
+	t.Helper()
+	for _, row := range []models.ChatContact{
+		{UserID: a, ContactID: b, Status: models.ContactAccepted},
+		{UserID: b, ContactID: a, Status: models.ContactAccepted},
+	} {
+		if err := config.DB.Create(&row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// presenceEvents 客户端收到的关于 userID 的在线状态推送
+func presenceEvents(frames []frame, userID int) []string {
+	var statuses []string
+	for _, f := range frames {
+		if f.Event == models.ChatEventPresence && f.UserID == userID {
+			statuses = append(statuses, f.Status)
+		}
+	}
+	return statuses
+}
+
+// waitOnline 等待其他实例转发来的 userID 上线推送
+func waitOnline(t *testing.T, c *models.Client, userID int) {
+	t.Helper()
+	if f := waitFrame(t, c); f.Event != models.ChatEventPresence || f.UserID != userID || f.Status != models.PresenceOnline {
+		t.Fatalf("应收到 %d 的上线推送，得到 %+v", userID, f)
+	}
+}
+
+func TestPresenceAnnouncesFirstAndLastDeviceOnly(t *testing.T) {
+	h, _ := startHub(t)
+	addContacts(t, 1, 2)
+	alice := connectClient(t, h, 1)
+
+	// 多个设备同时上线只推送一次
+	var wg sync.WaitGroup
+	devicesOfBob := make([]*models.Client, 5)
+	for i := range devicesOfBob {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			devicesOfBob[i] = models.NewClient(2, "session", nil, sendBufferSize)
+			h.connect(devicesOfBob[i])
+		}(i)
+	}
+	wg.Wait()
+	if got := presenceEvents(drain(t, alice), 2); len(got) != 1 || got[0] != models.PresenceOnline {
+		t.Fatalf("联系人应只收到一次上线推送，得到 %v", got)
+	}
+
+	for i, c := range devicesOfBob {
+		disconnectClient(h, c)
+		got := presenceEvents(drain(t, alice), 2)
+		if i < len(devicesOfBob)-1 && len(got) != 0 {
+			t.Fatalf("还有设备在线时不应推送下线，得到 %v", got)
+		}
+		if i == len(devicesOfBob)-1 && (len(got) != 1 || got[0] != models.PresenceOffline) {
+			t.Fatalf("最后一个设备下线时应推送 offline，得到 %v", got)
+		}
+	}
+}
+
+func TestPresenceAnnouncesOfflineOnShutdown(t *testing.T) {
+	_, a, b, stopB := startCluster(t)
+	addContacts(t, 1, 2)
+	alice := connectClient(t, a, 1)
+	connectClient(t, b, 2)
+	waitOnline(t, alice, 2)
+
+	stopB()
+	if f := waitFrame(t, alice); f.Event != models.ChatEventPresence || f.UserID != 2 || f.Status != models.PresenceOffline {
+		t.Fatalf("实例退出时应推送其上用户下线，得到 %+v", f)
+	}
+	if n := devices(t, a, 2); n != 0 {
+		t.Fatalf("实例退出后仍登记了 %d 个设备", n)
+	}
+}
+
+func TestPresenceReapsExpiredNode(t *testing.T) {
+	mr, a, b, _ := startCluster(t)
+	addContacts(t, 1, 2)
+	alice := connectClient(t, a, 1)
+	connectClient(t, b, 2)
+	waitOnline(t, alice, 2)
+
+	// 实例 b 停止续期（如进程被杀），由其他实例清理并推送下线，且只推送一次
+	mr.FastForward(nodeAliveTTL + time.Second)
+	a.reapNodes()
+	if got := presenceEvents(drain(t, alice), 2); len(got) != 1 || got[0] != models.PresenceOffline {
+		t.Fatalf("过期实例上的用户应推送一次下线，得到 %v", got)
+	}
+	a.reapNodes()
+	if got := presenceEvents(drain(t, alice), 2); len(got) != 0 {
+		t.Fatalf("过期实例只应清理一次，得到 %v", got)
+	}
+	if exists := mr.Exists(presenceKey(2)); exists {
+		t.Fatal("过期实例的在线登记应被删除")
+	}
+}
+
+func TestGetPresenceHidesBlockers(t *testing.T) {
+	h, _ := startHub(t)
+	connectClient(t, h, 2)
+	if err := config.DB.Create(&models.ChatContact{UserID: 2, ContactID: 1, Status: models.ContactBlocked}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := h.GetPresence(1, []int{2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p[0].Status != models.PresenceOffline || p[0].Devices != 0 || p[0].LastSeen != nil {
+		t.Fatalf("被屏蔽的用户应看到对方离线且没有最近在线时间，得到 %+v", p[0])
+	}
+	p, err = h.GetPresence(3, []int{2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p[0].Status != models.PresenceOnline || p[0].Devices != 1 {
+		t.Fatalf("其他用户应看到对方在线，得到 %+v", p[0])
+	}
+}
+
+func TestGetPresenceReportsRedisErrors(t *testing.T) {
+	mr, a, _, _ := startCluster(t)
+	mr.SetError("LOADING")
+	defer mr.SetError("")
+	if _, err := a.GetPresence(1, []int{2, 3}); err == nil {
+		t.Fatal("Redis 出错时应返回错误而不是全部离线")
+	}
+}
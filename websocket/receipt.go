@@ -0,0 +1,171 @@
+package websocket
+
+import (
+	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/services"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// 回执、输入状态和消息变更（编辑、撤回、删除）事件：只推送给在线连接，不落库（状态本身已持久化在 ChatMessage 上）
+
+// eventFrame 事件帧
+func eventFrame(ev models.ChatEvent) []byte {
+	data, _ := json.Marshal(ev)
+	return data
+}
+
+// notify 把事件推送给账号在本实例和其他实例上的全部连接，调度协程已退出时放弃。不能在调度协程中调用
+func (h *Hub) notify(recipientID int, frame []byte) {
+	h.pushToUser(recipientID, frame)
+}
+
+// NotifyMessagesRead 给发送方推送已读回执，并通知读者的其他设备会话已读（conversationID 为空时不通知）
+func (h *Hub) NotifyMessagesRead(readerID int, conversationID string, msgs []models.ChatMessage) {
+	for _, msg := range msgs {
+		at := time.Now()
+		if msg.ReadAt != nil {
+			at = *msg.ReadAt
+		}
+		h.notify(msg.SendID, eventFrame(models.ChatEvent{
+			Event:          models.ChatEventRead,
+			ConversationID: msg.ConversationID,
+			MsgKey:         msg.MsgKey,
+			MessageID:      msg.ID,
+			UserID:         readerID,
+			At:             at,
+		}))
+	}
+	if conversationID != "" {
+		h.notify(readerID, eventFrame(models.ChatEvent{
+			Event:          models.ChatEventConversationRead,
+			ConversationID: conversationID,
+			UserID:         readerID,
+			At:             time.Now(),
+		}))
+	}
+}
+
+// NotifyMessageChanged 把消息的编辑、撤回或删除推送给所有接收方和发送方的其他设备，
+// 每人收到的 message_id 为自己那一份的ID
+func (h *Hub) NotifyMessageChanged(event string, msgs []models.ChatMessage) {
+	if len(msgs) == 0 {
+		return
+	}
+	rowOf := make(map[int]*models.ChatMessage, len(msgs)+1)
+	for i := range msgs {
+		rowOf[msgs[i].RecipientID] = &msgs[i]
+		if msgs[i].GroupID == "" {
+			rowOf[msgs[i].SendID] = &msgs[i]
+		}
+	}
+	if _, ok := rowOf[msgs[0].SendID]; !ok { // 群消息上线前没有发送方副本
+		rowOf[msgs[0].SendID] = &msgs[0]
+	}
+
+	now := time.Now()
+	for userID, msg := range rowOf {
+		h.notify(userID, eventFrame(models.ChatEvent{
+			Event:          event,
+			ConversationID: msg.ConversationID,
+			MsgKey:         msg.MsgKey,
+			MessageID:      msg.ID,
+			UserID:         msg.SendID,
+			Content:        msg.Content,
+			At:             now,
+		}))
+	}
+}
+
+// NotifyContact 推送好友申请、申请通过等联系人事件，userID 为触发事件的一方
+func (h *Hub) NotifyContact(event string, recipientID, userID int, content string) {
+	h.notify(recipientID, eventFrame(models.ChatEvent{
+		Event:   event,
+		UserID:  userID,
+		Content: content,
+		At:      time.Now(),
+	}))
+}
+
+// ReadAck 接收方确认已读（message_ids 为收到的消息帧中的 id）
+func (h *Hub) ReadAck(c *models.Client, sendMsg *models.SendMsg) {
+	msgs, err := services.MarkMessagesRead(c.SendID, sendMsg.MessageIDs)
+	if err != nil {
+		ResponseWebSocket(c, CodeServerBusy, "服务繁忙")
+		return
+	}
+	h.NotifyMessagesRead(c.SendID, "", msgs)
+}
+
+// ConversationRead 把整个会话标记为已读
+func (h *Hub) ConversationRead(c *models.Client, sendMsg *models.SendMsg) {
+	msgs, err := services.MarkConversationRead(c.SendID, sendMsg.ConversationID)
+	if err != nil {
+		if errors.Is(err, services.ErrConversationInvalid) {
+			ResponseWebSocket(c, CodeParamError, err.Error())
+			return
+		}
+		ResponseWebSocket(c, CodeServerBusy, "服务繁忙")
+		return
+	}
+	h.NotifyMessagesRead(c.SendID, sendMsg.ConversationID, msgs)
+}
+
+// Typing 把正在输入 / 停止输入转发给会话中的其他人
+func (h *Hub) Typing(c *models.Client, sendMsg *models.SendMsg, typing bool) {
+	peers, groupID, ok := models.ParseConversationID(sendMsg.ConversationID)
+	if !ok {
+		ResponseWebSocket(c, CodeParamError, "会话ID格式错误")
+		return
+	}
+
+	var recipients []int
+	if groupID != "" {
+		memberIDs, err := GetAllGroupUser(groupID)
+		if err != nil {
+			ResponseWebSocket(c, CodeServerBusy, "服务繁忙")
+			return
+		}
+		if !containsID(memberIDs, c.SendID) {
+			ResponseWebSocket(c, CodeNotGroupMember, "不是该群成员")
+			return
+		}
+		recipients = memberIDs
+	} else {
+		if peers[0] != c.SendID && peers[1] != c.SendID {
+			ResponseWebSocket(c, CodeParamError, "会话ID格式错误")
+			return
+		}
+		recipients = peers[:]
+	}
+
+	// 不向屏蔽了自己的人推送
+	blockers, err := services.BlockedBy(c.SendID, recipients)
+	if err != nil {
+		ResponseWebSocket(c, CodeServerBusy, "服务繁忙")
+		return
+	}
+
+	frame := eventFrame(models.ChatEvent{
+		Event:          models.ChatEventTyping,
+		ConversationID: sendMsg.ConversationID,
+		UserID:         c.SendID,
+		Typing:         typing,
+		At:             time.Now(),
+	})
+	for _, id := range recipients {
+		if id != c.SendID && !blockers[id] {
+			h.notify(id, frame)
+		}
+	}
+}
+
+func containsID(ids []int, id int) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,27 @@
+package websocket
+
+import (
+	"EmployeeManagementDemo/config"
+	"EmployeeManagementDemo/models"
+	"testing"
+)
+
+func TestTypingSkipsBlockers(t *testing.T) {
+	h, _ := startHub(t)
+	alice := connectClient(t, h, 1)
+	bob := connectClient(t, h, 2)
+	conv := models.DirectConversationID(1, 2)
+
+	h.Typing(alice, &models.SendMsg{ConversationID: conv}, true)
+	if evs := events(drain(t, bob)); len(evs) != 1 || evs[0] != models.ChatEventTyping {
+		t.Fatalf("对方应收到正在输入，得到 %v", evs)
+	}
+
+	if err := config.DB.Create(&models.ChatContact{UserID: 2, ContactID: 1, Status: models.ContactBlocked}).Error; err != nil {
+		t.Fatal(err)
+	}
+	h.Typing(alice, &models.SendMsg{ConversationID: conv}, true)
+	if frames := drain(t, bob); len(frames) != 0 {
+		t.Fatalf("屏蔽了发送方的人不应收到正在输入，得到 %+v", frames)
+	}
+}
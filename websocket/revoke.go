@@ -0,0 +1,71 @@
+package websocket
+
+import (
+	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/services"
+	"context"
+	"encoding/json"
+	"go.uber.org/zap"
+)
+
+func (h *Hub) trackClient(c *models.Client) {
+	h.connMu.Lock()
+	defer h.connMu.Unlock()
+	pid := uint(c.SendID)
+	if h.conns[pid] == nil {
+		h.conns[pid] = map[*models.Client]struct{}{}
+	}
+	h.conns[pid][c] = struct{}{}
+}
+
+func (h *Hub) untrackClient(c *models.Client) {
+	h.connMu.Lock()
+	defer h.connMu.Unlock()
+	pid := uint(c.SendID)
+	delete(h.conns[pid], c)
+	if len(h.conns[pid]) == 0 {
+		delete(h.conns, pid)
+	}
+}
+
+// WatchRevocations 订阅会话吊销通知，断开本实例上被踢出或已注销会话的连接
+func (h *Hub) WatchRevocations(ctx context.Context) {
+	sub := h.rdb.Subscribe(ctx, services.SessionRevokedChannel)
+	defer sub.Close()
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			var ev services.SessionRevokedEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &ev); err != nil {
+				zap.L().Error("会话吊销通知解析失败", zap.Error(err))
+				continue
+			}
+			h.closeRevoked(ev)
+		}
+	}
+}
+
+// closeRevoked 关闭匹配的连接，读协程随之退出并完成注销
+func (h *Hub) closeRevoked(ev services.SessionRevokedEvent) {
+	h.connMu.Lock()
+	var targets []*models.Client
+	for c := range h.conns[ev.PrincipalID] {
+		if ev.SessionID == "" || c.SessionID == ev.SessionID {
+			targets = append(targets, c)
+		}
+	}
+	h.connMu.Unlock()
+
+	for _, c := range targets {
+		// 经写协程先通知再断开
+		ResponseWebSocket(c, CodeConnectionBreak, "会话已失效")
+		c.Close()
+	}
+}
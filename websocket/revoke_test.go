@@ -0,0 +1,54 @@
+package websocket
+
+import (
+	"EmployeeManagementDemo/models"
+	"EmployeeManagementDemo/services"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestCloseRevokedSendsReasonBeforeClosing(t *testing.T) {
+	h := NewHub(models.NewClientManager(), nil)
+	clients := make(chan *models.Client, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		c := models.NewClient(1, "session", conn, sendBufferSize)
+		h.trackClient(c)
+		clients <- c
+	}))
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	// 两者都就绪时 select 随机选择分支，多试几次
+	for i := 0; i < 20; i++ {
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := <-clients
+		// 写协程启动时原因和关闭都已就绪
+		h.closeRevoked(services.SessionRevokedEvent{PrincipalID: 1})
+		go Write(c)
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var f frame
+		if err := conn.ReadJSON(&f); err != nil {
+			t.Fatalf("断开前应收到原因，得到 %v", err)
+		}
+		if f.Code != CodeConnectionBreak || f.Message != "会话已失效" {
+			t.Fatalf("断开原因为 %+v", f)
+		}
+		if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseNoStatusReceived) {
+			t.Fatalf("随后应收到关闭帧，得到 %v", err)
+		}
+		conn.Close()
+		h.untrackClient(c)
+	}
+}
@@ -3,92 +3,148 @@ package websocket
 import (
 	"EmployeeManagementDemo/config"
 	"EmployeeManagementDemo/models"
-	"fmt"
+	"EmployeeManagementDemo/services"
+	"context"
 	"go.uber.org/zap"
 	"strconv"
+	"time"
 )
 
-// 用于在启动时进行监听
-func Start(manager *models.ClientManager) {
+// Start 调度协程：维护本实例的在线连接并投递到本地连接，随服务启动；ctx 取消时断开全部连接，
+// 注销本实例的在线登记并推送下线后退出。
+// 调度协程只操作内存中的连接表，多个实例共用一个 Redis 时，发往其他实例上用户的消息经 Redis 转发
+func (h *Hub) Start(ctx context.Context) {
+	manager := h.manager
+	// 先设置存活标记再接受连接，否则刚登记的连接不计入在线
+	h.rdb.Set(config.Ctx, nodeAlivePrefix+manager.NodeID, 1, nodeAliveTTL)
+	h.rdb.SAdd(config.Ctx, nodesKey, manager.NodeID)
+	nodeDone := make(chan struct{})
+	go func() {
+		defer close(nodeDone)
+		h.runNode(ctx)
+	}()
+
+	defer func() {
+		close(manager.Quit)
+		for _, conns := range manager.Clients {
+			for conn := range conns {
+				conn.Close()
+			}
+		}
+		manager.Clients = make(map[int]map[*models.Client]struct{})
+		<-nodeDone
+		h.leave()
+	}()
+
 	for {
-		fmt.Println("<-----监听通信管道----->")
 		select {
-		//监测model.Manager.Register这个的变化，有新的东西加入管道时会被监听到，从而建立连接
-		case conn := <-models.Manager.Register:
-			fmt.Println("建立新连接:", conn.ID)
-			//将新建立的连接加入到用户管理的map中，用于记录连接对象,以连接人的id为键，以连接对象为值
-			models.Manager.Clients[conn.ID] = conn
+		case <-ctx.Done():
+			return
+		//有新的连接加入，以账号ID为键记录，同一账号可以有多个连接（多设备）
+		case conn := <-manager.Register:
+			if manager.Clients[conn.SendID] == nil {
+				manager.Clients[conn.SendID] = make(map[*models.Client]struct{})
+			}
+			manager.Clients[conn.SendID][conn] = struct{}{}
 			//返回成功信息
-			ResponseWebSocket(conn.Socket, CodeConnectionSuccess, "已连接至服务器")
+			ResponseWebSocket(conn, CodeConnectionSuccess, "已连接至服务器")
 		//断开连接,监测到变化，有用户断开连接
-		case conn := <-models.Manager.Unregister:
-			fmt.Println("连接失败:", conn.ID)
-			if _, ok := models.Manager.Clients[conn.ID]; ok {
-				ResponseWebSocket(conn.Socket, CodeConnectionBreak, "连接已断开")
-			}
-			//关闭当前用户使用的管道
-			//close(conn.Send)
-			//删除用户管理中的已连接的用户
-			delete(models.Manager.Clients, conn.ID)
-		case broadcast := <-models.Manager.Broadcast: //广播消息
-			message := broadcast.Message
-			recipientID := broadcast.Client.RecipientID
-			//给一个变量用于确定状态
-			flag := false
-			contentid := createId(strconv.Itoa(broadcast.Client.SendID), strconv.Itoa(recipientID))
-			rID := strconv.Itoa(recipientID) + "->"
-			//遍历客户端连接map,查找该用户有没有在线,判断的是对方的连接例如:1要向2发消息,我现在是用户1,那么我需要判断2->1是否存在在用户管理中
-			for id, conn := range models.Manager.Clients {
-				//如果找不到就说明用户不在线,与接收人的id比较
-				if id != rID {
-					continue
-				}
-				//走到这一步,就说明用户在线,就把消息放入管道里面
-				select {
-				case conn.Send <- message:
-					flag = true
-				default: //否则就把该连接从用户管理中删除
-					close(conn.Send)
-					delete(models.Manager.Clients, conn.ID)
-				}
-			}
-			//判断完之后就把将消息发给用户
-			if flag {
-				fmt.Println("用户在线应答")
-				ResponseWebSocket(models.Manager.Clients[rID].Socket, CodeConnectionSuccess, string(message))
-				//把消息插到数据库中
-				msg := models.ChatMessage{
-					Direction:   contentid,
-					SendID:      broadcast.Client.SendID,
-					RecipientID: recipientID,
-					GroupID:     broadcast.Client.GroupID,
-					Content:     string(message),
-					Read:        true,
-				}
-				err := config.DB.Create(&msg).Error
-				if err != nil {
-					zap.L().Error("在线发送消息出现了错误", zap.Error(err))
-				}
-			} else { //如果不在线
-				ResponseWebSocket(broadcast.Client.Socket, CodeConnectionSuccess, "对方不在线")
-				//把消息插到数据库中
-				msg := models.ChatMessage{
-					Direction:   contentid,
-					SendID:      broadcast.Client.SendID,
-					RecipientID: recipientID,
-					GroupID:     broadcast.Client.GroupID,
-					Content:     string(message),
-					Read:        false,
-				}
-				err := config.DB.Create(&msg).Error
-				if err != nil {
-					zap.L().Error("不在线发送消息出现了错误", zap.Error(err))
-				}
+		case conn := <-manager.Unregister:
+			removeClient(manager, conn)
+		case relay := <-manager.Relay: //本实例的消息、事件以及其他实例转发来的消息
+			delivered := deliverLocal(manager, relay.RecipientID, relay.Frame)
+			if relay.Delivered != nil {
+				relay.Delivered <- delivered
 			}
 		}
+	}
+}
 
+// removeClient 从连接表中移除并关闭连接，在线登记由连接的读协程注销
+func removeClient(manager *models.ClientManager, conn *models.Client) {
+	conns := manager.Clients[conn.SendID]
+	if _, ok := conns[conn]; !ok {
+		return // 已经移除过（投递失败时先移除，读协程随后再注销）
+	}
+	delete(conns, conn)
+	if len(conns) == 0 {
+		delete(manager.Clients, conn.SendID)
 	}
+	conn.Close()
+}
 
+// deliverLocal 投递给本实例上接收方的全部连接，返回是否至少投递成功一个
+func deliverLocal(manager *models.ClientManager, recipientID int, frame []byte) bool {
+	delivered := false
+	for conn := range manager.Clients[recipientID] {
+		if deliver(conn, frame) {
+			delivered = true
+		} else { //投递失败（连接已断开或消费过慢）就把该连接从用户管理中删除
+			removeClient(manager, conn)
+		}
+	}
+	return delivered
+}
+
+// deliverMessage 落库后投递给接收方在本实例和其他实例上的全部连接（只由发送方所在实例落库），
+// 送达后标记为已送达并回执给发送方；接收方不在线时保持 sent，上线拉取未读时再送达。
+// 在发送方连接的读协程中执行，同一连接发出的消息按顺序投递
+func (h *Hub) deliverMessage(broadcast *models.Broadcast) {
+	message := broadcast.Message
+	recipientID := broadcast.RecipientID
+	senderID := broadcast.Client.SendID
+	contentid := createId(strconv.Itoa(senderID), strconv.Itoa(recipientID))
+
+	//先把消息插到数据库中，投递的帧带上消息ID供接收方确认已读
+	msg := models.ChatMessage{
+		ConversationID: conversationID(broadcast),
+		Direction:      contentid,
+		SendID:         senderID,
+		RecipientID:    recipientID,
+		GroupID:        broadcast.GroupID,
+		Content:        string(message),
+		MsgKey:         broadcast.MsgKey,
+		Status:         models.MsgStatusSent,
+	}
+	reply := models.ReplyMsg{
+		From:           contentid,
+		Code:           CodeConnectionSuccess,
+		Content:        string(message),
+		MsgKey:         msg.MsgKey,
+		ConversationID: msg.ConversationID,
+		SenderID:       senderID,
+	}
+	if broadcast.Attachment != nil {
+		msg.AttachmentID = &broadcast.Attachment.ID
+		reply.Attachment = broadcast.Attachment.ToDTO()
+	}
+	if err := config.DB.Create(&msg).Error; err != nil {
+		zap.L().Error("消息保存失败", zap.Error(err))
+		ResponseWebSocket(broadcast.Client, CodeServerBusy, "服务繁忙")
+		return
+	}
+
+	reply.ID = msg.ID
+	if !h.pushToUser(recipientID, replyFrame(reply)) {
+		if broadcast.GroupID == "" {
+			ResponseWebSocket(broadcast.Client, CodeConnectionSuccess, "对方不在线")
+		}
+		return
+	}
+
+	now := time.Now()
+	if err := services.MarkChatMessagesDelivered([]uint{msg.ID}, now); err != nil {
+		zap.L().Error("消息状态更新失败", zap.Error(err))
+		return
+	}
+	h.pushToUser(senderID, eventFrame(models.ChatEvent{
+		Event:          models.ChatEventDelivered,
+		ConversationID: msg.ConversationID,
+		MsgKey:         msg.MsgKey,
+		MessageID:      msg.ID,
+		UserID:         recipientID,
+		At:             now,
+	}))
 }
 
 func createId(uid, toUid string) string {